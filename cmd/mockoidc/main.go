@@ -0,0 +1,303 @@
+// Command mockoidc is a minimal OpenID Connect provider for trying out and
+// testing the OIDC login flow locally. It approves every authorization
+// request without a login page; the identity it reports can be changed with
+// flags or per request with the sub, email, name and username query
+// parameters on the authorization URL.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock-key"
+
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	identity      identity
+	expiresAt     time.Time
+}
+
+type identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+}
+
+type server struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	defaults     identity
+	key          *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]*authorization
+	tokens map[string]identity
+}
+
+func main() {
+	s := &server{
+		codes:  make(map[string]*authorization),
+		tokens: make(map[string]identity),
+	}
+
+	addr := flag.String("addr", getEnv("MOCK_OIDC_ADDR", ":9000"), "listen address")
+	flag.StringVar(&s.issuer, "issuer", getEnv("MOCK_OIDC_ISSUER", "http://localhost:9000"), "issuer URL")
+	flag.StringVar(&s.clientID, "client-id", getEnv("MOCK_OIDC_CLIENT_ID", "socialnet"), "expected client ID")
+	flag.StringVar(&s.clientSecret, "client-secret", getEnv("MOCK_OIDC_CLIENT_SECRET", "secret"), "expected client secret, empty for a public client")
+	flag.StringVar(&s.defaults.Subject, "sub", "mock-user-1", "default subject")
+	flag.StringVar(&s.defaults.Email, "email", "student@university.test", "default email")
+	flag.BoolVar(&s.defaults.EmailVerified, "email-verified", true, "report the email as verified")
+	flag.StringVar(&s.defaults.Name, "name", "Mock Student", "default name")
+	flag.StringVar(&s.defaults.Username, "username", "mockstudent", "default preferred username")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal("Failed to generate signing key:", err)
+	}
+	s.key = key
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/userinfo", s.userinfo)
+
+	log.Printf("Mock OIDC provider %s listening on %s", s.issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"userinfo_endpoint":                     s.issuer + "/userinfo",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize approves the request immediately and redirects back with a code.
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("client_id") != s.clientID {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+
+	params := redirectURI.Query()
+	params.Set("state", query.Get("state"))
+
+	if query.Get("response_type") != "code" {
+		params.Set("error", "unsupported_response_type")
+	} else if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		params.Set("error", "invalid_request")
+		params.Set("error_description", "PKCE with S256 is required")
+	} else {
+		code := randomString()
+		s.mu.Lock()
+		s.codes[code] = &authorization{
+			clientID:      s.clientID,
+			redirectURI:   query.Get("redirect_uri"),
+			nonce:         query.Get("nonce"),
+			codeChallenge: query.Get("code_challenge"),
+			identity:      s.identityFor(query),
+			expiresAt:     time.Now().Add(time.Minute),
+		}
+		s.mu.Unlock()
+		params.Set("code", code)
+	}
+
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+	if clientID != s.clientID || clientSecret != s.clientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	auth, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(auth.expiresAt) || auth.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                s.issuer,
+		"sub":                auth.identity.Subject,
+		"aud":                auth.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              auth.nonce,
+		"email":              auth.identity.Email,
+		"email_verified":     auth.identity.EmailVerified,
+		"name":               auth.identity.Name,
+		"preferred_username": auth.identity.Username,
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accessToken := randomString()
+	s.mu.Lock()
+	s.tokens[accessToken] = auth.identity
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (s *server) userinfo(w http.ResponseWriter, r *http.Request) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+		http.Error(w, "missing access token", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	id, ok := s.tokens[header[len(prefix):]]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "invalid access token", http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sub":                id.Subject,
+		"email":              id.Email,
+		"email_verified":     id.EmailVerified,
+		"name":               id.Name,
+		"preferred_username": id.Username,
+	})
+}
+
+func (s *server) identityFor(query url.Values) identity {
+	id := s.defaults
+	if v := query.Get("sub"); v != "" {
+		id.Subject = v
+	}
+	if v := query.Get("email"); v != "" {
+		id.Email = v
+	}
+	if v := query.Get("name"); v != "" {
+		id.Name = v
+	}
+	if v := query.Get("username"); v != "" {
+		id.Username = v
+	}
+	return id
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
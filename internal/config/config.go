@@ -1,12 +1,19 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// DefaultJWTSecret is the development fallback for JWT_SECRET. It is public,
+// so Validate rejects it outside development.
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
 type Config struct {
+	Environment     string
 	DatabasePath    string
 	ServerPort      string
 	JWTSecret       string
@@ -14,18 +21,195 @@ type Config struct {
 	MaxUploadSize   int64
 	RateLimitPerMin int
 	CleanupInterval time.Duration
+
+	NotificationAggregationWindow time.Duration
+	StreamHeartbeatInterval       time.Duration
+	MaxStreamsPerUser             int
+
+	PresenceOnlineWindow  time.Duration
+	PresenceWriteInterval time.Duration
+
+	ScheduledPostCheckInterval time.Duration
+	PollCheckInterval          time.Duration
+
+	MailDriver          string
+	MailFrom            string
+	MailLogPath         string
+	SMTPHost            string
+	SMTPPort            int
+	SMTPUsername        string
+	SMTPPassword        string
+	BaseURL             string
+	UnsubscribeSecret   string
+	DigestCheckInterval time.Duration
+
+	EmailVerificationTTL  time.Duration
+	PasswordResetTTL      time.Duration
+	TwoFactorChallengeTTL time.Duration
+	EmailChangeTTL        time.Duration
+
+	UsernameChangeCooldown time.Duration
+	UsernameRedirectTTL    time.Duration
+
+	AccountDeletionGracePeriod   time.Duration
+	AccountDeletionCheckInterval time.Duration
+	DataExportDir                string
+	DataExportTTL                time.Duration
+	DataExportCheckInterval      time.Duration
+
+	LoginBackoffThreshold   int
+	LoginBackoffBase        time.Duration
+	LoginLockoutThreshold   int
+	LoginLockoutDuration    time.Duration
+	LoginIPLockoutThreshold int
+	LoginFailureWindow      time.Duration
+
+	OIDCProviders   []OIDCProviderConfig
+	OIDCRedirectURL string
+
+	JWTSigningAlgorithm    string
+	JWTKeyRotationInterval time.Duration
+	JWTKeyCheckInterval    time.Duration
+
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	PasswordMinStrength   int
+	PasswordBreachedList  string
+	BcryptCost            int
+}
+
+// OIDCProviderConfig describes one OpenID Connect identity provider. Providers
+// are listed by ID in OIDC_PROVIDERS and configured through OIDC_<ID>_*
+// variables, e.g. OIDC_UNI_ISSUER for the provider "uni".
+type OIDCProviderConfig struct {
+	ID           string
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
 }
 
 func Load() *Config {
+	jwtSecret := getEnv("JWT_SECRET", DefaultJWTSecret)
+	baseURL := getEnv("APP_BASE_URL", "http://localhost:8080")
+
 	return &Config{
+		Environment:     getEnv("APP_ENV", "development"),
 		DatabasePath:    getEnv("DB_PATH", "socialnet.db"),
 		ServerPort:      getEnv("SERVER_PORT", "8080"),
-		JWTSecret:       getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+		JWTSecret:       jwtSecret,
 		SessionDuration: getDuration("SESSION_DURATION", 24*time.Hour),
 		MaxUploadSize:   getInt64("MAX_UPLOAD_SIZE", 10*1024*1024),
 		RateLimitPerMin: getInt("RATE_LIMIT_PER_MIN", 60),
 		CleanupInterval: getDuration("CLEANUP_INTERVAL", 1*time.Hour),
+
+		NotificationAggregationWindow: getDuration("NOTIFICATION_AGGREGATION_WINDOW", 24*time.Hour),
+		StreamHeartbeatInterval:       getDuration("STREAM_HEARTBEAT_INTERVAL", 25*time.Second),
+		MaxStreamsPerUser:             getInt("MAX_STREAMS_PER_USER", 5),
+
+		PresenceOnlineWindow:  getDuration("PRESENCE_ONLINE_WINDOW", 5*time.Minute),
+		PresenceWriteInterval: getDuration("PRESENCE_WRITE_INTERVAL", 1*time.Minute),
+
+		ScheduledPostCheckInterval: getDuration("SCHEDULED_POST_CHECK_INTERVAL", 1*time.Minute),
+		PollCheckInterval:          getDuration("POLL_CHECK_INTERVAL", 1*time.Minute),
+
+		MailDriver:          getEnv("MAIL_DRIVER", "log"),
+		MailFrom:            getEnv("MAIL_FROM", "SocialNet <no-reply@socialnet.local>"),
+		MailLogPath:         getEnv("MAIL_LOG_PATH", ""),
+		SMTPHost:            getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:            getInt("SMTP_PORT", 1025),
+		SMTPUsername:        getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:        getEnv("SMTP_PASSWORD", ""),
+		BaseURL:             baseURL,
+		UnsubscribeSecret:   getEnv("UNSUBSCRIBE_SECRET", jwtSecret),
+		DigestCheckInterval: getDuration("DIGEST_CHECK_INTERVAL", 1*time.Hour),
+
+		EmailVerificationTTL:  getDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		PasswordResetTTL:      getDuration("PASSWORD_RESET_TTL", 1*time.Hour),
+		TwoFactorChallengeTTL: getDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		EmailChangeTTL:        getDuration("EMAIL_CHANGE_TTL", 24*time.Hour),
+
+		UsernameChangeCooldown: getDuration("USERNAME_CHANGE_COOLDOWN", 30*24*time.Hour),
+		UsernameRedirectTTL:    getDuration("USERNAME_REDIRECT_TTL", 90*24*time.Hour),
+
+		AccountDeletionGracePeriod:   getDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+		AccountDeletionCheckInterval: getDuration("ACCOUNT_DELETION_CHECK_INTERVAL", 1*time.Hour),
+		DataExportDir:                getEnv("DATA_EXPORT_DIR", "exports"),
+		DataExportTTL:                getDuration("DATA_EXPORT_TTL", 7*24*time.Hour),
+		DataExportCheckInterval:      getDuration("DATA_EXPORT_CHECK_INTERVAL", 1*time.Minute),
+
+		LoginBackoffThreshold:   getInt("LOGIN_BACKOFF_THRESHOLD", 3),
+		LoginBackoffBase:        getDuration("LOGIN_BACKOFF_BASE", 1*time.Second),
+		LoginLockoutThreshold:   getInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LoginLockoutDuration:    getDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginIPLockoutThreshold: getInt("LOGIN_IP_LOCKOUT_THRESHOLD", 50),
+		LoginFailureWindow:      getDuration("LOGIN_FAILURE_WINDOW", 1*time.Hour),
+
+		OIDCProviders:   loadOIDCProviders(),
+		OIDCRedirectURL: getEnv("OIDC_REDIRECT_URL", baseURL+"/auth/oidc/callback"),
+
+		JWTSigningAlgorithm:    getEnv("JWT_SIGNING_ALGORITHM", "EdDSA"),
+		JWTKeyRotationInterval: getDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+		JWTKeyCheckInterval:    getDuration("JWT_KEY_CHECK_INTERVAL", 1*time.Minute),
+
+		PasswordMinLength:     getInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireUpper:  getBool("PASSWORD_REQUIRE_UPPER", true),
+		PasswordRequireLower:  getBool("PASSWORD_REQUIRE_LOWER", true),
+		PasswordRequireDigit:  getBool("PASSWORD_REQUIRE_DIGIT", true),
+		PasswordRequireSymbol: getBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordMinStrength:   getInt("PASSWORD_MIN_STRENGTH", 2),
+		PasswordBreachedList:  getEnv("PASSWORD_BREACHED_LIST", ""),
+		BcryptCost:            getInt("BCRYPT_COST", 12),
+	}
+}
+
+func (c *Config) IsDevelopment() bool {
+	return c.Environment == "development"
+}
+
+// Validate reports settings the server must not run with.
+func (c *Config) Validate() error {
+	if !c.IsDevelopment() && (c.JWTSecret == DefaultJWTSecret || c.UnsubscribeSecret == DefaultJWTSecret) {
+		return errors.New("JWT_SECRET must be set outside development (APP_ENV=" + c.Environment + ")")
+	}
+	if c.JWTSigningAlgorithm != "EdDSA" && c.JWTSigningAlgorithm != "RS256" {
+		return errors.New("JWT_SIGNING_ALGORITHM must be EdDSA or RS256")
+	}
+	if c.PasswordMinLength < 1 || c.PasswordMinLength > 72 {
+		return errors.New("PASSWORD_MIN_LENGTH must be between 1 and 72")
+	}
+	if c.PasswordMinStrength < 0 || c.PasswordMinStrength > 4 {
+		return errors.New("PASSWORD_MIN_STRENGTH must be between 0 and 4")
+	}
+	if c.BcryptCost < 10 || c.BcryptCost > 31 {
+		return errors.New("BCRYPT_COST must be between 10 and 31")
 	}
+	return nil
+}
+
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, id := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(id) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			ID:           strings.ToLower(id),
+			Name:         getEnv(prefix+"NAME", id),
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+	return providers
 }
 
 func getEnv(key, defaultValue string) string {
@@ -44,6 +228,15 @@ func getInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
 func getInt64(key string, defaultValue int64) int64 {
 	if value := os.Getenv(key); value != "" {
 		if intVal, err := strconv.ParseInt(value, 10, 64); err == nil {
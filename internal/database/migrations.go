@@ -2,19 +2,78 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
 )
 
 func runMigrations(db *sql.DB) error {
+	// Tables whose constraints changed in a way ALTER TABLE cannot express,
+	// such as a widened CHECK, are rebuilt when their stored schema lacks
+	// marker. This runs first so the indexes below are created on the new
+	// tables.
+	rebuilds := []struct {
+		table      string
+		marker     string
+		definition string
+	}{
+		{"auth_tokens", "'email_change'", authTokensTable},
+	}
+
+	for _, r := range rebuilds {
+		if err := rebuildTableIfOutdated(db, r.table, r.marker, r.definition); err != nil {
+			return err
+		}
+	}
+
+	// Tables added later whose first rows derive from existing data. seed
+	// runs once, right after the table is created.
+	seeds := []struct {
+		table string
+		seed  string
+	}{
+		// Friends follow each other.
+		{"follows", `INSERT OR IGNORE INTO follows (follower_id, followee_id, status)
+			SELECT requester_id, addressee_id, 'accepted' FROM friendships WHERE status = 'accepted'
+			UNION ALL
+			SELECT addressee_id, requester_id, 'accepted' FROM friendships WHERE status = 'accepted'`},
+	}
+
+	var pendingSeeds []string
+	for _, s := range seeds {
+		exists, err := tableExists(db, s.table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			pendingSeeds = append(pendingSeeds, s.seed)
+		}
+	}
+
 	queries := []string{
 		`CREATE TABLE IF NOT EXISTS users (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			email TEXT UNIQUE NOT NULL,
-			username TEXT UNIQUE NOT NULL,
+			username TEXT UNIQUE NOT NULL COLLATE NOCASE,
 			password_hash TEXT NOT NULL,
 			full_name TEXT,
 			bio TEXT,
 			avatar_url TEXT,
+			cover_url TEXT,
+			location TEXT,
+			website TEXT,
+			birthday TEXT,
 			is_admin BOOLEAN DEFAULT FALSE,
+			email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+			token_version INTEGER NOT NULL DEFAULT 0,
+			two_factor_required BOOLEAN NOT NULL DEFAULT FALSE,
+			password_set BOOLEAN NOT NULL DEFAULT TRUE,
+			username_changed_at TIMESTAMP,
+			deactivated_at TIMESTAMP,
+			deletion_scheduled_at TIMESTAMP,
+			deleted_at TIMESTAMP,
+			last_seen_at TIMESTAMP,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
 
@@ -23,11 +82,27 @@ func runMigrations(db *sql.DB) error {
 			user_id INTEGER NOT NULL,
 			content TEXT NOT NULL,
 			media_url TEXT,
+			shared_post_id INTEGER,
+			share_type TEXT CHECK(share_type IN ('repost', 'quote')),
+			status TEXT CHECK(status IN ('draft', 'scheduled', 'published')) NOT NULL DEFAULT 'published',
+			publish_at TIMESTAMP,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
 
+		// Earlier versions of edited posts. created_at is when the version
+		// was written and replaced_at when an edit replaced it.
+		`CREATE TABLE IF NOT EXISTS post_revisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			post_id INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			media_url TEXT,
+			created_at TIMESTAMP NOT NULL,
+			replaced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+		)`,
+
 		`CREATE TABLE IF NOT EXISTS comments (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			post_id INTEGER NOT NULL,
@@ -38,10 +113,64 @@ func runMigrations(db *sql.DB) error {
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
 
+		`CREATE TABLE IF NOT EXISTS polls (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			post_id INTEGER UNIQUE NOT NULL,
+			multiple_choice BOOLEAN NOT NULL DEFAULT FALSE,
+			anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+			hide_results BOOLEAN NOT NULL DEFAULT FALSE,
+			ends_at TIMESTAMP,
+			closed_notified_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS poll_options (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			poll_id INTEGER NOT NULL,
+			position INTEGER NOT NULL,
+			text TEXT NOT NULL,
+			FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS poll_votes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			poll_id INTEGER NOT NULL,
+			option_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(option_id, user_id),
+			FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+			FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS bookmark_collections (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL COLLATE NOCASE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS bookmarks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			post_id INTEGER NOT NULL,
+			collection_id INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, post_id),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+			FOREIGN KEY (collection_id) REFERENCES bookmark_collections(id) ON DELETE SET NULL
+		)`,
+
 		`CREATE TABLE IF NOT EXISTS likes (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			post_id INTEGER NOT NULL,
 			user_id INTEGER NOT NULL,
+			reaction TEXT CHECK(reaction IN ('like', 'love', 'haha', 'wow', 'sad', 'angry')) NOT NULL DEFAULT 'like',
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			UNIQUE(post_id, user_id),
 			FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
@@ -122,10 +251,182 @@ func runMigrations(db *sql.DB) error {
 			target_id INTEGER,
 			message TEXT NOT NULL,
 			read BOOLEAN DEFAULT FALSE,
+			actor_count INTEGER NOT NULL DEFAULT 1,
+			seq INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS notification_actors (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			notification_id INTEGER NOT NULL,
+			actor_id INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(notification_id, actor_id),
+			FOREIGN KEY (notification_id) REFERENCES notifications(id) ON DELETE CASCADE,
+			FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS notification_preferences (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			type TEXT NOT NULL,
+			channel TEXT CHECK(channel IN ('in_app', 'email', 'push')) NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			UNIQUE(user_id, type, channel),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS notification_mutes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			target_type TEXT CHECK(target_type IN ('post', 'conversation', 'group')) NOT NULL,
+			target_id INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, target_type, target_id),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS notification_quiet_hours (
+			user_id INTEGER PRIMARY KEY,
+			start_time TEXT NOT NULL,
+			end_time TEXT NOT NULL,
+			timezone TEXT NOT NULL DEFAULT 'UTC',
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS email_digest_settings (
+			user_id INTEGER PRIMARY KEY,
+			frequency TEXT CHECK(frequency IN ('off', 'daily', 'weekly')) NOT NULL DEFAULT 'off',
+			last_sent_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		authTokensTable,
+
+		`CREATE TABLE IF NOT EXISTS user_totp (
+			user_id INTEGER PRIMARY KEY,
+			secret TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			last_used_step INTEGER NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			enabled_at TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
 
+		`CREATE TABLE IF NOT EXISTS recovery_codes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			code_hash TEXT NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS login_failures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			scope TEXT CHECK(scope IN ('account', 'ip')) NOT NULL,
+			key TEXT NOT NULL,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			locked_until TIMESTAMP,
+			UNIQUE(scope, key)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS identities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			email TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_login_at TIMESTAMP,
+			UNIQUE(provider, subject),
+			UNIQUE(user_id, provider),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS oidc_states (
+			state TEXT PRIMARY KEY,
+			provider TEXT NOT NULL,
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			user_id INTEGER,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS personal_access_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			token_prefix TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			expires_at TIMESTAMP,
+			last_used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS username_redirects (
+			username TEXT PRIMARY KEY COLLATE NOCASE,
+			user_id INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS data_exports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			status TEXT CHECK(status IN ('pending', 'ready', 'failed')) NOT NULL DEFAULT 'pending',
+			file_path TEXT,
+			size_bytes INTEGER NOT NULL DEFAULT 0,
+			error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			completed_at TIMESTAMP,
+			expires_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS follows (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			follower_id INTEGER NOT NULL,
+			followee_id INTEGER NOT NULL,
+			status TEXT CHECK(status IN ('pending', 'accepted')) NOT NULL DEFAULT 'accepted',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE(follower_id, followee_id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS privacy_settings (
+			user_id INTEGER PRIMARY KEY,
+			email_visibility TEXT CHECK(email_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'only_me',
+			friends_visibility TEXT CHECK(friends_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'everyone',
+			posts_visibility TEXT CHECK(posts_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'friends',
+			groups_visibility TEXT CHECK(groups_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'everyone',
+			location_visibility TEXT CHECK(location_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'everyone',
+			website_visibility TEXT CHECK(website_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'everyone',
+			birthday_visibility TEXT CHECK(birthday_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'friends',
+			friend_requests TEXT CHECK(friend_requests IN ('everyone', 'friends_of_friends', 'nobody')) NOT NULL DEFAULT 'everyone',
+			searchable BOOLEAN NOT NULL DEFAULT TRUE,
+			approve_followers BOOLEAN NOT NULL DEFAULT FALSE,
+			presence_visibility TEXT CHECK(presence_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'friends',
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS signing_keys (
+			kid TEXT PRIMARY KEY,
+			algorithm TEXT NOT NULL,
+			private_key TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			activates_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP
+		)`,
+
 		`CREATE TABLE IF NOT EXISTS reports (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			reporter_id INTEGER NOT NULL,
@@ -138,13 +439,27 @@ func runMigrations(db *sql.DB) error {
 		)`,
 
 		`CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_post_revisions_post_id ON post_revisions(post_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_likes_post_id ON likes(post_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_poll_options_poll_id ON poll_options(poll_id, position)`,
+		`CREATE INDEX IF NOT EXISTS idx_poll_votes_poll_user ON poll_votes(poll_id, user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_polls_ends_at ON polls(ends_at) WHERE closed_notified_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_bookmarks_user_collection ON bookmarks(user_id, collection_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookmarks_post_id ON bookmarks(post_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_friendships_requester ON friendships(requester_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_friendships_addressee ON friendships(addressee_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_reports_status ON reports(status)`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_mutes_user ON notification_mutes(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_actors_notification ON notification_actors(notification_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_auth_tokens_user ON auth_tokens(user_id, purpose)`,
+		`CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user ON personal_access_tokens(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_username_redirects_user ON username_redirects(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_data_exports_user ON data_exports(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_follows_followee ON follows(followee_id, status)`,
 	}
 
 	for _, query := range queries {
@@ -153,5 +468,275 @@ func runMigrations(db *sql.DB) error {
 		}
 	}
 
+	for _, seed := range pendingSeeds {
+		if _, err := db.Exec(seed); err != nil {
+			return err
+		}
+	}
+
+	// Columns added after the initial schema. CREATE TABLE IF NOT EXISTS
+	// leaves existing databases untouched, so they are added here when missing.
+	// backfill runs once, right after the column is added to an existing table.
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+		backfill   string
+	}{
+		{"notifications", "actor_count", "INTEGER NOT NULL DEFAULT 1", ""},
+		{"notifications", "updated_at", "TIMESTAMP", ""},
+		{"notifications", "seq", "INTEGER", ""},
+		// Accounts created before verification existed are trusted as verified.
+		{"users", "email_verified", "BOOLEAN NOT NULL DEFAULT FALSE", `UPDATE users SET email_verified = TRUE`},
+		{"users", "token_version", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"users", "two_factor_required", "BOOLEAN NOT NULL DEFAULT FALSE", ""},
+		{"users", "password_set", "BOOLEAN NOT NULL DEFAULT TRUE", ""},
+		{"users", "username_changed_at", "TIMESTAMP", ""},
+		{"users", "deactivated_at", "TIMESTAMP", ""},
+		{"users", "deletion_scheduled_at", "TIMESTAMP", ""},
+		{"users", "deleted_at", "TIMESTAMP", ""},
+		{"users", "cover_url", "TEXT", ""},
+		{"users", "location", "TEXT", ""},
+		{"users", "website", "TEXT", ""},
+		{"users", "birthday", "TEXT", ""},
+		{"privacy_settings", "location_visibility",
+			"TEXT CHECK(location_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'everyone'", ""},
+		{"privacy_settings", "website_visibility",
+			"TEXT CHECK(website_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'everyone'", ""},
+		{"privacy_settings", "birthday_visibility",
+			"TEXT CHECK(birthday_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'friends'", ""},
+		{"privacy_settings", "approve_followers", "BOOLEAN NOT NULL DEFAULT FALSE", ""},
+		{"privacy_settings", "presence_visibility",
+			"TEXT CHECK(presence_visibility IN ('everyone', 'friends', 'only_me')) NOT NULL DEFAULT 'friends'", ""},
+		{"users", "last_seen_at", "TIMESTAMP", ""},
+		// Likes from before reactions existed become like reactions.
+		{"likes", "reaction",
+			"TEXT CHECK(reaction IN ('like', 'love', 'haha', 'wow', 'sad', 'angry')) NOT NULL DEFAULT 'like'", ""},
+		{"posts", "shared_post_id", "INTEGER", ""},
+		{"posts", "share_type", "TEXT CHECK(share_type IN ('repost', 'quote'))", ""},
+		{"posts", "status", "TEXT CHECK(status IN ('draft', 'scheduled', 'published')) NOT NULL DEFAULT 'published'", ""},
+		{"posts", "publish_at", "TIMESTAMP", ""},
+		// The address an email change token confirms.
+		{"auth_tokens", "email", "TEXT", ""},
+	}
+
+	for _, c := range columns {
+		added, err := addColumnIfMissing(db, c.table, c.column, c.definition)
+		if err != nil {
+			return err
+		}
+		if added && c.backfill != "" {
+			if _, err := db.Exec(c.backfill); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Indexes on columns from the list above can only be created once the
+	// columns exist.
+	indexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_notifications_user_seq ON notifications(user_id, seq)`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_shared_post_id ON posts(shared_post_id)`,
+		// A user reposts a post at most once; quotes are not limited.
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_repost ON posts(user_id, shared_post_id) WHERE share_type = 'repost'`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts(publish_at) WHERE status = 'scheduled'`,
+	}
+
+	for _, query := range indexes {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	// Usernames used to be unique only when compared case-sensitively.
+	// Older databases keep that constraint, so case-insensitive uniqueness
+	// is enforced by an index, once accounts that collide with an earlier one
+	// are renamed.
+	if err := renameCollidingUsernames(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_nocase ON users(username COLLATE NOCASE)`); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// maxRenamedUsername leaves renamed accounts room to stay within the username
+// length limit, as generated usernames do.
+const maxRenamedUsername = 25
+
+// renameCollidingUsernames gives every account whose username matches an
+// earlier one case-insensitively a free name of the form username_N, and
+// leaves the user a security notification saying so.
+func renameCollidingUsernames(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, username FROM users WHERE EXISTS(
+		SELECT 1 FROM users other WHERE other.username = users.username COLLATE NOCASE AND other.id < users.id)
+		ORDER BY id`)
+	if err != nil {
+		return err
+	}
+
+	type collision struct {
+		id       int64
+		username string
+	}
+	var collisions []collision
+	for rows.Next() {
+		var c collision
+		if err := rows.Scan(&c.id, &c.username); err != nil {
+			rows.Close()
+			return err
+		}
+		collisions = append(collisions, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range collisions {
+		username, err := freeUsername(db, c.username)
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE users SET username = ? WHERE id = ?`, username, c.id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		message := fmt.Sprintf("Your username %s was already taken by another account and has been changed to %s. "+
+			"You can choose a different one in your profile settings.", c.username, username)
+		if _, err := tx.Exec(`INSERT INTO notifications (user_id, type, message, updated_at, seq)
+			VALUES (?, 'security', ?, CURRENT_TIMESTAMP, (SELECT COALESCE(MAX(seq), 0) + 1 FROM notifications))`,
+			c.id, message); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("Renamed user %d from %q to %q: the username collided with an earlier account", c.id, c.username, username)
+	}
+	return nil
+}
+
+// freeUsername returns the first username_N, shortened to fit
+// maxRenamedUsername, that no account uses or redirects from.
+func freeUsername(db *sql.DB, username string) (string, error) {
+	for i := 2; ; i++ {
+		suffix := "_" + strconv.Itoa(i)
+		base := username
+		if len(base)+len(suffix) > maxRenamedUsername {
+			base = base[:maxRenamedUsername-len(suffix)]
+		}
+		candidate := base + suffix
+
+		var taken bool
+		err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE username = ? COLLATE NOCASE)
+			OR EXISTS(SELECT 1 FROM username_redirects WHERE username = ? COLLATE NOCASE)`,
+			candidate, candidate).Scan(&taken)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+}
+
+const authTokensTable = `CREATE TABLE IF NOT EXISTS auth_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			purpose TEXT CHECK(purpose IN ('email_verification', 'password_reset', 'email_change')) NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			email TEXT,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`
+
+// rebuildTableIfOutdated recreates an existing table from definition and
+// copies its rows over, keeping the columns both versions share.
+func rebuildTableIfOutdated(db *sql.DB, table, marker, definition string) error {
+	var schema string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&schema)
+	if err == sql.ErrNoRows || strings.Contains(schema, marker) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	columns, err := tableColumns(db, table)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old := table + "_old"
+	list := strings.Join(columns, ", ")
+	statements := []string{
+		`ALTER TABLE ` + table + ` RENAME TO ` + old,
+		definition,
+		`INSERT INTO ` + table + ` (` + list + `) SELECT ` + list + ` FROM ` + old,
+		`DROP TABLE ` + old,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func tableColumns(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+func tableExists(db *sql.DB, table string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`, table).Scan(&exists)
+	return exists, err
+}
+
+func addColumnIfMissing(db *sql.DB, table, column, definition string) (bool, error) {
+	columns, err := tableColumns(db, table)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range columns {
+		if name == column {
+			return false, nil
+		}
+	}
+
+	if _, err := db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition); err != nil {
+		return false, err
+	}
+	return true, nil
+}
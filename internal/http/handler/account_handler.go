@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"socialnet/internal/http/middleware"
+	"socialnet/internal/model"
+	"socialnet/internal/service"
+	"strconv"
+	"strings"
+)
+
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+func (h *AccountHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var confirmation model.AccountConfirmation
+	if err := json.NewDecoder(r.Body).Decode(&confirmation); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountService.Deactivate(userID, &confirmation); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"account deactivated"}`))
+}
+
+func (h *AccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var confirmation model.AccountConfirmation
+	if err := json.NewDecoder(r.Body).Decode(&confirmation); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.accountService.ScheduleDeletion(userID, &confirmation)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deletion)
+}
+
+func (h *AccountHandler) GetExports(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	exports, err := h.accountService.GetExports(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exports)
+}
+
+func (h *AccountHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	export, err := h.accountService.RequestExport(userID)
+	if err != nil {
+		writeThrottleError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(export)
+}
+
+func (h *AccountHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	exportID, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.accountService.GetExportFile(exportID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filepath.Base(export.FilePath)+`"`)
+	http.ServeFile(w, r, export.FilePath)
+}
@@ -106,3 +106,62 @@ func (h *AdminHandler) DeleteContent(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message":"content deleted"}`))
 }
+
+func (h *AdminHandler) SetTwoFactorRequired(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 5 {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req model.TwoFactorRequirement
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminService.SetTwoFactorRequired(userID, req.Required); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"two-factor requirement updated"}`))
+}
+
+func (h *AdminHandler) GetLockouts(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := h.adminService.GetLockouts()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lockouts)
+}
+
+func (h *AdminHandler) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "invalid lockout ID", http.StatusBadRequest)
+		return
+	}
+
+	lockoutID, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid lockout ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminService.ClearLockout(lockoutID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Write([]byte(`{"message":"lockout cleared"}`))
+}
@@ -2,24 +2,31 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"socialnet/internal/http/middleware"
 	"socialnet/internal/model"
 	"socialnet/internal/security"
 	"socialnet/internal/service"
+	"strconv"
 	"time"
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
-	jwtSecret   string
-	jwtDuration time.Duration
+	authService      *service.AuthService
+	twoFactorService *service.TwoFactorService
+	signingKeys      *security.KeySet
+	jwtDuration      time.Duration
 }
 
-func NewAuthHandler(authService *service.AuthService, jwtSecret string, jwtDuration time.Duration) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, twoFactorService *service.TwoFactorService,
+	signingKeys *security.KeySet, jwtDuration time.Duration) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		jwtSecret:   jwtSecret,
-		jwtDuration: jwtDuration,
+		authService:      authService,
+		twoFactorService: twoFactorService,
+		signingKeys:      signingKeys,
+		jwtDuration:      jwtDuration,
 	}
 }
 
@@ -48,13 +55,97 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.authService.Login(&login)
+	user, challenge, err := h.authService.Login(&login, middleware.ClientIP(r))
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	if challenge != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(challenge)
+		return
+	}
+
+	writeSession(w, user, nil, h.signingKeys, h.jwtDuration)
+}
+
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var login model.TwoFactorLogin
+	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.twoFactorService.CompleteLogin(&login, middleware.ClientIP(r))
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	writeSession(w, user, nil, h.signingKeys, h.jwtDuration)
+}
+
+func (h *AuthHandler) LoginTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	var login model.TwoFactorLogin
+	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	setup, err := h.twoFactorService.SetupFromChallenge(login.ChallengeToken)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	token, err := security.GenerateToken(user.ID, user.IsAdmin, h.jwtSecret, h.jwtDuration)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setup)
+}
+
+func (h *AuthHandler) LoginTwoFactorEnable(w http.ResponseWriter, r *http.Request) {
+	var login model.TwoFactorLogin
+	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, codes, err := h.twoFactorService.EnableFromChallenge(login.ChallengeToken, login.Code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	writeSession(w, user, codes, h.signingKeys, h.jwtDuration)
+}
+
+func writeLoginError(w http.ResponseWriter, err error) {
+	var locked *service.LockedError
+	if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+// writeThrottleError reports a throttled request as 429 with Retry-After, and
+// anything else with the fallback status.
+func writeThrottleError(w http.ResponseWriter, err error, fallback int) {
+	var throttled *service.ThrottledError
+	if errors.As(err, &throttled) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	http.Error(w, err.Error(), fallback)
+}
+
+// writeSession issues the JWT for a completed login. Recovery codes are
+// included when the login also finished two-factor enrollment.
+func writeSession(w http.ResponseWriter, user *model.User, recoveryCodes []string, signingKeys *security.KeySet,
+	jwtDuration time.Duration) {
+	token, err := security.GenerateToken(user.ID, user.IsAdmin, user.TokenVersion, signingKeys, jwtDuration)
 	if err != nil {
 		http.Error(w, "failed to generate token", http.StatusInternalServerError)
 		return
@@ -64,7 +155,199 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		"token": token,
 		"user":  user,
 	}
+	if recoveryCodes != nil {
+		response["recovery_codes"] = recoveryCodes
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var verification model.EmailVerification
+	if err := json.NewDecoder(r.Body).Decode(&verification); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.VerifyEmail(verification.Token); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"email verified"}`))
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	if err := h.authService.ResendVerification(userID); err != nil {
+		writeThrottleError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"verification email sent"}`))
+}
+
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var request model.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(request.Email); err != nil {
+		http.Error(w, "failed to request password reset", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(`{"message":"if the address is registered, a reset link has been sent"}`))
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var reset model.PasswordReset
+	if err := json.NewDecoder(r.Body).Decode(&reset); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ResetPassword(&reset); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"password reset"}`))
+}
+
+// ChangePassword revokes every session, including the one making the
+// request, and answers with a fresh session for it.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var change model.PasswordChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authService.ChangePassword(userID, &change)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeSession(w, user, nil, h.signingKeys, h.jwtDuration)
+}
+
+func (h *AuthHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var change model.EmailChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RequestEmailChange(userID, &change); err != nil {
+		writeThrottleError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"confirmation email sent to the new address"}`))
+}
+
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var verification model.EmailVerification
+	if err := json.NewDecoder(r.Body).Decode(&verification); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ConfirmEmailChange(verification.Token); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"email changed"}`))
+}
+
+func (h *AuthHandler) GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	status, err := h.twoFactorService.GetStatus(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func (h *AuthHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	setup, err := h.twoFactorService.Setup(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setup)
+}
+
+func (h *AuthHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var req model.TwoFactorCode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.twoFactorService.Enable(userID, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&model.RecoveryCodes{Codes: codes})
+}
+
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var req model.TwoFactorDisable
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.twoFactorService.Disable(userID, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"two-factor authentication disabled"}`))
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var req model.TwoFactorCode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&model.RecoveryCodes{Codes: codes})
+}
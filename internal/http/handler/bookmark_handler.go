@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"socialnet/internal/http/middleware"
+	"socialnet/internal/model"
+	"socialnet/internal/service"
+	"strconv"
+	"strings"
+)
+
+type BookmarkHandler struct {
+	bookmarkService *service.BookmarkService
+}
+
+func NewBookmarkHandler(bookmarkService *service.BookmarkService) *BookmarkHandler {
+	return &BookmarkHandler{bookmarkService: bookmarkService}
+}
+
+func (h *BookmarkHandler) GetBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	query := r.URL.Query()
+	filter := &model.BookmarkFilter{}
+	if collectionStr := query.Get("collection_id"); collectionStr != "" {
+		collectionID, err := strconv.ParseInt(collectionStr, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid collection_id", http.StatusBadRequest)
+			return
+		}
+		filter.CollectionID = &collectionID
+	}
+	if beforeStr := query.Get("before"); beforeStr != "" {
+		beforeID, err := strconv.ParseInt(beforeStr, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+		filter.BeforeID = beforeID
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	bookmarks, err := h.bookmarkService.GetBookmarks(userID, filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrCollectionNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bookmarks)
+}
+
+func (h *BookmarkHandler) AddBookmark(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var create model.BookmarkCreate
+	if err := json.NewDecoder(r.Body).Decode(&create); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	bookmark, err := h.bookmarkService.AddBookmark(userID, &create)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bookmark)
+}
+
+func (h *BookmarkHandler) MoveBookmark(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	postID, ok := bookmarkIDFromPath(w, r, "invalid post ID")
+	if !ok {
+		return
+	}
+
+	var move model.BookmarkCreate
+	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	bookmark, err := h.bookmarkService.MoveBookmark(userID, postID, move.CollectionID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrBookmarkNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bookmark)
+}
+
+func (h *BookmarkHandler) RemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	postID, ok := bookmarkIDFromPath(w, r, "invalid post ID")
+	if !ok {
+		return
+	}
+
+	if err := h.bookmarkService.RemoveBookmark(userID, postID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrBookmarkNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Write([]byte(`{"message":"bookmark removed"}`))
+}
+
+func (h *BookmarkHandler) GetCollections(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	collections, err := h.bookmarkService.GetCollections(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collections)
+}
+
+func (h *BookmarkHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var create model.BookmarkCollectionCreate
+	if err := json.NewDecoder(r.Body).Decode(&create); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.bookmarkService.CreateCollection(userID, &create)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *BookmarkHandler) RenameCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	collectionID, ok := bookmarkIDFromPath(w, r, "invalid collection ID")
+	if !ok {
+		return
+	}
+
+	var update model.BookmarkCollectionCreate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.bookmarkService.RenameCollection(collectionID, userID, &update)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrCollectionNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *BookmarkHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	collectionID, ok := bookmarkIDFromPath(w, r, "invalid collection ID")
+	if !ok {
+		return
+	}
+
+	if err := h.bookmarkService.DeleteCollection(collectionID, userID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrCollectionNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Write([]byte(`{"message":"collection deleted"}`))
+}
+
+// bookmarkIDFromPath reads the ID at the end of /bookmarks/{postID} and
+// /bookmarks/collections/{id}.
+func bookmarkIDFromPath(w http.ResponseWriter, r *http.Request, invalid string) (int64, bool) {
+	parts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+	id, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		http.Error(w, invalid, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
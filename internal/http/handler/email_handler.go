@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"socialnet/internal/service"
+)
+
+// unsubscribePage asks for a click before anything changes, so link
+// scanners and mail clients that prefetch the link do not unsubscribe users.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+<form method="post" action="/email/unsubscribe">
+<input type="hidden" name="token" value="{{.}}">
+<p>Stop receiving these emails from SocialNet?</p>
+<button type="submit">Unsubscribe</button>
+</form>
+</body>
+</html>
+`))
+
+type EmailHandler struct {
+	emailService *service.EmailService
+}
+
+func NewEmailHandler(emailService *service.EmailService) *EmailHandler {
+	return &EmailHandler{emailService: emailService}
+}
+
+// ConfirmUnsubscribe serves the link in the email body: a page that posts
+// the token back.
+func (h *EmailHandler) ConfirmUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "token required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	unsubscribePage.Execute(w, token)
+}
+
+// Unsubscribe takes the token from the query, as in one-click unsubscribe
+// (RFC 8058), or from the confirmation form.
+func (h *EmailHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	if token == "" {
+		http.Error(w, "token required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.emailService.Unsubscribe(token); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"unsubscribed"}`))
+}
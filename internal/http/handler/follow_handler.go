@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"socialnet/internal/http/middleware"
+	"socialnet/internal/service"
+	"strconv"
+	"strings"
+)
+
+type FollowHandler struct {
+	followService *service.FollowService
+}
+
+func NewFollowHandler(followService *service.FollowService) *FollowHandler {
+	return &FollowHandler{followService: followService}
+}
+
+func (h *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	followerID := middleware.GetUserID(r)
+
+	followeeID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	follow, err := h.followService.Follow(followerID, followeeID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(follow)
+}
+
+func (h *FollowHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	followerID := middleware.GetUserID(r)
+
+	followeeID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.Unfollow(followerID, followeeID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"unfollowed"}`))
+}
+
+func (h *FollowHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	viewerID := middleware.GetUserID(r)
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	followers, err := h.followService.GetFollowers(userID, viewerID)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(followers)
+}
+
+func (h *FollowHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	viewerID := middleware.GetUserID(r)
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	following, err := h.followService.GetFollowing(userID, viewerID)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(following)
+}
+
+func (h *FollowHandler) GetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	requests, err := h.followService.GetFollowRequests(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+func (h *FollowHandler) AcceptFollowRequest(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	requestID, ok := followRequestIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.AcceptFollowRequest(requestID, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"follow request accepted"}`))
+}
+
+func (h *FollowHandler) DeclineFollowRequest(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	requestID, ok := followRequestIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.DeclineFollowRequest(requestID, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"follow request declined"}`))
+}
+
+// userIDFromPath reads the ID in /users/{id}/...
+func userIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return userID, true
+}
+
+// followRequestIDFromPath reads the ID in /follow-requests/{id}/...
+func followRequestIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 3 {
+		http.Error(w, "invalid request ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	requestID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid request ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return requestID, true
+}
+
+// writeListError reports a missing user as 404 and a private list as 403.
+func writeListError(w http.ResponseWriter, err error) {
+	status := http.StatusForbidden
+	if errors.Is(err, service.ErrUserNotFound) {
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"socialnet/internal/http/middleware"
 	"socialnet/internal/model"
@@ -180,3 +181,32 @@ func (h *GroupHandler) GetUserGroups(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(groups)
 }
+
+func (h *GroupHandler) GetGroupsOfUser(w http.ResponseWriter, r *http.Request) {
+	viewerID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	groups, err := h.groupService.GetGroupsOfUser(userID, viewerID)
+	if err != nil {
+		status := http.StatusForbidden
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"socialnet/internal/service"
+)
+
+type JWKSHandler struct {
+	signingKeyService *service.SigningKeyService
+}
+
+func NewJWKSHandler(signingKeyService *service.SigningKeyService) *JWKSHandler {
+	return &JWKSHandler{signingKeyService: signingKeyService}
+}
+
+// GetJWKS publishes the public halves of the session signing keys so other
+// services can verify tokens. Verifiers should refetch when they see an
+// unknown kid.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.signingKeyService.JWKS())
+}
@@ -27,7 +27,7 @@ func (h *MessageHandler) StartConversation(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	conversation, err := h.messageService.StartConversation(userID, create.ParticipantID)
+	conversation, err := h.messageService.StartConversation(userID, &create)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
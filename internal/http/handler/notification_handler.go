@@ -2,27 +2,49 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"socialnet/internal/http/middleware"
+	"socialnet/internal/model"
 	"socialnet/internal/service"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type NotificationHandler struct {
 	notificationService *service.NotificationService
+	presenceService     *service.PresenceService
+	heartbeatInterval   time.Duration
 }
 
-func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
-	return &NotificationHandler{notificationService: notificationService}
+func NewNotificationHandler(notificationService *service.NotificationService,
+	presenceService *service.PresenceService, heartbeatInterval time.Duration) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		presenceService:     presenceService,
+		heartbeatInterval:   heartbeatInterval,
+	}
 }
 
 func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 
-	notifications, err := h.notificationService.GetNotifications(userID)
+	filter := &model.NotificationFilter{
+		Type: model.NotificationType(r.URL.Query().Get("type")),
+	}
+	if readStr := r.URL.Query().Get("read"); readStr != "" {
+		read, err := strconv.ParseBool(readStr)
+		if err != nil {
+			http.Error(w, "invalid read filter", http.StatusBadRequest)
+			return
+		}
+		filter.Read = &read
+	}
+
+	notifications, err := h.notificationService.GetNotifications(userID, filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -31,6 +53,8 @@ func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Re
 }
 
 func (h *NotificationHandler) MarkAsRead(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) < 3 {
 		http.Error(w, "invalid notification ID", http.StatusBadRequest)
@@ -43,8 +67,8 @@ func (h *NotificationHandler) MarkAsRead(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.notificationService.MarkAsRead(notificationID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.notificationService.MarkAsRead(notificationID, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
@@ -52,6 +76,53 @@ func (h *NotificationHandler) MarkAsRead(w http.ResponseWriter, r *http.Request)
 	w.Write([]byte(`{"message":"notification marked as read"}`))
 }
 
+func (h *NotificationHandler) MarkAllAsRead(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var upToID int64
+	if upToStr := r.URL.Query().Get("up_to_id"); upToStr != "" {
+		id, err := strconv.ParseInt(upToStr, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid notification ID", http.StatusBadRequest)
+			return
+		}
+		upToID = id
+	}
+
+	updated, err := h.notificationService.MarkAllAsRead(userID, upToID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"updated": updated})
+}
+
+func (h *NotificationHandler) DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 3 {
+		http.Error(w, "invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationService.DeleteNotification(notificationID, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"notification deleted"}`))
+}
+
 func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 
@@ -76,3 +147,182 @@ func (h *NotificationHandler) ClearNotifications(w http.ResponseWriter, r *http.
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message":"notifications cleared"}`))
 }
+
+func (h *NotificationHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	settings, err := h.notificationService.GetSettings(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *NotificationHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var update model.NotificationSettingsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.notificationService.UpdateSettings(userID, &update)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *NotificationHandler) Mute(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var create model.NotificationMuteCreate
+	if err := json.NewDecoder(r.Body).Decode(&create); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationService.Mute(userID, &create); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"message":"notifications muted"}`))
+}
+
+func (h *NotificationHandler) Unmute(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 5 {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	targetType := model.MuteTargetType(parts[3])
+	targetID, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid target ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationService.Unmute(userID, targetType, targetID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"notifications unmuted"}`))
+}
+
+// Stream serves notifications as Server-Sent Events. Notification events carry
+// their sequence number as the event ID, so a reconnecting client that sends
+// Last-Event-ID gets everything it missed replayed from the database first.
+func (h *NotificationHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastSeq int64
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastSeq = seq
+	}
+
+	events, err := h.notificationService.Subscribe(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	defer h.notificationService.Unsubscribe(userID, events)
+
+	// An open stream keeps the user online.
+	h.presenceService.Connect(userID)
+	defer h.presenceService.Disconnect(userID)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", 5000)
+
+	// The replay is paged, so it keeps going until nothing newer is left;
+	// stopping after one page would skip the rest for good.
+	for lastSeq > 0 {
+		missed, err := h.notificationService.GetNotificationsSince(userID, lastSeq)
+		if err != nil {
+			return
+		}
+		if len(missed) == 0 {
+			break
+		}
+		for _, notification := range missed {
+			writeEvent(w, &model.NotificationEvent{Seq: notification.Seq, Name: service.EventNotification, Data: notification})
+			lastSeq = notification.Seq
+		}
+		flusher.Flush()
+	}
+
+	count, err := h.notificationService.GetUnreadCount(userID)
+	if err != nil {
+		return
+	}
+	writeEvent(w, &model.NotificationEvent{Name: service.EventUnreadCount, Data: map[string]int{"count": count}})
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			// The hub closes the channel when the stream falls too far
+			// behind; the client reconnects and replays what it missed.
+			if !ok {
+				return
+			}
+			// Anything up to lastSeq was already sent during the replay.
+			if event.Seq != 0 && event.Seq <= lastSeq {
+				continue
+			}
+			if event.Seq != 0 {
+				lastSeq = event.Seq
+			}
+			writeEvent(w, event)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event *model.NotificationEvent) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return
+	}
+	if event.Seq != 0 {
+		fmt.Fprintf(w, "id: %d\n", event.Seq)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, data)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"socialnet/internal/http/middleware"
+	"socialnet/internal/model"
+	"socialnet/internal/security"
+	"socialnet/internal/service"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// oidcFlowCookie holds the hash of the state of a flow the browser started.
+// The callback only goes ahead in the same browser, so nobody can have a
+// victim finish a flow they started themselves.
+const oidcFlowCookie = "oidc_flow"
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+	signingKeys *security.KeySet
+	jwtDuration time.Duration
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService, signingKeys *security.KeySet, jwtDuration time.Duration) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		signingKeys: signingKeys,
+		jwtDuration: jwtDuration,
+	}
+}
+
+func (h *OIDCHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.oidcService.GetProviders())
+}
+
+// Begin serves both /auth/oidc/{provider}/login and, for an authenticated
+// user, /auth/oidc/{provider}/link.
+func (h *OIDCHandler) Begin(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 5 {
+		http.Error(w, "invalid provider", http.StatusBadRequest)
+		return
+	}
+
+	var userID int64
+	if parts[4] == "link" {
+		userID = middleware.GetUserID(r)
+	}
+
+	authorization, err := h.oidcService.Begin(parts[3], userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    security.HashToken(authorization.State),
+		Path:     "/auth/oidc",
+		Expires:  authorization.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(authorization)
+}
+
+// Callback accepts the provider's redirect directly (GET with query
+// parameters) or the code and state forwarded by the frontend as JSON.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var callback model.OIDCCallback
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&callback); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		callback = model.OIDCCallback{
+			Code:             query.Get("code"),
+			State:            query.Get("state"),
+			Error:            query.Get("error"),
+			ErrorDescription: query.Get("error_description"),
+		}
+	}
+
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil || callback.State == "" ||
+		subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(security.HashToken(callback.State))) != 1 {
+		http.Error(w, "sign-in was not started in this browser", http.StatusUnauthorized)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcFlowCookie, Path: "/auth/oidc", MaxAge: -1})
+
+	result, err := h.oidcService.Complete(&callback)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case result.Linked:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":  "identity linked",
+			"identity": result.Identity,
+		})
+	case result.Challenge != nil:
+		json.NewEncoder(w).Encode(result.Challenge)
+	default:
+		writeSession(w, result.User, nil, h.signingKeys, h.jwtDuration)
+	}
+}
+
+func (h *OIDCHandler) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	identities, err := h.oidcService.GetIdentities(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identities)
+}
+
+func (h *OIDCHandler) Unlink(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "invalid identity ID", http.StatusBadRequest)
+		return
+	}
+
+	identityID, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid identity ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.oidcService.Unlink(userID, identityID); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrIdentityNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Write([]byte(`{"message":"identity unlinked"}`))
+}
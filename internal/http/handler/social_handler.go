@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"socialnet/internal/http/middleware"
 	"socialnet/internal/model"
@@ -27,7 +28,7 @@ func (h *SocialHandler) SendFriendRequest(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := h.socialService.SendFriendRequest(userID, req.AddresseeID); err != nil {
+	if err := h.socialService.SendFriendRequest(userID, &req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -97,6 +98,35 @@ func (h *SocialHandler) GetFriends(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(friends)
 }
 
+func (h *SocialHandler) GetUserFriends(w http.ResponseWriter, r *http.Request) {
+	viewerID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	friends, err := h.socialService.GetUserFriends(userID, viewerID)
+	if err != nil {
+		status := http.StatusForbidden
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(friends)
+}
+
 func (h *SocialHandler) GetPendingRequests(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 
@@ -158,6 +188,91 @@ func (h *SocialHandler) UnlikePost(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"message":"post unliked"}`))
 }
 
+func (h *SocialHandler) ReactToPost(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 3 {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var set model.ReactionSet
+	if err := json.NewDecoder(r.Body).Decode(&set); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.socialService.ReactToPost(postID, userID, &set); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"reaction saved"}`))
+}
+
+func (h *SocialHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 3 {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.socialService.UnlikePost(postID, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte(`{"message":"reaction removed"}`))
+}
+
+func (h *SocialHandler) GetReactions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 3 {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	reaction := model.ReactionType(r.URL.Query().Get("reaction"))
+	reactions, err := h.socialService.GetReactions(postID, userID, reaction)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.Error() {
+		case "post not found":
+			status = http.StatusNotFound
+		case "invalid reaction: use like, love, haha, wow, sad or angry":
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reactions)
+}
+
 func (h *SocialHandler) CommentOnPost(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 
@@ -191,6 +306,8 @@ func (h *SocialHandler) CommentOnPost(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SocialHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) < 3 {
 		http.Error(w, "invalid post ID", http.StatusBadRequest)
@@ -203,7 +320,7 @@ func (h *SocialHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comments, err := h.socialService.GetComments(postID)
+	comments, err := h.socialService.GetComments(postID, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
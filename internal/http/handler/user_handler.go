@@ -11,34 +11,94 @@ import (
 )
 
 type UserHandler struct {
-	userService *service.UserService
+	userService    *service.UserService
+	privacyService *service.PrivacyService
 }
 
-func NewUserHandler(userService *service.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService *service.UserService, privacyService *service.PrivacyService) *UserHandler {
+	return &UserHandler{
+		userService:    userService,
+		privacyService: privacyService,
+	}
 }
 
 func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	viewerID := middleware.GetUserID(r)
+
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) < 3 {
 		http.Error(w, "invalid user ID", http.StatusBadRequest)
 		return
 	}
 
+	// /users/@username is the same as /users/by-username/username.
+	if strings.HasPrefix(parts[2], "@") {
+		h.writeProfileByUsername(w, parts[2], viewerID)
+		return
+	}
+
 	userID, err := strconv.ParseInt(parts[2], 10, 64)
 	if err != nil {
 		http.Error(w, "invalid user ID", http.StatusBadRequest)
 		return
 	}
 
-	user, err := h.userService.GetProfile(userID)
+	profile, err := h.userService.GetProfile(userID, viewerID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (h *UserHandler) GetProfileByUsername(w http.ResponseWriter, r *http.Request) {
+	viewerID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 || parts[3] == "" {
+		http.Error(w, "invalid username", http.StatusBadRequest)
+		return
+	}
+
+	h.writeProfileByUsername(w, parts[3], viewerID)
+}
+
+func (h *UserHandler) writeProfileByUsername(w http.ResponseWriter, username string, viewerID int64) {
+	profile, err := h.userService.GetProfileByUsername(username, viewerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (h *UserHandler) GetMutualFriends(w http.ResponseWriter, r *http.Request) {
+	viewerID := middleware.GetUserID(r)
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	friends, err := h.userService.GetMutualFriends(userID, viewerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(friends)
 }
 
 func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
@@ -51,7 +111,7 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.userService.UpdateProfile(userID, &profile); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -59,6 +119,25 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"message":"profile updated"}`))
 }
 
+func (h *UserHandler) ChangeUsername(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var change model.UsernameChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.ChangeUsername(userID, change.Username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
 func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
@@ -75,3 +154,35 @@ func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(users)
 }
+
+func (h *UserHandler) GetPrivacySettings(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	settings, err := h.privacyService.GetSettings(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *UserHandler) UpdatePrivacySettings(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var update model.PrivacySettingsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.privacyService.UpdateSettings(userID, &update)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"socialnet/internal/model"
 	"socialnet/internal/security"
+	"socialnet/internal/service"
 	"strings"
 )
 
@@ -13,14 +16,35 @@ const UserIDKey contextKey = "userID"
 const IsAdminKey contextKey = "isAdmin"
 
 type AuthMiddleware struct {
-	jwtSecret string
+	signingKeys        *security.KeySet
+	authService        *service.AuthService
+	accessTokenService *service.AccessTokenService
+	presenceService    *service.PresenceService
 }
 
-func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
-	return &AuthMiddleware{jwtSecret: jwtSecret}
+func NewAuthMiddleware(signingKeys *security.KeySet, authService *service.AuthService,
+	accessTokenService *service.AccessTokenService, presenceService *service.PresenceService) *AuthMiddleware {
+	return &AuthMiddleware{
+		signingKeys:        signingKeys,
+		authService:        authService,
+		accessTokenService: accessTokenService,
+		presenceService:    presenceService,
+	}
 }
 
+// Authenticate accepts session JWTs only. Routes that bots may call use
+// AuthenticateScope instead.
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return m.authenticate("", next)
+}
+
+// AuthenticateScope accepts session JWTs and personal access tokens that
+// were granted scope.
+func (m *AuthMiddleware) AuthenticateScope(scope model.TokenScope, next http.Handler) http.Handler {
+	return m.authenticate(scope, next)
+}
+
+func (m *AuthMiddleware) authenticate(scope model.TokenScope, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -34,12 +58,44 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		claims, err := security.ValidateToken(parts[1], m.jwtSecret)
+		if security.IsAccessToken(parts[1]) {
+			if scope == "" {
+				http.Error(w, "personal access tokens cannot be used for this endpoint", http.StatusForbidden)
+				return
+			}
+
+			token, err := m.accessTokenService.Authenticate(parts[1])
+			if err != nil {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			if !token.HasScope(scope) {
+				http.Error(w, "token is missing the "+string(scope)+" scope", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+			ctx = context.WithValue(ctx, IsAdminKey, false)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		claims, err := security.ValidateToken(parts[1], m.signingKeys)
 		if err != nil {
 			http.Error(w, "invalid token", http.StatusUnauthorized)
 			return
 		}
 
+		if err := m.authService.CheckSession(claims.UserID, claims.TokenVersion); err != nil {
+			http.Error(w, "session expired", http.StatusUnauthorized)
+			return
+		}
+
+		// Only sessions count as activity; access tokens are used by bots
+		// and integrations while the user is away.
+		m.presenceService.Touch(claims.UserID)
+
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, IsAdminKey, claims.IsAdmin)
 
@@ -62,3 +118,13 @@ func IsAdmin(r *http.Request) bool {
 	}
 	return isAdmin
 }
+
+// ClientIP returns the address of the connecting client without the port.
+// Forwarding headers are ignored because clients can set them freely.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -26,7 +26,6 @@ func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 		ip := r.RemoteAddr
 
 		rl.mu.Lock()
-		defer rl.mu.Unlock()
 
 		now := time.Now()
 		windowStart := now.Add(-rl.window)
@@ -40,13 +39,17 @@ func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 		}
 
 		if len(validRequests) >= rl.limit {
+			rl.mu.Unlock()
 			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
 
 		validRequests = append(validRequests, now)
 		rl.requests[ip] = validRequests
+		rl.mu.Unlock()
 
+		// The lock must not be held while serving: long-lived requests such as
+		// the notification stream would otherwise block every other client.
 		next.ServeHTTP(w, r)
 	})
 }
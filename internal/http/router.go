@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"socialnet/internal/http/handler"
 	"socialnet/internal/http/middleware"
+	"socialnet/internal/model"
 	"strings"
 )
 
@@ -16,6 +17,13 @@ type Router struct {
 	groupHandler        *handler.GroupHandler
 	notificationHandler *handler.NotificationHandler
 	adminHandler        *handler.AdminHandler
+	emailHandler        *handler.EmailHandler
+	oidcHandler         *handler.OIDCHandler
+	accessTokenHandler  *handler.AccessTokenHandler
+	jwksHandler         *handler.JWKSHandler
+	accountHandler      *handler.AccountHandler
+	followHandler       *handler.FollowHandler
+	bookmarkHandler     *handler.BookmarkHandler
 	authMiddleware      *middleware.AuthMiddleware
 	rateLimiter         *middleware.RateLimiter
 }
@@ -29,6 +37,13 @@ func NewRouter(
 	groupHandler *handler.GroupHandler,
 	notificationHandler *handler.NotificationHandler,
 	adminHandler *handler.AdminHandler,
+	emailHandler *handler.EmailHandler,
+	oidcHandler *handler.OIDCHandler,
+	accessTokenHandler *handler.AccessTokenHandler,
+	jwksHandler *handler.JWKSHandler,
+	accountHandler *handler.AccountHandler,
+	followHandler *handler.FollowHandler,
+	bookmarkHandler *handler.BookmarkHandler,
 	authMiddleware *middleware.AuthMiddleware,
 	rateLimiter *middleware.RateLimiter,
 ) *Router {
@@ -41,6 +56,13 @@ func NewRouter(
 		groupHandler:        groupHandler,
 		notificationHandler: notificationHandler,
 		adminHandler:        adminHandler,
+		emailHandler:        emailHandler,
+		oidcHandler:         oidcHandler,
+		accessTokenHandler:  accessTokenHandler,
+		jwksHandler:         jwksHandler,
+		accountHandler:      accountHandler,
+		followHandler:       followHandler,
+		bookmarkHandler:     bookmarkHandler,
 		authMiddleware:      authMiddleware,
 		rateLimiter:         rateLimiter,
 	}
@@ -52,18 +74,346 @@ func (rt *Router) Setup() http.Handler {
 	mux.HandleFunc("/register", rt.authHandler.Register)
 	mux.HandleFunc("/login", rt.authHandler.Login)
 
+	mux.HandleFunc("/login/2fa", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authHandler.LoginTwoFactor(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/login/2fa/setup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authHandler.LoginTwoFactorSetup(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/login/2fa/enable", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authHandler.LoginTwoFactorEnable(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/2fa", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.authHandler.GetTwoFactorStatus)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/2fa/setup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.authHandler.SetupTwoFactor)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/2fa/enable", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.authHandler.EnableTwoFactor)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/2fa/disable", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.authHandler.DisableTwoFactor)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/2fa/recovery-codes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.authHandler.RegenerateRecoveryCodes)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/oidc/providers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.oidcHandler.GetProviders(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/oidc/callback", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodPost {
+			rt.oidcHandler.Callback(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/oidc/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/login") {
+			rt.oidcHandler.Begin(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/link") {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.oidcHandler.Begin)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+
+	mux.HandleFunc("/auth/identities", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.oidcHandler.GetIdentities)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/identities/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.oidcHandler.Unlink)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.jwksHandler.GetJWKS(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.accessTokenHandler.GetTokens)).ServeHTTP(w, r)
+		} else if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.accessTokenHandler.CreateToken)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/tokens/scopes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.accessTokenHandler.GetScopes(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/tokens/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.accessTokenHandler.RevokeToken)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/verify-email", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authHandler.VerifyEmail(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/verify-email/resend", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.authHandler.ResendVerification)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/password-reset", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authHandler.RequestPasswordReset(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/password-reset/confirm", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authHandler.ResetPassword(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/password", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.authHandler.ChangePassword)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/email", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.authHandler.RequestEmailChange)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/email/confirm", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authHandler.ConfirmEmailChange(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/auth/username", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.userHandler.ChangeUsername)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Account routes
+	mux.HandleFunc("/account/deactivate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.accountHandler.Deactivate)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/account/delete", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.accountHandler.Delete)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/account/privacy", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.AuthenticateScope(model.ScopeProfileRead, http.HandlerFunc(rt.userHandler.GetPrivacySettings)).ServeHTTP(w, r)
+		} else if r.Method == http.MethodPut {
+			rt.authMiddleware.AuthenticateScope(model.ScopeProfileWrite, http.HandlerFunc(rt.userHandler.UpdatePrivacySettings)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/account/exports", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.accountHandler.GetExports)).ServeHTTP(w, r)
+		} else if r.Method == http.MethodPost {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.accountHandler.RequestExport)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/account/exports/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/download") {
+			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.accountHandler.DownloadExport)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/users/by-username/") {
+			if r.Method == http.MethodGet {
+				rt.authMiddleware.AuthenticateScope(model.ScopeProfileRead, http.HandlerFunc(rt.userHandler.GetProfileByUsername)).ServeHTTP(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		if strings.HasSuffix(r.URL.Path, "/search") {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.userHandler.SearchUsers)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeProfileRead, http.HandlerFunc(rt.userHandler.SearchUsers)).ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/friends") {
+			if r.Method == http.MethodGet {
+				rt.authMiddleware.AuthenticateScope(model.ScopeFriendsRead, http.HandlerFunc(rt.socialHandler.GetUserFriends)).ServeHTTP(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/mutual-friends") {
+			if r.Method == http.MethodGet {
+				rt.authMiddleware.AuthenticateScope(model.ScopeFriendsRead, http.HandlerFunc(rt.userHandler.GetMutualFriends)).ServeHTTP(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/posts") {
+			if r.Method == http.MethodGet {
+				rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.postHandler.GetUserPosts)).ServeHTTP(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/groups") {
+			if r.Method == http.MethodGet {
+				rt.authMiddleware.AuthenticateScope(model.ScopeGroupsRead, http.HandlerFunc(rt.groupHandler.GetGroupsOfUser)).ServeHTTP(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/follow") {
+			if r.Method == http.MethodPost {
+				rt.authMiddleware.AuthenticateScope(model.ScopeFriendsWrite, http.HandlerFunc(rt.followHandler.Follow)).ServeHTTP(w, r)
+			} else if r.Method == http.MethodDelete {
+				rt.authMiddleware.AuthenticateScope(model.ScopeFriendsWrite, http.HandlerFunc(rt.followHandler.Unfollow)).ServeHTTP(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/followers") {
+			if r.Method == http.MethodGet {
+				rt.authMiddleware.AuthenticateScope(model.ScopeFriendsRead, http.HandlerFunc(rt.followHandler.GetFollowers)).ServeHTTP(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/following") {
+			if r.Method == http.MethodGet {
+				rt.authMiddleware.AuthenticateScope(model.ScopeFriendsRead, http.HandlerFunc(rt.followHandler.GetFollowing)).ServeHTTP(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
 			return
 		}
 
 		parts := strings.Split(r.URL.Path, "/")
 		if len(parts) >= 3 && parts[2] != "" {
 			if r.Method == http.MethodGet {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.userHandler.GetProfile)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopeProfileRead, http.HandlerFunc(rt.userHandler.GetProfile)).ServeHTTP(w, r)
 			} else if r.Method == http.MethodPut {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.userHandler.UpdateProfile)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopeProfileWrite, http.HandlerFunc(rt.userHandler.UpdateProfile)).ServeHTTP(w, r)
 			} else {
 				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			}
@@ -74,7 +424,15 @@ func (rt *Router) Setup() http.Handler {
 
 	mux.HandleFunc("/posts", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.postHandler.CreatePost)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.CreatePost)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/posts/drafts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.postHandler.GetDrafts)).ServeHTTP(w, r)
 		} else {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -85,41 +443,168 @@ func (rt *Router) Setup() http.Handler {
 		if len(parts) >= 3 && parts[2] != "" {
 			if strings.HasSuffix(r.URL.Path, "/like") {
 				if r.Method == http.MethodPost {
-					rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.LikePost)).ServeHTTP(w, r)
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.socialHandler.LikePost)).ServeHTTP(w, r)
+				} else if r.Method == http.MethodDelete {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.socialHandler.UnlikePost)).ServeHTTP(w, r)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/repost") {
+				if r.Method == http.MethodPost {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.Repost)).ServeHTTP(w, r)
+				} else if r.Method == http.MethodDelete {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.Unrepost)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/quote") {
+				if r.Method == http.MethodPost {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.QuotePost)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/schedule") {
+				if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.SchedulePost)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/publish") {
+				if r.Method == http.MethodPost {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.PublishPost)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/revisions") {
+				if r.Method == http.MethodGet {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.postHandler.GetRevisions)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/poll/vote") {
+				if r.Method == http.MethodPut {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.Vote)).ServeHTTP(w, r)
 				} else if r.Method == http.MethodDelete {
-					rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.UnlikePost)).ServeHTTP(w, r)
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.RemoveVote)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/poll") {
+				if r.Method == http.MethodGet {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.postHandler.GetPoll)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/reaction") {
+				if r.Method == http.MethodPut {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.socialHandler.ReactToPost)).ServeHTTP(w, r)
+				} else if r.Method == http.MethodDelete {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.socialHandler.RemoveReaction)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/reactions") {
+				if r.Method == http.MethodGet {
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.socialHandler.GetReactions)).ServeHTTP(w, r)
+				} else {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 				}
 				return
 			}
 
 			if strings.HasSuffix(r.URL.Path, "/comments") {
 				if r.Method == http.MethodPost {
-					rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.CommentOnPost)).ServeHTTP(w, r)
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.socialHandler.CommentOnPost)).ServeHTTP(w, r)
 				} else if r.Method == http.MethodGet {
-					rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.GetComments)).ServeHTTP(w, r)
+					rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.socialHandler.GetComments)).ServeHTTP(w, r)
 				}
 				return
 			}
 
 			if r.Method == http.MethodGet {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.postHandler.GetPost)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.postHandler.GetPost)).ServeHTTP(w, r)
 			} else if r.Method == http.MethodPut {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.postHandler.UpdatePost)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.UpdatePost)).ServeHTTP(w, r)
 			} else if r.Method == http.MethodDelete {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.postHandler.DeletePost)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.postHandler.DeletePost)).ServeHTTP(w, r)
 			}
 			return
 		}
 		http.Error(w, "not found", http.StatusNotFound)
 	})
 
+	mux.HandleFunc("/bookmarks", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.bookmarkHandler.GetBookmarks)).ServeHTTP(w, r)
+		} else if r.Method == http.MethodPost {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.bookmarkHandler.AddBookmark)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/bookmarks/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.bookmarkHandler.MoveBookmark)).ServeHTTP(w, r)
+		} else if r.Method == http.MethodDelete {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.bookmarkHandler.RemoveBookmark)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/bookmarks/collections", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.bookmarkHandler.GetCollections)).ServeHTTP(w, r)
+		} else if r.Method == http.MethodPost {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.bookmarkHandler.CreateCollection)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/bookmarks/collections/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.bookmarkHandler.RenameCollection)).ServeHTTP(w, r)
+		} else if r.Method == http.MethodDelete {
+			rt.authMiddleware.AuthenticateScope(model.ScopePostsWrite, http.HandlerFunc(rt.bookmarkHandler.DeleteCollection)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
-		rt.authMiddleware.Authenticate(http.HandlerFunc(rt.postHandler.GetFeed)).ServeHTTP(w, r)
+		rt.authMiddleware.AuthenticateScope(model.ScopePostsRead, http.HandlerFunc(rt.postHandler.GetFeed)).ServeHTTP(w, r)
 	})
 
 	mux.HandleFunc("/friends", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.GetFriends)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeFriendsRead, http.HandlerFunc(rt.socialHandler.GetFriends)).ServeHTTP(w, r)
 		} else {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -127,34 +612,58 @@ func (rt *Router) Setup() http.Handler {
 
 	mux.HandleFunc("/friends/request", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.SendFriendRequest)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeFriendsWrite, http.HandlerFunc(rt.socialHandler.SendFriendRequest)).ServeHTTP(w, r)
 		} else {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 
 	mux.HandleFunc("/friends/pending", func(w http.ResponseWriter, r *http.Request) {
-		rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.GetPendingRequests)).ServeHTTP(w, r)
+		rt.authMiddleware.AuthenticateScope(model.ScopeFriendsRead, http.HandlerFunc(rt.socialHandler.GetPendingRequests)).ServeHTTP(w, r)
 	})
 
 	mux.HandleFunc("/friends/", func(w http.ResponseWriter, r *http.Request) {
 		parts := strings.Split(r.URL.Path, "/")
 		if len(parts) >= 3 && parts[2] != "" {
 			if strings.HasSuffix(r.URL.Path, "/accept") {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.AcceptFriendRequest)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopeFriendsWrite, http.HandlerFunc(rt.socialHandler.AcceptFriendRequest)).ServeHTTP(w, r)
 			} else if strings.HasSuffix(r.URL.Path, "/block") {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.socialHandler.BlockUser)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopeFriendsWrite, http.HandlerFunc(rt.socialHandler.BlockUser)).ServeHTTP(w, r)
 			}
 			return
 		}
 		http.Error(w, "not found", http.StatusNotFound)
 	})
 
+	mux.HandleFunc("/follow-requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.AuthenticateScope(model.ScopeFriendsRead, http.HandlerFunc(rt.followHandler.GetFollowRequests)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/follow-requests/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/accept") {
+			rt.authMiddleware.AuthenticateScope(model.ScopeFriendsWrite, http.HandlerFunc(rt.followHandler.AcceptFollowRequest)).ServeHTTP(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/decline") {
+			rt.authMiddleware.AuthenticateScope(model.ScopeFriendsWrite, http.HandlerFunc(rt.followHandler.DeclineFollowRequest)).ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
 	mux.HandleFunc("/conversations", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.messageHandler.StartConversation)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeMessagesWrite, http.HandlerFunc(rt.messageHandler.StartConversation)).ServeHTTP(w, r)
 		} else if r.Method == http.MethodGet {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.messageHandler.GetConversations)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeMessagesRead, http.HandlerFunc(rt.messageHandler.GetConversations)).ServeHTTP(w, r)
 		}
 	})
 
@@ -163,9 +672,9 @@ func (rt *Router) Setup() http.Handler {
 		if len(parts) >= 3 && parts[2] != "" {
 			if strings.HasSuffix(r.URL.Path, "/messages") {
 				if r.Method == http.MethodPost {
-					rt.authMiddleware.Authenticate(http.HandlerFunc(rt.messageHandler.SendMessage)).ServeHTTP(w, r)
+					rt.authMiddleware.AuthenticateScope(model.ScopeMessagesWrite, http.HandlerFunc(rt.messageHandler.SendMessage)).ServeHTTP(w, r)
 				} else if r.Method == http.MethodGet {
-					rt.authMiddleware.Authenticate(http.HandlerFunc(rt.messageHandler.GetMessages)).ServeHTTP(w, r)
+					rt.authMiddleware.AuthenticateScope(model.ScopeMessagesRead, http.HandlerFunc(rt.messageHandler.GetMessages)).ServeHTTP(w, r)
 				}
 			}
 			return
@@ -175,9 +684,9 @@ func (rt *Router) Setup() http.Handler {
 
 	mux.HandleFunc("/groups", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.groupHandler.CreateGroup)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeGroupsWrite, http.HandlerFunc(rt.groupHandler.CreateGroup)).ServeHTTP(w, r)
 		} else if r.Method == http.MethodGet {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.groupHandler.GetUserGroups)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeGroupsRead, http.HandlerFunc(rt.groupHandler.GetUserGroups)).ServeHTTP(w, r)
 		}
 	})
 
@@ -185,17 +694,17 @@ func (rt *Router) Setup() http.Handler {
 		parts := strings.Split(r.URL.Path, "/")
 		if len(parts) >= 3 && parts[2] != "" {
 			if strings.HasSuffix(r.URL.Path, "/join") {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.groupHandler.JoinGroup)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopeGroupsWrite, http.HandlerFunc(rt.groupHandler.JoinGroup)).ServeHTTP(w, r)
 			} else if strings.HasSuffix(r.URL.Path, "/leave") {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.groupHandler.LeaveGroup)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopeGroupsWrite, http.HandlerFunc(rt.groupHandler.LeaveGroup)).ServeHTTP(w, r)
 			} else if strings.HasSuffix(r.URL.Path, "/posts") {
 				if r.Method == http.MethodPost {
-					rt.authMiddleware.Authenticate(http.HandlerFunc(rt.groupHandler.PostToGroup)).ServeHTTP(w, r)
+					rt.authMiddleware.AuthenticateScope(model.ScopeGroupsWrite, http.HandlerFunc(rt.groupHandler.PostToGroup)).ServeHTTP(w, r)
 				} else if r.Method == http.MethodGet {
-					rt.authMiddleware.Authenticate(http.HandlerFunc(rt.groupHandler.GetGroupPosts)).ServeHTTP(w, r)
+					rt.authMiddleware.AuthenticateScope(model.ScopeGroupsRead, http.HandlerFunc(rt.groupHandler.GetGroupPosts)).ServeHTTP(w, r)
 				}
 			} else if r.Method == http.MethodGet {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.groupHandler.GetGroup)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopeGroupsRead, http.HandlerFunc(rt.groupHandler.GetGroup)).ServeHTTP(w, r)
 			}
 			return
 		}
@@ -204,9 +713,9 @@ func (rt *Router) Setup() http.Handler {
 
 	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.notificationHandler.GetNotifications)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsRead, http.HandlerFunc(rt.notificationHandler.GetNotifications)).ServeHTTP(w, r)
 		} else if r.Method == http.MethodDelete {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.notificationHandler.ClearNotifications)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsWrite, http.HandlerFunc(rt.notificationHandler.ClearNotifications)).ServeHTTP(w, r)
 		}
 	})
 
@@ -214,7 +723,9 @@ func (rt *Router) Setup() http.Handler {
 		parts := strings.Split(r.URL.Path, "/")
 		if len(parts) >= 3 && parts[2] != "" {
 			if strings.HasSuffix(r.URL.Path, "/read") {
-				rt.authMiddleware.Authenticate(http.HandlerFunc(rt.notificationHandler.MarkAsRead)).ServeHTTP(w, r)
+				rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsWrite, http.HandlerFunc(rt.notificationHandler.MarkAsRead)).ServeHTTP(w, r)
+			} else if r.Method == http.MethodDelete {
+				rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsWrite, http.HandlerFunc(rt.notificationHandler.DeleteNotification)).ServeHTTP(w, r)
 			}
 			return
 		}
@@ -222,12 +733,64 @@ func (rt *Router) Setup() http.Handler {
 	})
 
 	mux.HandleFunc("/notifications/unread", func(w http.ResponseWriter, r *http.Request) {
-		rt.authMiddleware.Authenticate(http.HandlerFunc(rt.notificationHandler.GetUnreadCount)).ServeHTTP(w, r)
+		rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsRead, http.HandlerFunc(rt.notificationHandler.GetUnreadCount)).ServeHTTP(w, r)
+	})
+
+	mux.HandleFunc("/notifications/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsRead, http.HandlerFunc(rt.notificationHandler.Stream)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/notifications/read-all", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsWrite, http.HandlerFunc(rt.notificationHandler.MarkAllAsRead)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/notifications/settings", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsRead, http.HandlerFunc(rt.notificationHandler.GetSettings)).ServeHTTP(w, r)
+		} else if r.Method == http.MethodPut {
+			rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsWrite, http.HandlerFunc(rt.notificationHandler.UpdateSettings)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/notifications/mutes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsWrite, http.HandlerFunc(rt.notificationHandler.Mute)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/notifications/mutes/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			rt.authMiddleware.AuthenticateScope(model.ScopeNotificationsWrite, http.HandlerFunc(rt.notificationHandler.Unmute)).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/email/unsubscribe", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.emailHandler.ConfirmUnsubscribe(w, r)
+		} else if r.Method == http.MethodPost {
+			rt.emailHandler.Unsubscribe(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
 	})
 
 	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			rt.authMiddleware.Authenticate(http.HandlerFunc(rt.adminHandler.CreateReport)).ServeHTTP(w, r)
+			rt.authMiddleware.AuthenticateScope(model.ScopeReportsWrite, http.HandlerFunc(rt.adminHandler.CreateReport)).ServeHTTP(w, r)
 		}
 	})
 
@@ -249,6 +812,36 @@ func (rt *Router) Setup() http.Handler {
 		).ServeHTTP(w, r)
 	})
 
+	mux.HandleFunc("/admin/lockouts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			rt.authMiddleware.Authenticate(
+				middleware.RequireAdmin(http.HandlerFunc(rt.adminHandler.GetLockouts)),
+			).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/admin/lockouts/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			rt.authMiddleware.Authenticate(
+				middleware.RequireAdmin(http.HandlerFunc(rt.adminHandler.ClearLockout)),
+			).ServeHTTP(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/admin/users/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/2fa-required") {
+			rt.authMiddleware.Authenticate(
+				middleware.RequireAdmin(http.HandlerFunc(rt.adminHandler.SetTwoFactorRequired)),
+			).ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
 			w.Write([]byte(`{"message":"SocialNet API","version":"1.0"}`))
@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	Headers map[string]string
+}
+
+type Mailer interface {
+	Send(message *Message) error
+}
+
+// Bytes renders the message as a plain-text RFC 5322 email.
+func (m *Message) Bytes(from string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+
+	keys := make([]string, 0, len(m.Headers))
+	for key := range m.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, m.Headers[key])
+	}
+
+	buf.WriteString("\r\n")
+	buf.WriteString(m.Body)
+	return buf.Bytes()
+}
+
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer sends through an SMTP server. Authentication is skipped when
+// no username is given, which is what local stand-ins like MailHog expect.
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		addr: host + ":" + strconv.Itoa(port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (m *SMTPMailer) Send(message *Message) error {
+	// The envelope sender must be a bare address, while the From header may
+	// carry a display name.
+	sender := m.from
+	if address, err := netmail.ParseAddress(m.from); err == nil {
+		sender = address.Address
+	}
+	return smtp.SendMail(m.addr, m.auth, sender, []string{message.To}, message.Bytes(m.from))
+}
+
+// LogMailer is the development sink. It appends every email to the file at
+// path, or writes it to the standard logger when path is empty.
+type LogMailer struct {
+	path string
+	from string
+	mu   sync.Mutex
+}
+
+func NewLogMailer(path, from string) *LogMailer {
+	return &LogMailer{path: path, from: from}
+}
+
+func (m *LogMailer) Send(message *Message) error {
+	raw := message.Bytes(m.from)
+
+	if m.path == "" {
+		log.Printf("Email to %s:\n%s", message.To, raw)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(raw); err != nil {
+		return err
+	}
+	_, err = file.WriteString("\r\n\r\n")
+	return err
+}
@@ -0,0 +1,172 @@
+package mail
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+)
+
+type FriendRequestData struct {
+	RecipientName  string
+	ActorName      string
+	BaseURL        string
+	UnsubscribeURL string
+}
+
+type MentionData struct {
+	RecipientName  string
+	ActorName      string
+	PostID         int64
+	BaseURL        string
+	UnsubscribeURL string
+}
+
+type DigestItem struct {
+	Message   string
+	CreatedAt time.Time
+}
+
+type DigestData struct {
+	RecipientName  string
+	Period         string
+	Items          []DigestItem
+	BaseURL        string
+	UnsubscribeURL string
+}
+
+type AccountLinkData struct {
+	RecipientName string
+	Link          string
+	ExpiresIn     string
+}
+
+type SecurityAlertData struct {
+	RecipientName string
+	Message       string
+	BaseURL       string
+}
+
+var (
+	friendRequestTemplate = template.Must(template.New("friend_request").Parse(
+		`Hi {{.RecipientName}},
+
+{{.ActorName}} sent you a friend request on SocialNet.
+
+Review your pending requests: {{.BaseURL}}/friends
+
+--
+Stop these emails: {{.UnsubscribeURL}}
+`))
+
+	mentionTemplate = template.Must(template.New("mention").Parse(
+		`Hi {{.RecipientName}},
+
+{{.ActorName}} mentioned you on SocialNet.
+
+See the post: {{.BaseURL}}/posts/{{.PostID}}
+
+--
+Stop these emails: {{.UnsubscribeURL}}
+`))
+
+	digestTemplate = template.Must(template.New("digest").Parse(
+		`Hi {{.RecipientName}},
+
+Here is what you missed on SocialNet {{.Period}}:
+{{range .Items}}
+- {{.Message}} ({{.CreatedAt.Format "Jan 2, 15:04"}}){{end}}
+
+Open your notifications: {{.BaseURL}}/notifications
+
+--
+Stop these digests: {{.UnsubscribeURL}}
+`))
+
+	verificationTemplate = template.Must(template.New("verification").Parse(
+		`Hi {{.RecipientName}},
+
+Please confirm your email address for SocialNet:
+
+{{.Link}}
+
+This link expires in {{.ExpiresIn}}. If you did not create an account, you can ignore this email.
+`))
+
+	emailChangeTemplate = template.Must(template.New("email_change").Parse(
+		`Hi {{.RecipientName}},
+
+Please confirm that you want to use this address for your SocialNet account:
+
+{{.Link}}
+
+This link expires in {{.ExpiresIn}}. Until then your account keeps using its current address. If you did not ask for this change, you can ignore this email.
+`))
+
+	dataExportTemplate = template.Must(template.New("data_export").Parse(
+		`Hi {{.RecipientName}},
+
+The copy of your SocialNet data you asked for is ready. Download it from your account settings:
+
+{{.Link}}
+
+The archive is deleted after {{.ExpiresIn}}.
+`))
+
+	securityAlertTemplate = template.Must(template.New("security_alert").Parse(
+		`Hi {{.RecipientName}},
+
+{{.Message}}
+
+Reset your password: {{.BaseURL}}/forgot-password
+`))
+
+	passwordResetTemplate = template.Must(template.New("password_reset").Parse(
+		`Hi {{.RecipientName}},
+
+Someone asked to reset the password of your SocialNet account. Choose a new one here:
+
+{{.Link}}
+
+This link expires in {{.ExpiresIn}} and can only be used once. If you did not ask for a reset, you can ignore this email.
+`))
+)
+
+func RenderFriendRequest(data *FriendRequestData) (string, error) {
+	return render(friendRequestTemplate, data)
+}
+
+func RenderMention(data *MentionData) (string, error) {
+	return render(mentionTemplate, data)
+}
+
+func RenderDigest(data *DigestData) (string, error) {
+	return render(digestTemplate, data)
+}
+
+func RenderVerification(data *AccountLinkData) (string, error) {
+	return render(verificationTemplate, data)
+}
+
+func RenderPasswordReset(data *AccountLinkData) (string, error) {
+	return render(passwordResetTemplate, data)
+}
+
+func RenderEmailChange(data *AccountLinkData) (string, error) {
+	return render(emailChangeTemplate, data)
+}
+
+func RenderDataExport(data *AccountLinkData) (string, error) {
+	return render(dataExportTemplate, data)
+}
+
+func RenderSecurityAlert(data *SecurityAlertData) (string, error) {
+	return render(securityAlertTemplate, data)
+}
+
+func render(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
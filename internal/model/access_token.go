@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+type TokenScope string
+
+const (
+	ScopeProfileRead        TokenScope = "profile:read"
+	ScopeProfileWrite       TokenScope = "profile:write"
+	ScopePostsRead          TokenScope = "posts:read"
+	ScopePostsWrite         TokenScope = "posts:write"
+	ScopeFriendsRead        TokenScope = "friends:read"
+	ScopeFriendsWrite       TokenScope = "friends:write"
+	ScopeMessagesRead       TokenScope = "messages:read"
+	ScopeMessagesWrite      TokenScope = "messages:write"
+	ScopeGroupsRead         TokenScope = "groups:read"
+	ScopeGroupsWrite        TokenScope = "groups:write"
+	ScopeNotificationsRead  TokenScope = "notifications:read"
+	ScopeNotificationsWrite TokenScope = "notifications:write"
+	ScopeReportsWrite       TokenScope = "reports:write"
+)
+
+var TokenScopes = []TokenScope{
+	ScopeProfileRead, ScopeProfileWrite,
+	ScopePostsRead, ScopePostsWrite,
+	ScopeFriendsRead, ScopeFriendsWrite,
+	ScopeMessagesRead, ScopeMessagesWrite,
+	ScopeGroupsRead, ScopeGroupsWrite,
+	ScopeNotificationsRead, ScopeNotificationsWrite,
+	ScopeReportsWrite,
+}
+
+func IsValidScope(scope TokenScope) bool {
+	for _, s := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonalAccessToken is a long-lived credential for bots and scripts. Only
+// a hash of the token is stored; Prefix is kept so users can tell their
+// tokens apart.
+type PersonalAccessToken struct {
+	ID         int64        `json:"id"`
+	UserID     int64        `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []TokenScope `json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+func (t *PersonalAccessToken) HasScope(scope TokenScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type PersonalAccessTokenCreate struct {
+	Name          string       `json:"name"`
+	Scopes        []TokenScope `json:"scopes"`
+	ExpiresInDays int          `json:"expires_in_days"`
+}
+
+// PersonalAccessTokenCreated carries the plain token, which is shown once.
+type PersonalAccessTokenCreated struct {
+	Token string `json:"token"`
+	*PersonalAccessToken
+}
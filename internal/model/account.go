@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+type DataExportStatus string
+
+const (
+	ExportPending DataExportStatus = "pending"
+	ExportReady   DataExportStatus = "ready"
+	ExportFailed  DataExportStatus = "failed"
+)
+
+type DataExport struct {
+	ID          int64            `json:"id"`
+	UserID      int64            `json:"user_id"`
+	Status      DataExportStatus `json:"status"`
+	FilePath    string           `json:"-"`
+	SizeBytes   int64            `json:"size_bytes,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
+}
+
+// AccountConfirmation carries the password that confirms deactivating or
+// deleting an account.
+type AccountConfirmation struct {
+	Password string `json:"password"`
+}
+
+type AccountDeletion struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// ExportedFriendship is a friendship as it appears in a data export, with
+// the other user's name instead of bare IDs.
+type ExportedFriendship struct {
+	Username  string           `json:"username"`
+	Status    FriendshipStatus `json:"status"`
+	Outgoing  bool             `json:"outgoing"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// ExportedFollow is a follow in either direction; Outgoing is set for
+// accounts the user follows.
+type ExportedFollow struct {
+	Username  string       `json:"username"`
+	Status    FollowStatus `json:"status"`
+	Outgoing  bool         `json:"outgoing"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ExportedLike struct {
+	PostID    int64        `json:"post_id"`
+	Reaction  ReactionType `json:"reaction"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ExportedMedia struct {
+	Source string `json:"source"`
+	URL    string `json:"url"`
+}
+
+type ExportedConversation struct {
+	ID           int64              `json:"id"`
+	Participants []string           `json:"participants"`
+	Messages     []*ExportedMessage `json:"messages"`
+}
+
+type ExportedMessage struct {
+	From      string     `json:"from"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
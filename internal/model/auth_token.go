@@ -0,0 +1,9 @@
+package model
+
+type AuthTokenPurpose string
+
+const (
+	TokenEmailVerification AuthTokenPurpose = "email_verification"
+	TokenPasswordReset     AuthTokenPurpose = "password_reset"
+	TokenEmailChange       AuthTokenPurpose = "email_change"
+)
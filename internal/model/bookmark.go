@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// Bookmark saves a post for later, optionally in one of the user's
+// collections. Bookmarks without a collection are unsorted.
+type Bookmark struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"-"`
+	PostID       int64     `json:"post_id"`
+	CollectionID *int64    `json:"collection_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Post         *Post     `json:"post"`
+}
+
+// BookmarkCreate adds a bookmark. Used on an existing bookmark, it moves
+// the bookmark to CollectionID, or out of any collection when that is nil.
+type BookmarkCreate struct {
+	PostID       int64  `json:"post_id"`
+	CollectionID *int64 `json:"collection_id"`
+}
+
+type BookmarkCollection struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BookmarkCollectionCreate struct {
+	Name string `json:"name"`
+}
+
+type BookmarkFilter struct {
+	CollectionID *int64
+	BeforeID     int64
+	Limit        int
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type FollowStatus string
+
+const (
+	FollowPending  FollowStatus = "pending"
+	FollowAccepted FollowStatus = "accepted"
+)
+
+// Follow is a one-way subscription to another user's public posts. Follows
+// of accounts that approve their followers stay pending until accepted.
+type Follow struct {
+	ID         int64        `json:"id"`
+	FollowerID int64        `json:"follower_id"`
+	FolloweeID int64        `json:"followee_id"`
+	Status     FollowStatus `json:"status"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	Follower   *User        `json:"follower,omitempty"`
+}
@@ -21,6 +21,9 @@ type Friendship struct {
 	Addressee   *User            `json:"addressee,omitempty"`
 }
 
+// FriendRequest names the addressee by ID or, when AddresseeID is zero, by
+// username.
 type FriendRequest struct {
-	AddresseeID int64 `json:"addressee_id"`
+	AddresseeID int64  `json:"addressee_id"`
+	Username    string `json:"username,omitempty"`
 }
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// Identity links an account at an external OpenID Connect provider to a user.
+type Identity struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+type OIDCProviderInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// OIDCAuthorization is where to send the browser. State and ExpiresAt stay
+// on the server, which uses them to tie the callback to the same browser.
+type OIDCAuthorization struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	State            string    `json:"-"`
+	ExpiresAt        time.Time `json:"-"`
+}
+
+type OIDCCallback struct {
+	Code             string `json:"code"`
+	State            string `json:"state"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// OIDCState is what the server remembers between sending the user to the
+// provider and the callback. UserID is set when an existing account is
+// linking a provider rather than logging in.
+type OIDCState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	UserID       int64
+}
+
+// OIDCResult is the outcome of a callback: a linked identity, a completed
+// login, or a login that still needs its second factor.
+type OIDCResult struct {
+	User      *User
+	Challenge *LoginChallenge
+	Identity  *Identity
+	Linked    bool
+}
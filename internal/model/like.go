@@ -2,9 +2,28 @@ package model
 
 import "time"
 
+// ReactionType is how a user reacted to a post. A like is the plain
+// reaction; the likes table stores every kind.
+type ReactionType string
+
+const (
+	ReactionLike  ReactionType = "like"
+	ReactionLove  ReactionType = "love"
+	ReactionHaha  ReactionType = "haha"
+	ReactionWow   ReactionType = "wow"
+	ReactionSad   ReactionType = "sad"
+	ReactionAngry ReactionType = "angry"
+)
+
 type Like struct {
-	ID        int64     `json:"id"`
-	PostID    int64     `json:"post_id"`
-	UserID    int64     `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int64        `json:"id"`
+	PostID    int64        `json:"post_id"`
+	UserID    int64        `json:"user_id"`
+	Reaction  ReactionType `json:"reaction"`
+	CreatedAt time.Time    `json:"created_at"`
+	User      *User        `json:"user,omitempty"`
+}
+
+type ReactionSet struct {
+	Reaction ReactionType `json:"reaction"`
 }
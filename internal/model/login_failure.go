@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type LockoutScope string
+
+const (
+	LockoutAccount LockoutScope = "account"
+	LockoutIP      LockoutScope = "ip"
+)
+
+// Lockout is a failed-login counter for one email address or one client IP.
+// The key is recorded whether or not an account exists for it.
+type Lockout struct {
+	ID            int64        `json:"id"`
+	Scope         LockoutScope `json:"scope"`
+	Key           string       `json:"key"`
+	Failures      int          `json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	LockedUntil   *time.Time   `json:"locked_until,omitempty"`
+}
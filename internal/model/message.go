@@ -24,6 +24,9 @@ type MessageCreate struct {
 	Body string `json:"body"`
 }
 
+// ConversationCreate names the participant by ID or, when ParticipantID is
+// zero, by username.
 type ConversationCreate struct {
-	ParticipantID int64 `json:"participant_id"`
+	ParticipantID int64  `json:"participant_id"`
+	Username      string `json:"username,omitempty"`
 }
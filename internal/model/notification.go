@@ -10,14 +10,109 @@ const (
 	NotificationComment       NotificationType = "comment"
 	NotificationMessage       NotificationType = "message"
 	NotificationGroupInvite   NotificationType = "group_invite"
+	NotificationMention       NotificationType = "mention"
+	NotificationSecurity      NotificationType = "security"
+	NotificationFollow        NotificationType = "follow"
+	NotificationFollowRequest NotificationType = "follow_request"
+	NotificationShare         NotificationType = "share"
+	NotificationPollClosed    NotificationType = "poll_closed"
+)
+
+type NotificationChannel string
+
+const (
+	ChannelInApp NotificationChannel = "in_app"
+	ChannelEmail NotificationChannel = "email"
+	ChannelPush  NotificationChannel = "push"
+)
+
+type DigestFrequency string
+
+const (
+	DigestOff    DigestFrequency = "off"
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+)
+
+type MuteTargetType string
+
+const (
+	MuteTargetPost         MuteTargetType = "post"
+	MuteTargetConversation MuteTargetType = "conversation"
+	MuteTargetGroup        MuteTargetType = "group"
 )
 
 type Notification struct {
-	ID        int64            `json:"id"`
-	UserID    int64            `json:"user_id"`
-	Type      NotificationType `json:"type"`
-	TargetID  int64            `json:"target_id,omitempty"`
-	Message   string           `json:"message"`
-	Read      bool             `json:"read"`
-	CreatedAt time.Time        `json:"created_at"`
+	ID         int64                `json:"id"`
+	UserID     int64                `json:"user_id"`
+	ActorID    int64                `json:"-"`
+	Seq        int64                `json:"-"`
+	Type       NotificationType     `json:"type"`
+	TargetID   int64                `json:"target_id,omitempty"`
+	Message    string               `json:"message"`
+	Read       bool                 `json:"read"`
+	ActorCount int                  `json:"actor_count"`
+	Actors     []*NotificationActor `json:"actors"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+// NotificationEvent is pushed to live subscribers. Seq is only set for
+// notification events and is what clients send back as Last-Event-ID.
+type NotificationEvent struct {
+	Seq  int64
+	Name string
+	Data interface{}
+}
+
+type NotificationFilter struct {
+	Type  NotificationType
+	Read  *bool
+	Limit int
+}
+
+type NotificationActor struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	AvatarURL string    `json:"avatar_url"`
+	ActedAt   time.Time `json:"acted_at"`
+}
+
+type NotificationPreference struct {
+	Type    NotificationType    `json:"type"`
+	Channel NotificationChannel `json:"channel"`
+	Enabled bool                `json:"enabled"`
+}
+
+type NotificationMute struct {
+	ID         int64          `json:"id"`
+	UserID     int64          `json:"user_id"`
+	TargetType MuteTargetType `json:"target_type"`
+	TargetID   int64          `json:"target_id"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+type NotificationMuteCreate struct {
+	TargetType MuteTargetType `json:"target_type"`
+	TargetID   int64          `json:"target_id"`
+}
+
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
+}
+
+type NotificationSettings struct {
+	Preferences []*NotificationPreference `json:"preferences"`
+	Mutes       []*NotificationMute       `json:"mutes"`
+	QuietHours  *QuietHours               `json:"quiet_hours,omitempty"`
+	EmailDigest DigestFrequency           `json:"email_digest"`
+}
+
+type NotificationSettingsUpdate struct {
+	Preferences []*NotificationPreference `json:"preferences"`
+	QuietHours  *QuietHours               `json:"quiet_hours,omitempty"`
+	EmailDigest DigestFrequency           `json:"email_digest,omitempty"`
 }
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Poll is attached to a post. Votes and voter counts are left out until the
+// viewer may see the results: with HideResults set, that is once they voted
+// or the poll closed. Voters of an option are only ever listed for polls
+// that are not anonymous.
+type Poll struct {
+	ID             int64         `json:"id"`
+	PostID         int64         `json:"post_id"`
+	MultipleChoice bool          `json:"multiple_choice"`
+	Anonymous      bool          `json:"anonymous"`
+	HideResults    bool          `json:"hide_results"`
+	EndsAt         *time.Time    `json:"ends_at,omitempty"`
+	Closed         bool          `json:"closed"`
+	ResultsVisible bool          `json:"results_visible"`
+	VoterCount     *int          `json:"voter_count,omitempty"`
+	Options        []*PollOption `json:"options"`
+	MyVotes        []int64       `json:"my_votes"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+type PollOption struct {
+	ID     int64   `json:"id"`
+	Text   string  `json:"text"`
+	Votes  *int    `json:"votes,omitempty"`
+	Voters []*User `json:"voters,omitempty"`
+}
+
+type PollCreate struct {
+	Options        []string   `json:"options"`
+	MultipleChoice bool       `json:"multiple_choice"`
+	Anonymous      bool       `json:"anonymous"`
+	HideResults    bool       `json:"hide_results"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+}
+
+type PollVote struct {
+	OptionIDs []int64 `json:"option_ids"`
+}
@@ -2,24 +2,101 @@ package model
 
 import "time"
 
+// ShareType tells a repost, which only points at another post, from a
+// quote, which adds the sharer's commentary.
+type ShareType string
+
+const (
+	ShareRepost ShareType = "repost"
+	ShareQuote  ShareType = "quote"
+)
+
+// PostStatus tells published posts from drafts and posts scheduled to be
+// published at PublishAt. Only the author sees posts that are not
+// published.
+type PostStatus string
+
+const (
+	PostDraft     PostStatus = "draft"
+	PostScheduled PostStatus = "scheduled"
+	PostPublished PostStatus = "published"
+)
+
+// Post carries reaction totals for the viewer: LikeCount and Liked count
+// reactions of every type, Reactions breaks them down by type and
+// MyReaction is the viewer's own.
+//
+// A share points at the original through SharedPostID. SharedPost holds the
+// original when the viewer can see it; when it was deleted or is hidden from
+// them, SharedPostUnavailable is set instead.
 type Post struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	Content   string    `json:"content"`
-	MediaURL  string    `json:"media_url,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Author    *User     `json:"author,omitempty"`
-	LikeCount int       `json:"like_count"`
-	Liked     bool      `json:"liked"`
+	ID                    int64                `json:"id"`
+	UserID                int64                `json:"user_id"`
+	Content               string               `json:"content"`
+	MediaURL              string               `json:"media_url,omitempty"`
+	SharedPostID          int64                `json:"shared_post_id,omitempty"`
+	ShareType             ShareType            `json:"share_type,omitempty"`
+	Status                PostStatus           `json:"status"`
+	PublishAt             *time.Time           `json:"publish_at,omitempty"`
+	CreatedAt             time.Time            `json:"created_at"`
+	UpdatedAt             time.Time            `json:"updated_at"`
+	Author                *User                `json:"author,omitempty"`
+	LikeCount             int                  `json:"like_count"`
+	Liked                 bool                 `json:"liked"`
+	Reactions             map[ReactionType]int `json:"reactions"`
+	MyReaction            ReactionType         `json:"my_reaction,omitempty"`
+	RepostCount           int                  `json:"repost_count"`
+	QuoteCount            int                  `json:"quote_count"`
+	Reposted              bool                 `json:"reposted"`
+	Edited                bool                 `json:"edited"`
+	Poll                  *Poll                `json:"poll,omitempty"`
+	Bookmarked            bool                 `json:"bookmarked"`
+	SharedPost            *Post                `json:"shared_post,omitempty"`
+	SharedPostUnavailable bool                 `json:"shared_post_unavailable,omitempty"`
 }
 
+// PostCreate publishes the post right away unless it is saved as a draft
+// or scheduled with PublishAt.
 type PostCreate struct {
-	Content  string `json:"content"`
-	MediaURL string `json:"media_url,omitempty"`
+	Content   string      `json:"content"`
+	MediaURL  string      `json:"media_url,omitempty"`
+	Draft     bool        `json:"draft,omitempty"`
+	PublishAt *time.Time  `json:"publish_at,omitempty"`
+	Poll      *PollCreate `json:"poll,omitempty"`
 }
 
 type PostUpdate struct {
 	Content  string `json:"content"`
 	MediaURL string `json:"media_url,omitempty"`
 }
+
+type PostSchedule struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// PostRevision is an earlier version of a post, kept when the post was
+// edited. Diff holds the changes the edit made to it.
+type PostRevision struct {
+	ID         int64     `json:"id"`
+	PostID     int64     `json:"post_id"`
+	Content    string    `json:"content"`
+	MediaURL   string    `json:"media_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+	Diff       []*DiffOp `json:"diff"`
+}
+
+type DiffOpType string
+
+const (
+	DiffEqual  DiffOpType = "equal"
+	DiffInsert DiffOpType = "insert"
+	DiffDelete DiffOpType = "delete"
+)
+
+// DiffOp is a run of text that two versions share, or that one of them
+// adds or removes.
+type DiffOp struct {
+	Op   DiffOpType `json:"op"`
+	Text string     `json:"text"`
+}
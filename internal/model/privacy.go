@@ -0,0 +1,120 @@
+package model
+
+import "time"
+
+// Audience is who can see a part of a profile.
+type Audience string
+
+const (
+	AudienceEveryone Audience = "everyone"
+	AudienceFriends  Audience = "friends"
+	AudienceOnlyMe   Audience = "only_me"
+)
+
+type FriendRequestPolicy string
+
+const (
+	FriendRequestsEveryone         FriendRequestPolicy = "everyone"
+	FriendRequestsFriendsOfFriends FriendRequestPolicy = "friends_of_friends"
+	FriendRequestsNobody           FriendRequestPolicy = "nobody"
+)
+
+type PrivacySettings struct {
+	EmailVisibility    Audience            `json:"email_visibility"`
+	FriendsVisibility  Audience            `json:"friends_visibility"`
+	PostsVisibility    Audience            `json:"posts_visibility"`
+	GroupsVisibility   Audience            `json:"groups_visibility"`
+	LocationVisibility Audience            `json:"location_visibility"`
+	WebsiteVisibility  Audience            `json:"website_visibility"`
+	BirthdayVisibility Audience            `json:"birthday_visibility"`
+	FriendRequests     FriendRequestPolicy `json:"friend_requests"`
+	Searchable         bool                `json:"searchable"`
+	ApproveFollowers   bool                `json:"approve_followers"`
+	PresenceVisibility Audience            `json:"presence_visibility"`
+}
+
+// DefaultPrivacySettings apply to users who never changed their settings.
+func DefaultPrivacySettings() *PrivacySettings {
+	return &PrivacySettings{
+		EmailVisibility:    AudienceOnlyMe,
+		FriendsVisibility:  AudienceEveryone,
+		PostsVisibility:    AudienceFriends,
+		GroupsVisibility:   AudienceEveryone,
+		LocationVisibility: AudienceEveryone,
+		WebsiteVisibility:  AudienceEveryone,
+		BirthdayVisibility: AudienceFriends,
+		FriendRequests:     FriendRequestsEveryone,
+		Searchable:         true,
+		PresenceVisibility: AudienceFriends,
+	}
+}
+
+// PrivacySettingsUpdate changes only the fields that are set.
+type PrivacySettingsUpdate struct {
+	EmailVisibility    Audience            `json:"email_visibility,omitempty"`
+	FriendsVisibility  Audience            `json:"friends_visibility,omitempty"`
+	PostsVisibility    Audience            `json:"posts_visibility,omitempty"`
+	GroupsVisibility   Audience            `json:"groups_visibility,omitempty"`
+	LocationVisibility Audience            `json:"location_visibility,omitempty"`
+	WebsiteVisibility  Audience            `json:"website_visibility,omitempty"`
+	BirthdayVisibility Audience            `json:"birthday_visibility,omitempty"`
+	FriendRequests     FriendRequestPolicy `json:"friend_requests,omitempty"`
+	Searchable         *bool               `json:"searchable,omitempty"`
+	ApproveFollowers   *bool               `json:"approve_followers,omitempty"`
+	PresenceVisibility Audience            `json:"presence_visibility,omitempty"`
+}
+
+// Relationship is how the viewer of a profile is connected to its owner.
+type Relationship string
+
+const (
+	RelationshipSelf            Relationship = "self"
+	RelationshipFriends         Relationship = "friends"
+	RelationshipRequestSent     Relationship = "request_sent"
+	RelationshipRequestReceived Relationship = "request_received"
+	RelationshipBlocked         Relationship = "blocked"
+	RelationshipNone            Relationship = "none"
+)
+
+// ProfileStats counts what the viewer is allowed to see; counts of hidden
+// lists are left out.
+type ProfileStats struct {
+	Posts         *int `json:"posts,omitempty"`
+	Friends       *int `json:"friends,omitempty"`
+	Groups        *int `json:"groups,omitempty"`
+	Followers     *int `json:"followers,omitempty"`
+	Following     *int `json:"following,omitempty"`
+	MutualFriends int  `json:"mutual_friends"`
+}
+
+// Profile is a user as seen by a particular viewer. Email, location,
+// website and birthday are only set when the owner's privacy settings let
+// the viewer see them, and the Can* flags say which other parts of the
+// profile the viewer has access to. FriendshipID refers to the friendship or
+// pending request between the two, if any. FollowStatus is the viewer's
+// follow of the owner and FollowsYou whether the owner follows the viewer.
+// EmailVerified is only set when owners view their own profile.
+type Profile struct {
+	ID                   int64         `json:"id"`
+	Email                string        `json:"email,omitempty"`
+	Username             string        `json:"username"`
+	FullName             string        `json:"full_name"`
+	Bio                  string        `json:"bio"`
+	AvatarURL            string        `json:"avatar_url"`
+	CoverURL             string        `json:"cover_url"`
+	Location             string        `json:"location,omitempty"`
+	Website              string        `json:"website,omitempty"`
+	Birthday             string        `json:"birthday,omitempty"`
+	IsAdmin              bool          `json:"is_admin"`
+	EmailVerified        *bool         `json:"email_verified,omitempty"`
+	CreatedAt            time.Time     `json:"created_at"`
+	Relationship         Relationship  `json:"relationship"`
+	FriendshipID         int64         `json:"friendship_id,omitempty"`
+	FollowStatus         FollowStatus  `json:"follow_status,omitempty"`
+	FollowsYou           bool          `json:"follows_you"`
+	CanViewFriends       bool          `json:"can_view_friends"`
+	CanViewPosts         bool          `json:"can_view_posts"`
+	CanViewGroups        bool          `json:"can_view_groups"`
+	CanSendFriendRequest bool          `json:"can_send_friend_request"`
+	Stats                *ProfileStats `json:"stats"`
+}
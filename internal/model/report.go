@@ -24,6 +24,10 @@ type Report struct {
 	Status     ReportStatus     `json:"status"`
 	CreatedAt  time.Time        `json:"created_at"`
 	Reporter   *User            `json:"reporter,omitempty"`
+	// ReportedContent is a reported post's content as it was when the
+	// report was filed, even if the post was edited since.
+	ReportedContent   string `json:"reported_content,omitempty"`
+	EditedSinceReport bool   `json:"edited_since_report,omitempty"`
 }
 
 type ReportCreate struct {
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// SigningKey is a stored session token signing key. ExpiresAt is set once a
+// newer key has replaced it; until then tokens it signed stay verifiable.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  string
+	CreatedAt   time.Time
+	ActivatesAt time.Time
+	ExpiresAt   *time.Time
+}
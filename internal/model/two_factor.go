@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorDisable struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// TwoFactorLogin completes a login with either a TOTP code or one of the
+// recovery codes.
+type TwoFactorLogin struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TwoFactorRequirement struct {
+	Required bool `json:"required"`
+}
+
+type LoginChallenge struct {
+	TwoFactorRequired      bool      `json:"two_factor_required"`
+	TwoFactorSetupRequired bool      `json:"two_factor_setup_required,omitempty"`
+	ChallengeToken         string    `json:"challenge_token"`
+	ExpiresAt              time.Time `json:"expires_at"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
@@ -2,16 +2,22 @@ package model
 
 import "time"
 
+// User is an account. Online and LastSeenAt are only filled in where
+// presence is shown, and only if the user lets the viewer see it.
 type User struct {
-	ID           int64     `json:"id"`
-	Email        string    `json:"email"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"`
-	FullName     string    `json:"full_name"`
-	Bio          string    `json:"bio"`
-	AvatarURL    string    `json:"avatar_url"`
-	IsAdmin      bool      `json:"is_admin"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            int64      `json:"id"`
+	Email         string     `json:"email,omitempty"`
+	Username      string     `json:"username"`
+	PasswordHash  string     `json:"-"`
+	FullName      string     `json:"full_name"`
+	Bio           string     `json:"bio"`
+	AvatarURL     string     `json:"avatar_url"`
+	IsAdmin       bool       `json:"is_admin"`
+	EmailVerified bool       `json:"-"`
+	TokenVersion  int        `json:"-"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Online        *bool      `json:"online,omitempty"`
+	LastSeenAt    *time.Time `json:"last_seen_at,omitempty"`
 }
 
 type UserRegistration struct {
@@ -26,8 +32,50 @@ type UserLogin struct {
 	Password string `json:"password"`
 }
 
+type EmailVerification struct {
+	Token string `json:"token"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordReset struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type EmailChange struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type UsernameChange struct {
+	Username string `json:"username"`
+}
+
+// UserProfile replaces the name, bio and avatar. The optional fields added
+// later are only changed when present.
 type UserProfile struct {
-	FullName  string `json:"full_name"`
-	Bio       string `json:"bio"`
-	AvatarURL string `json:"avatar_url"`
+	FullName  string  `json:"full_name"`
+	Bio       string  `json:"bio"`
+	AvatarURL string  `json:"avatar_url"`
+	CoverURL  *string `json:"cover_url,omitempty"`
+	Location  *string `json:"location,omitempty"`
+	Website   *string `json:"website,omitempty"`
+	Birthday  *string `json:"birthday,omitempty"`
+}
+
+// ProfileDetails are the optional profile fields. Birthday is a YYYY-MM-DD
+// date.
+type ProfileDetails struct {
+	CoverURL string `json:"cover_url"`
+	Location string `json:"location"`
+	Website  string `json:"website"`
+	Birthday string `json:"birthday"`
 }
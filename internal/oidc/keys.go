@@ -0,0 +1,142 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// keyRefreshInterval limits how often an unknown key ID triggers a new JWKS
+// fetch, so tokens with made-up key IDs cannot hammer the provider.
+const keyRefreshInterval = time.Minute
+
+type keySet struct {
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// key returns the verification key with the given ID, refetching the key set
+// when the provider may have rotated its keys.
+func (p *Provider) key(jwksURI, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil {
+		if key, ok := lookupKey(p.keys, kid); ok {
+			return key, nil
+		}
+		if time.Since(p.keys.fetchedAt) < keyRefreshInterval {
+			return nil, errors.New("unknown signing key")
+		}
+	}
+
+	keys, err := p.fetchKeys(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// lookupKey falls back to the only key of the set when the token carries no
+// key ID.
+func lookupKey(keys *keySet, kid string) (interface{}, bool) {
+	if kid == "" && len(keys.keys) == 1 {
+		for _, key := range keys.keys {
+			return key, true
+		}
+	}
+	key, ok := keys.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys(jwksURI string) (*keySet, error) {
+	req, err := http.NewRequest(http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.do(req, &document); err != nil {
+		return nil, fmt.Errorf("fetching signing keys failed: %w", err)
+	}
+
+	keys := &keySet{keys: make(map[string]interface{}), fetchedAt: time.Now()}
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseKey(&jwk)
+		if err != nil {
+			continue
+		}
+		keys.keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func parseKey(jwk *jsonWebKey) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, errors.New("unsupported curve")
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, errors.New("unsupported key type")
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
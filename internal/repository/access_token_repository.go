@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"socialnet/internal/model"
+	"strings"
+	"time"
+)
+
+type AccessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAccessTokenRepository(db *sql.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+// Create stores a token that expires after ttl, or never when ttl is zero.
+func (r *AccessTokenRepository) Create(token *model.PersonalAccessToken, hash string, ttl time.Duration) (int64, error) {
+	query := `INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+			  VALUES (?, ?, ?, ?, ?, CASE WHEN ? THEN datetime('now', ?) END)`
+	modifier := fmt.Sprintf("+%d seconds", int64(ttl.Seconds()))
+	result, err := r.db.Exec(query, token.UserID, token.Name, hash, token.Prefix,
+		joinScopes(token.Scopes), ttl > 0, modifier)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *AccessTokenRepository) GetByID(id, userID int64) (*model.PersonalAccessToken, error) {
+	query := `SELECT id, user_id, name, token_prefix, scopes, expires_at, last_used_at, created_at
+			  FROM personal_access_tokens WHERE id = ? AND user_id = ?`
+	token, err := scanAccessToken(r.db.QueryRow(query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("token not found")
+	}
+	return token, err
+}
+
+// GetByHash returns an unexpired token of an active account.
+func (r *AccessTokenRepository) GetByHash(hash string) (*model.PersonalAccessToken, error) {
+	query := `SELECT id, user_id, name, token_prefix, scopes, expires_at, last_used_at, created_at
+			  FROM personal_access_tokens
+			  WHERE token_hash = ? AND (expires_at IS NULL OR expires_at > datetime('now'))
+			  AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)`
+	token, err := scanAccessToken(r.db.QueryRow(query, hash))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("token not found")
+	}
+	return token, err
+}
+
+func (r *AccessTokenRepository) GetByUser(userID int64) ([]*model.PersonalAccessToken, error) {
+	query := `SELECT id, user_id, name, token_prefix, scopes, expires_at, last_used_at, created_at
+			  FROM personal_access_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*model.PersonalAccessToken
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *AccessTokenRepository) CountByUser(userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM personal_access_tokens WHERE user_id = ?`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+// TouchLastUsed records a use at most once a minute to keep writes off the
+// hot path of every API call.
+func (r *AccessTokenRepository) TouchLastUsed(id int64) error {
+	query := `UPDATE personal_access_tokens SET last_used_at = CURRENT_TIMESTAMP
+			  WHERE id = ? AND (last_used_at IS NULL OR last_used_at < datetime('now', '-1 minute'))`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+func (r *AccessTokenRepository) Delete(id, userID int64) error {
+	query := `DELETE FROM personal_access_tokens WHERE id = ? AND user_id = ?`
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// DeleteByUser revokes every token of the user.
+func (r *AccessTokenRepository) DeleteByUser(userID int64) error {
+	_, err := r.db.Exec(`DELETE FROM personal_access_tokens WHERE user_id = ?`, userID)
+	return err
+}
+
+type accessTokenScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAccessToken(scanner accessTokenScanner) (*model.PersonalAccessToken, error) {
+	token := &model.PersonalAccessToken{}
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	err := scanner.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &scopes,
+		&expiresAt, &lastUsedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = splitScopes(scopes)
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return token, nil
+}
+
+func joinScopes(scopes []model.TokenScope) string {
+	values := make([]string, len(scopes))
+	for i, scope := range scopes {
+		values[i] = string(scope)
+	}
+	return strings.Join(values, " ")
+}
+
+func splitScopes(value string) []model.TokenScope {
+	fields := strings.Fields(value)
+	scopes := make([]model.TokenScope, len(fields))
+	for i, field := range fields {
+		scopes[i] = model.TokenScope(field)
+	}
+	return scopes
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"database/sql"
+	"socialnet/internal/model"
+)
+
+// AccountRepository erases accounts whose deletion is due and gathers the
+// data behind exports.
+type AccountRepository struct {
+	db *sql.DB
+}
+
+func NewAccountRepository(db *sql.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// GetDueDeletions returns accounts whose grace period is over.
+func (r *AccountRepository) GetDueDeletions(limit int) ([]int64, error) {
+	query := `SELECT id FROM users
+			  WHERE deletion_scheduled_at <= datetime('now') AND deleted_at IS NULL LIMIT ?`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Purge erases an account in one transaction. Content only the user had a
+// stake in (posts, comments, likes, poll votes, bookmarks, friendships, settings and credentials)
+// is deleted. Messages and group posts belong to conversations other people
+// are part of; they stay, attributed to the anonymized user row, which is
+// kept as a tombstone so their user_id still points somewhere. Groups the
+// user owned pass to their longest-standing member, or are deleted when
+// nobody is left.
+func (r *AccountRepository) Purge(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		// Reports against the user's content are settled before it goes,
+		// including other people's comments and reposts deleted along with it.
+		`UPDATE reports SET status = 'resolved' WHERE status != 'resolved' AND (
+			(target_type = 'user' AND target_id = ?1)
+			OR (target_type = 'post' AND target_id IN (SELECT id FROM posts WHERE user_id = ?1))
+			OR (target_type = 'post' AND target_id IN (SELECT id FROM posts WHERE share_type = 'repost'
+				AND shared_post_id IN (SELECT id FROM posts WHERE user_id = ?1)))
+			OR (target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE user_id = ?1))
+			OR (target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?1)))
+			OR (target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE post_id IN (SELECT id FROM posts WHERE share_type = 'repost'
+				AND shared_post_id IN (SELECT id FROM posts WHERE user_id = ?1)))))`,
+		`DELETE FROM reports WHERE reporter_id = ?1`,
+
+		`UPDATE groups SET owner_id = (
+			SELECT user_id FROM group_members
+			WHERE group_id = groups.id AND user_id != ?1 ORDER BY joined_at, id LIMIT 1
+		 ) WHERE owner_id = ?1 AND EXISTS(
+			SELECT 1 FROM group_members WHERE group_id = groups.id AND user_id != ?1)`,
+		`DELETE FROM group_posts WHERE group_id IN (SELECT id FROM groups WHERE owner_id = ?1)`,
+		`DELETE FROM group_members WHERE group_id IN (SELECT id FROM groups WHERE owner_id = ?1)`,
+		`DELETE FROM groups WHERE owner_id = ?1`,
+		`DELETE FROM group_members WHERE user_id = ?1`,
+
+		// Reposts of the user's posts go with them, along with anything
+		// attached to the reposts; quotes of them stay.
+		`DELETE FROM likes WHERE post_id IN (SELECT id FROM posts WHERE share_type = 'repost' AND shared_post_id IN (SELECT id FROM posts WHERE user_id = ?1))`,
+		`DELETE FROM comments WHERE post_id IN (SELECT id FROM posts WHERE share_type = 'repost' AND shared_post_id IN (SELECT id FROM posts WHERE user_id = ?1))`,
+		`DELETE FROM bookmarks WHERE post_id IN (SELECT id FROM posts WHERE share_type = 'repost' AND shared_post_id IN (SELECT id FROM posts WHERE user_id = ?1))`,
+		`DELETE FROM notification_actors WHERE notification_id IN (SELECT id FROM notifications
+			WHERE type IN ('like', 'comment', 'mention')
+			AND target_id IN (SELECT id FROM posts WHERE share_type = 'repost' AND shared_post_id IN (SELECT id FROM posts WHERE user_id = ?1)))`,
+		`DELETE FROM notifications WHERE type IN ('like', 'comment', 'mention')
+			AND target_id IN (SELECT id FROM posts WHERE share_type = 'repost' AND shared_post_id IN (SELECT id FROM posts WHERE user_id = ?1))`,
+		`DELETE FROM posts WHERE share_type = 'repost' AND shared_post_id IN (SELECT id FROM posts WHERE user_id = ?1)`,
+		`DELETE FROM comments WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?1)`,
+		`DELETE FROM likes WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?1)`,
+		`DELETE FROM post_revisions WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?1)`,
+		`DELETE FROM poll_votes WHERE poll_id IN (SELECT id FROM polls WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?1))`,
+		`DELETE FROM poll_options WHERE poll_id IN (SELECT id FROM polls WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?1))`,
+		`DELETE FROM polls WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?1)`,
+		`DELETE FROM bookmarks WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?1)`,
+		`DELETE FROM posts WHERE user_id = ?1`,
+		`DELETE FROM comments WHERE user_id = ?1`,
+		`DELETE FROM likes WHERE user_id = ?1`,
+		`DELETE FROM poll_votes WHERE user_id = ?1`,
+		`DELETE FROM bookmarks WHERE user_id = ?1`,
+		`DELETE FROM bookmark_collections WHERE user_id = ?1`,
+		`DELETE FROM friendships WHERE requester_id = ?1 OR addressee_id = ?1`,
+		`DELETE FROM follows WHERE follower_id = ?1 OR followee_id = ?1`,
+
+		// Notifications name their actors in the message text, so the ones
+		// the user took part in are dropped rather than edited.
+		`DELETE FROM notifications WHERE user_id = ?1
+			OR id IN (SELECT notification_id FROM notification_actors WHERE actor_id = ?1)`,
+		`DELETE FROM notification_actors WHERE actor_id = ?1
+			OR notification_id NOT IN (SELECT id FROM notifications)`,
+		`DELETE FROM notification_preferences WHERE user_id = ?1`,
+		`DELETE FROM notification_mutes WHERE user_id = ?1`,
+		`DELETE FROM notification_quiet_hours WHERE user_id = ?1`,
+		`DELETE FROM email_digest_settings WHERE user_id = ?1`,
+		`DELETE FROM privacy_settings WHERE user_id = ?1`,
+
+		`DELETE FROM login_failures WHERE scope = 'account' AND key = (SELECT LOWER(TRIM(email)) FROM users WHERE id = ?1)`,
+		`DELETE FROM auth_tokens WHERE user_id = ?1`,
+		`DELETE FROM user_totp WHERE user_id = ?1`,
+		`DELETE FROM recovery_codes WHERE user_id = ?1`,
+		`DELETE FROM identities WHERE user_id = ?1`,
+		`DELETE FROM oidc_states WHERE user_id = ?1`,
+		`DELETE FROM personal_access_tokens WHERE user_id = ?1`,
+		`DELETE FROM username_redirects WHERE user_id = ?1`,
+		`DELETE FROM data_exports WHERE user_id = ?1`,
+
+		`UPDATE users SET email = 'deleted-' || id || '@deleted.invalid', username = 'deleted_' || id,
+			password_hash = '', full_name = 'Deleted user', bio = '', avatar_url = '', cover_url = NULL,
+			location = NULL, website = NULL, birthday = NULL, last_seen_at = NULL, is_admin = FALSE,
+			email_verified = FALSE, password_set = FALSE, two_factor_required = FALSE,
+			deletion_scheduled_at = NULL, deleted_at = CURRENT_TIMESTAMP,
+			deactivated_at = COALESCE(deactivated_at, CURRENT_TIMESTAMP), token_version = token_version + 1
+		 WHERE id = ?1`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *AccountRepository) GetCommentsByUser(userID int64) ([]*model.Comment, error) {
+	query := `SELECT id, post_id, user_id, content, created_at
+			  FROM comments WHERE user_id = ? ORDER BY created_at, id`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		comment := &model.Comment{}
+		err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (r *AccountRepository) GetLikesByUser(userID int64) ([]*model.ExportedLike, error) {
+	query := `SELECT post_id, reaction, created_at FROM likes WHERE user_id = ? ORDER BY created_at, id`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var likes []*model.ExportedLike
+	for rows.Next() {
+		like := &model.ExportedLike{}
+		if err := rows.Scan(&like.PostID, &like.Reaction, &like.CreatedAt); err != nil {
+			return nil, err
+		}
+		likes = append(likes, like)
+	}
+	return likes, rows.Err()
+}
+
+// GetFriendshipsByUser returns friendships in every state, including
+// pending requests and blocks.
+func (r *AccountRepository) GetFriendshipsByUser(userID int64) ([]*model.ExportedFriendship, error) {
+	query := `SELECT u.username, f.status, f.requester_id = ?, f.created_at
+			  FROM friendships f
+			  INNER JOIN users u ON u.id = CASE WHEN f.requester_id = ? THEN f.addressee_id ELSE f.requester_id END
+			  WHERE f.requester_id = ? OR f.addressee_id = ?
+			  ORDER BY f.created_at, f.id`
+	rows, err := r.db.Query(query, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var friendships []*model.ExportedFriendship
+	for rows.Next() {
+		friendship := &model.ExportedFriendship{}
+		err := rows.Scan(&friendship.Username, &friendship.Status, &friendship.Outgoing, &friendship.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		friendships = append(friendships, friendship)
+	}
+	return friendships, rows.Err()
+}
+
+// GetFollowsByUser returns follows in both directions, including pending
+// ones.
+func (r *AccountRepository) GetFollowsByUser(userID int64) ([]*model.ExportedFollow, error) {
+	query := `SELECT u.username, f.status, f.follower_id = ?, f.created_at
+			  FROM follows f
+			  INNER JOIN users u ON u.id = CASE WHEN f.follower_id = ? THEN f.followee_id ELSE f.follower_id END
+			  WHERE f.follower_id = ? OR f.followee_id = ?
+			  ORDER BY f.created_at, f.id`
+	rows, err := r.db.Query(query, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var follows []*model.ExportedFollow
+	for rows.Next() {
+		follow := &model.ExportedFollow{}
+		if err := rows.Scan(&follow.Username, &follow.Status, &follow.Outgoing, &follow.CreatedAt); err != nil {
+			return nil, err
+		}
+		follows = append(follows, follow)
+	}
+	return follows, rows.Err()
+}
+
+func (r *AccountRepository) GetConversationIDs(userID int64) ([]int64, error) {
+	query := `SELECT conversation_id FROM conversation_members WHERE user_id = ? ORDER BY conversation_id`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *AccountRepository) GetGroupPostsByUser(userID int64) ([]*model.GroupPost, error) {
+	query := `SELECT id, group_id, user_id, content, created_at
+			  FROM group_posts WHERE user_id = ? ORDER BY created_at, id`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*model.GroupPost
+	for rows.Next() {
+		post := &model.GroupPost{}
+		err := rows.Scan(&post.ID, &post.GroupID, &post.UserID, &post.Content, &post.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"socialnet/internal/model"
+	"time"
+)
+
+type AuthTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAuthTokenRepository(db *sql.DB) *AuthTokenRepository {
+	return &AuthTokenRepository{db: db}
+}
+
+// Create stores the hash of a new token. Earlier unused tokens for the same
+// purpose are retired so only the most recent link works.
+func (r *AuthTokenRepository) Create(userID int64, purpose model.AuthTokenPurpose, tokenHash string,
+	ttl time.Duration) error {
+	return r.create(userID, purpose, tokenHash, "", ttl)
+}
+
+// CreateEmailChange stores the hash of an email change token along with the
+// address it confirms, so each link can only switch to the address it was
+// sent to.
+func (r *AuthTokenRepository) CreateEmailChange(userID int64, tokenHash, email string, ttl time.Duration) error {
+	return r.create(userID, model.TokenEmailChange, tokenHash, email, ttl)
+}
+
+func (r *AuthTokenRepository) create(userID int64, purpose model.AuthTokenPurpose, tokenHash, email string,
+	ttl time.Duration) error {
+	if err := r.Invalidate(userID, purpose); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO auth_tokens (user_id, purpose, token_hash, email, expires_at)
+			  VALUES (?, ?, ?, NULLIF(?, ''), datetime('now', ?))`
+	modifier := fmt.Sprintf("+%d seconds", int64(ttl.Seconds()))
+	_, err := r.db.Exec(query, userID, purpose, tokenHash, email, modifier)
+	return err
+}
+
+// Lookup returns the owner of a valid token without using it up.
+func (r *AuthTokenRepository) Lookup(purpose model.AuthTokenPurpose, tokenHash string) (int64, error) {
+	query := `SELECT user_id FROM auth_tokens
+			  WHERE purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > datetime('now')`
+	var userID int64
+	err := r.db.QueryRow(query, purpose, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("invalid or expired token")
+	}
+	return userID, err
+}
+
+// Consume marks a valid token as used and returns its owner. Marking and
+// checking happen in one statement, so a token can only be redeemed once.
+func (r *AuthTokenRepository) Consume(purpose model.AuthTokenPurpose, tokenHash string) (int64, error) {
+	query := `UPDATE auth_tokens SET used_at = CURRENT_TIMESTAMP
+			  WHERE purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > datetime('now')
+			  RETURNING user_id`
+	var userID int64
+	err := r.db.QueryRow(query, purpose, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("invalid or expired token")
+	}
+	return userID, err
+}
+
+// ConsumeEmailChange redeems an email change token like Consume and also
+// returns the address it was issued for.
+func (r *AuthTokenRepository) ConsumeEmailChange(tokenHash string) (int64, string, error) {
+	query := `UPDATE auth_tokens SET used_at = CURRENT_TIMESTAMP
+			  WHERE purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > datetime('now')
+			  RETURNING user_id, COALESCE(email, '')`
+	var userID int64
+	var email string
+	err := r.db.QueryRow(query, model.TokenEmailChange, tokenHash).Scan(&userID, &email)
+	if err == sql.ErrNoRows {
+		return 0, "", errors.New("invalid or expired token")
+	}
+	return userID, email, err
+}
+
+func (r *AuthTokenRepository) Invalidate(userID int64, purpose model.AuthTokenPurpose) error {
+	query := `UPDATE auth_tokens SET used_at = CURRENT_TIMESTAMP
+			  WHERE user_id = ? AND purpose = ? AND used_at IS NULL`
+	_, err := r.db.Exec(query, userID, purpose)
+	return err
+}
+
+// IssuedWithin reports whether a token for the purpose was created in the
+// last interval, used to throttle resends.
+func (r *AuthTokenRepository) IssuedWithin(userID int64, purpose model.AuthTokenPurpose,
+	interval time.Duration) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM auth_tokens
+			  WHERE user_id = ? AND purpose = ? AND created_at > datetime('now', ?))`
+	modifier := fmt.Sprintf("-%d seconds", int64(interval.Seconds()))
+	var exists bool
+	err := r.db.QueryRow(query, userID, purpose, modifier).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"socialnet/internal/model"
+)
+
+var (
+	ErrBookmarkNotFound   = errors.New("bookmark not found")
+	ErrCollectionNotFound = errors.New("collection not found")
+)
+
+type BookmarkRepository struct {
+	db *sql.DB
+}
+
+func NewBookmarkRepository(db *sql.DB) *BookmarkRepository {
+	return &BookmarkRepository{db: db}
+}
+
+func (r *BookmarkRepository) Create(userID, postID int64, collectionID *int64) (int64, error) {
+	query := `INSERT INTO bookmarks (user_id, post_id, collection_id) VALUES (?, ?, ?)`
+	result, err := r.db.Exec(query, userID, postID, nullID(collectionID))
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Get returns the user's bookmark of the post, or nil if they have not
+// bookmarked it.
+func (r *BookmarkRepository) Get(userID, postID int64) (*model.Bookmark, error) {
+	query := `SELECT id, user_id, post_id, collection_id, created_at FROM bookmarks WHERE user_id = ? AND post_id = ?`
+	bookmark := &model.Bookmark{}
+	var collectionID sql.NullInt64
+	err := r.db.QueryRow(query, userID, postID).Scan(&bookmark.ID, &bookmark.UserID, &bookmark.PostID,
+		&collectionID, &bookmark.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if collectionID.Valid {
+		bookmark.CollectionID = &collectionID.Int64
+	}
+	return bookmark, nil
+}
+
+func (r *BookmarkRepository) IsBookmarked(userID, postID int64) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM bookmarks WHERE user_id = ? AND post_id = ?)`
+	var exists bool
+	err := r.db.QueryRow(query, userID, postID).Scan(&exists)
+	return exists, err
+}
+
+func (r *BookmarkRepository) Move(id int64, collectionID *int64) error {
+	_, err := r.db.Exec(`UPDATE bookmarks SET collection_id = ? WHERE id = ?`, nullID(collectionID), id)
+	return err
+}
+
+func (r *BookmarkRepository) Delete(userID, postID int64) error {
+	result, err := r.db.Exec(`DELETE FROM bookmarks WHERE user_id = ? AND post_id = ?`, userID, postID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+// GetBookmarks returns the user's bookmarks with their posts, latest first,
+// leaving out posts of deactivated accounts. With a collection set, only
+// the bookmarks in it are returned. BeforeID pages by bookmark ID.
+func (r *BookmarkRepository) GetBookmarks(userID int64, filter *model.BookmarkFilter) ([]*model.Bookmark, error) {
+	query := `SELECT b.id, b.user_id, b.post_id, b.collection_id, b.created_at,
+			  p.id, p.user_id, p.content, p.media_url, p.shared_post_id, p.share_type, p.status, p.publish_at,
+			  p.created_at, p.updated_at
+			  FROM bookmarks b
+			  INNER JOIN posts p ON p.id = b.post_id
+			  WHERE b.user_id = ? AND (? IS NULL OR b.collection_id = ?) AND (? = 0 OR b.id < ?)
+			  AND p.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+			  ORDER BY b.id DESC LIMIT ?`
+	collectionID := nullID(filter.CollectionID)
+	rows, err := r.db.Query(query, userID, collectionID, collectionID, filter.BeforeID, filter.BeforeID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookmarks []*model.Bookmark
+	for rows.Next() {
+		bookmark := &model.Bookmark{}
+		var collectionID sql.NullInt64
+		post, err := scanPost(scannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append([]interface{}{&bookmark.ID, &bookmark.UserID, &bookmark.PostID,
+				&collectionID, &bookmark.CreatedAt}, dest...)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+		if collectionID.Valid {
+			bookmark.CollectionID = &collectionID.Int64
+		}
+		bookmark.Post = post
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks, rows.Err()
+}
+
+func (r *BookmarkRepository) CreateCollection(userID int64, name string) (int64, error) {
+	query := `INSERT INTO bookmark_collections (user_id, name) VALUES (?, ?)`
+	result, err := r.db.Exec(query, userID, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *BookmarkRepository) GetCollection(id, userID int64) (*model.BookmarkCollection, error) {
+	query := `SELECT id, user_id, name, created_at FROM bookmark_collections WHERE id = ? AND user_id = ?`
+	collection := &model.BookmarkCollection{}
+	err := r.db.QueryRow(query, id, userID).Scan(&collection.ID, &collection.UserID, &collection.Name,
+		&collection.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCollectionNotFound
+	}
+	return collection, err
+}
+
+// CollectionNameTaken reports whether another of the user's collections
+// has the name, ignoring case.
+func (r *BookmarkRepository) CollectionNameTaken(userID int64, name string, exceptID int64) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM bookmark_collections WHERE user_id = ? AND name = ? AND id != ?)`
+	var taken bool
+	err := r.db.QueryRow(query, userID, name, exceptID).Scan(&taken)
+	return taken, err
+}
+
+func (r *BookmarkRepository) GetCollections(userID int64) ([]*model.BookmarkCollection, error) {
+	query := `SELECT id, user_id, name, created_at FROM bookmark_collections WHERE user_id = ? ORDER BY name, id`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*model.BookmarkCollection{}
+	for rows.Next() {
+		collection := &model.BookmarkCollection{}
+		err := rows.Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	return collections, rows.Err()
+}
+
+func (r *BookmarkRepository) RenameCollection(id int64, name string) error {
+	_, err := r.db.Exec(`UPDATE bookmark_collections SET name = ? WHERE id = ?`, name, id)
+	return err
+}
+
+// DeleteCollection removes the collection. Its bookmarks are kept and
+// become unsorted.
+func (r *BookmarkRepository) DeleteCollection(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE bookmarks SET collection_id = NULL WHERE collection_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM bookmark_collections WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scannerFunc adapts a function to postScanner, so a post can be scanned
+// from the tail of a wider row.
+type scannerFunc func(dest ...interface{}) error
+
+func (f scannerFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
+
+func nullID(id *int64) sql.NullInt64 {
+	if id == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *id, Valid: true}
+}
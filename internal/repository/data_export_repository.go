@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"socialnet/internal/model"
+	"time"
+)
+
+type DataExportRepository struct {
+	db *sql.DB
+}
+
+func NewDataExportRepository(db *sql.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+func (r *DataExportRepository) Create(userID int64) (int64, error) {
+	query := `INSERT INTO data_exports (user_id) VALUES (?)`
+	result, err := r.db.Exec(query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *DataExportRepository) GetByID(id, userID int64) (*model.DataExport, error) {
+	query := `SELECT id, user_id, status, COALESCE(file_path, ''), size_bytes, COALESCE(error, ''),
+			  created_at, completed_at, expires_at
+			  FROM data_exports WHERE id = ? AND user_id = ?`
+	export, err := scanDataExport(r.db.QueryRow(query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("export not found")
+	}
+	return export, err
+}
+
+func (r *DataExportRepository) GetByUser(userID int64) ([]*model.DataExport, error) {
+	query := `SELECT id, user_id, status, COALESCE(file_path, ''), size_bytes, COALESCE(error, ''),
+			  created_at, completed_at, expires_at
+			  FROM data_exports WHERE user_id = ? ORDER BY created_at DESC, id DESC`
+	return r.query(query, userID)
+}
+
+// GetPending returns queued exports, oldest first.
+func (r *DataExportRepository) GetPending(limit int) ([]*model.DataExport, error) {
+	query := `SELECT id, user_id, status, COALESCE(file_path, ''), size_bytes, COALESCE(error, ''),
+			  created_at, completed_at, expires_at
+			  FROM data_exports WHERE status = 'pending' ORDER BY created_at, id LIMIT ?`
+	return r.query(query, limit)
+}
+
+// GetExpired returns finished exports past their expiry, whose files can be
+// removed.
+func (r *DataExportRepository) GetExpired() ([]*model.DataExport, error) {
+	query := `SELECT id, user_id, status, COALESCE(file_path, ''), size_bytes, COALESCE(error, ''),
+			  created_at, completed_at, expires_at
+			  FROM data_exports WHERE expires_at <= datetime('now')`
+	return r.query(query)
+}
+
+// RequestedWithin reports whether the user has an export in progress or
+// requested one in the last interval.
+func (r *DataExportRepository) RequestedWithin(userID int64, interval time.Duration) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM data_exports
+			  WHERE user_id = ? AND (status = 'pending' OR created_at > datetime('now', ?)))`
+	modifier := fmt.Sprintf("-%d seconds", int64(interval.Seconds()))
+	var exists bool
+	err := r.db.QueryRow(query, userID, modifier).Scan(&exists)
+	return exists, err
+}
+
+// MarkReady records the finished archive, which is kept for ttl.
+func (r *DataExportRepository) MarkReady(id int64, filePath string, size int64, ttl time.Duration) error {
+	query := `UPDATE data_exports SET status = 'ready', file_path = ?, size_bytes = ?,
+			  completed_at = CURRENT_TIMESTAMP, expires_at = datetime('now', ?) WHERE id = ?`
+	modifier := fmt.Sprintf("+%d seconds", int64(ttl.Seconds()))
+	_, err := r.db.Exec(query, filePath, size, modifier, id)
+	return err
+}
+
+func (r *DataExportRepository) MarkFailed(id int64, message string, ttl time.Duration) error {
+	query := `UPDATE data_exports SET status = 'failed', error = ?,
+			  completed_at = CURRENT_TIMESTAMP, expires_at = datetime('now', ?) WHERE id = ?`
+	modifier := fmt.Sprintf("+%d seconds", int64(ttl.Seconds()))
+	_, err := r.db.Exec(query, message, modifier, id)
+	return err
+}
+
+func (r *DataExportRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM data_exports WHERE id = ?`, id)
+	return err
+}
+
+func (r *DataExportRepository) query(query string, args ...interface{}) ([]*model.DataExport, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []*model.DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
+
+type dataExportScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDataExport(scanner dataExportScanner) (*model.DataExport, error) {
+	export := &model.DataExport{}
+	var completedAt, expiresAt sql.NullTime
+	err := scanner.Scan(&export.ID, &export.UserID, &export.Status, &export.FilePath, &export.SizeBytes,
+		&export.Error, &export.CreatedAt, &completedAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+	return export, nil
+}
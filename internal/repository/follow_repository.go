@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"socialnet/internal/model"
+)
+
+type FollowRepository struct {
+	db *sql.DB
+}
+
+func NewFollowRepository(db *sql.DB) *FollowRepository {
+	return &FollowRepository{db: db}
+}
+
+func (r *FollowRepository) Create(followerID, followeeID int64, status model.FollowStatus) (int64, error) {
+	query := `INSERT INTO follows (follower_id, followee_id, status) VALUES (?, ?, ?)`
+	result, err := r.db.Exec(query, followerID, followeeID, status)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *FollowRepository) GetByID(id int64) (*model.Follow, error) {
+	query := `SELECT id, follower_id, followee_id, status, created_at, updated_at FROM follows WHERE id = ?`
+	follow := &model.Follow{}
+	err := r.db.QueryRow(query, id).Scan(&follow.ID, &follow.FollowerID, &follow.FolloweeID,
+		&follow.Status, &follow.CreatedAt, &follow.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("follow request not found")
+	}
+	return follow, err
+}
+
+// Get returns followerID's follow of followeeID, or nil if there is none.
+func (r *FollowRepository) Get(followerID, followeeID int64) (*model.Follow, error) {
+	query := `SELECT id, follower_id, followee_id, status, created_at, updated_at
+			  FROM follows WHERE follower_id = ? AND followee_id = ?`
+	follow := &model.Follow{}
+	err := r.db.QueryRow(query, followerID, followeeID).Scan(&follow.ID, &follow.FollowerID,
+		&follow.FolloweeID, &follow.Status, &follow.CreatedAt, &follow.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return follow, nil
+}
+
+func (r *FollowRepository) Accept(id int64) error {
+	query := `UPDATE follows SET status = 'accepted', updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// AcceptAllPending accepts every pending follow of the user, for when they
+// stop approving followers.
+func (r *FollowRepository) AcceptAllPending(followeeID int64) error {
+	query := `UPDATE follows SET status = 'accepted', updated_at = CURRENT_TIMESTAMP
+			  WHERE followee_id = ? AND status = 'pending'`
+	_, err := r.db.Exec(query, followeeID)
+	return err
+}
+
+// FollowMutually makes two users follow each other, accepting any pending
+// follow between them.
+func (r *FollowRepository) FollowMutually(userID1, userID2 int64) error {
+	query := `INSERT INTO follows (follower_id, followee_id, status) VALUES (?, ?, 'accepted'), (?, ?, 'accepted')
+			  ON CONFLICT(follower_id, followee_id) DO UPDATE SET status = 'accepted', updated_at = CURRENT_TIMESTAMP`
+	_, err := r.db.Exec(query, userID1, userID2, userID2, userID1)
+	return err
+}
+
+func (r *FollowRepository) Delete(id int64) error {
+	query := `DELETE FROM follows WHERE id = ?`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// DeleteBetween removes follows in both directions.
+func (r *FollowRepository) DeleteBetween(userID1, userID2 int64) error {
+	query := `DELETE FROM follows WHERE (follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)`
+	_, err := r.db.Exec(query, userID1, userID2, userID2, userID1)
+	return err
+}
+
+func (r *FollowRepository) GetFollowers(userID int64) ([]*model.User, error) {
+	query := `SELECT u.id, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at
+			  FROM follows f
+			  INNER JOIN users u ON u.id = f.follower_id
+			  WHERE f.followee_id = ? AND f.status = 'accepted' AND u.deactivated_at IS NULL
+			  ORDER BY f.created_at DESC, f.id DESC`
+	return r.queryUsers(query, userID)
+}
+
+func (r *FollowRepository) GetFollowing(userID int64) ([]*model.User, error) {
+	query := `SELECT u.id, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at
+			  FROM follows f
+			  INNER JOIN users u ON u.id = f.followee_id
+			  WHERE f.follower_id = ? AND f.status = 'accepted' AND u.deactivated_at IS NULL
+			  ORDER BY f.created_at DESC, f.id DESC`
+	return r.queryUsers(query, userID)
+}
+
+func (r *FollowRepository) GetPendingRequests(followeeID int64) ([]*model.Follow, error) {
+	query := `SELECT f.id, f.follower_id, f.followee_id, f.status, f.created_at, f.updated_at
+			  FROM follows f
+			  INNER JOIN users u ON u.id = f.follower_id
+			  WHERE f.followee_id = ? AND f.status = 'pending' AND u.deactivated_at IS NULL
+			  ORDER BY f.created_at DESC, f.id DESC`
+	rows, err := r.db.Query(query, followeeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var follows []*model.Follow
+	for rows.Next() {
+		follow := &model.Follow{}
+		err := rows.Scan(&follow.ID, &follow.FollowerID, &follow.FolloweeID,
+			&follow.Status, &follow.CreatedAt, &follow.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		follows = append(follows, follow)
+	}
+	return follows, rows.Err()
+}
+
+func (r *FollowRepository) CountFollowers(userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM follows f
+			  INNER JOIN users u ON u.id = f.follower_id
+			  WHERE f.followee_id = ? AND f.status = 'accepted' AND u.deactivated_at IS NULL`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *FollowRepository) CountFollowing(userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM follows f
+			  INNER JOIN users u ON u.id = f.followee_id
+			  WHERE f.follower_id = ? AND f.status = 'accepted' AND u.deactivated_at IS NULL`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *FollowRepository) queryUsers(query string, args ...interface{}) ([]*model.User, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user := &model.User{}
+		err := rows.Scan(&user.ID, &user.Username, &user.FullName,
+			&user.Bio, &user.AvatarURL, &user.IsAdmin, &user.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
@@ -40,11 +40,11 @@ func (r *FriendshipRepository) GetByID(id int64) (*model.Friendship, error) {
 }
 
 func (r *FriendshipRepository) GetFriends(userID int64) ([]*model.User, error) {
-	query := `SELECT u.id, u.email, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at
+	query := `SELECT u.id, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at
 			  FROM users u
 			  INNER JOIN friendships f ON (f.requester_id = u.id OR f.addressee_id = u.id)
 			  WHERE (f.requester_id = ? OR f.addressee_id = ?) 
-			  AND f.status = 'accepted' AND u.id != ?`
+			  AND f.status = 'accepted' AND u.id != ? AND u.deactivated_at IS NULL`
 	rows, err := r.db.Query(query, userID, userID, userID)
 	if err != nil {
 		return nil, err
@@ -54,7 +54,7 @@ func (r *FriendshipRepository) GetFriends(userID int64) ([]*model.User, error) {
 	var users []*model.User
 	for rows.Next() {
 		user := &model.User{}
-		err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.FullName,
+		err := rows.Scan(&user.ID, &user.Username, &user.FullName,
 			&user.Bio, &user.AvatarURL, &user.IsAdmin, &user.CreatedAt)
 		if err != nil {
 			return nil, err
@@ -96,3 +96,73 @@ func (r *FriendshipRepository) AreFriends(userID1, userID2 int64) (bool, error)
 	err := r.db.QueryRow(query, userID1, userID2, userID2, userID1).Scan(&exists)
 	return exists, err
 }
+
+func (r *FriendshipRepository) HaveMutualFriend(userID1, userID2 int64) (bool, error) {
+	query := `SELECT EXISTS(
+		SELECT 1 FROM friendships f1
+		INNER JOIN friendships f2 ON f2.status = 'accepted'
+			AND (CASE WHEN f1.requester_id = ? THEN f1.addressee_id ELSE f1.requester_id END)
+				IN (f2.requester_id, f2.addressee_id)
+			AND ? IN (f2.requester_id, f2.addressee_id)
+		WHERE f1.status = 'accepted' AND ? IN (f1.requester_id, f1.addressee_id)
+	)`
+	var exists bool
+	err := r.db.QueryRow(query, userID1, userID2, userID1).Scan(&exists)
+	return exists, err
+}
+
+// GetBetween returns the friendship or request between two users, or nil if
+// there is none. A block takes precedence over anything else.
+func (r *FriendshipRepository) GetBetween(userID1, userID2 int64) (*model.Friendship, error) {
+	query := `SELECT id, requester_id, addressee_id, status, created_at, updated_at
+			  FROM friendships
+			  WHERE (requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)
+			  ORDER BY status = 'blocked' DESC, status = 'accepted' DESC, id DESC LIMIT 1`
+	friendship := &model.Friendship{}
+	err := r.db.QueryRow(query, userID1, userID2, userID2, userID1).Scan(&friendship.ID, &friendship.RequesterID,
+		&friendship.AddresseeID, &friendship.Status, &friendship.CreatedAt, &friendship.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return friendship, nil
+}
+
+func (r *FriendshipRepository) CountFriends(userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM friendships f
+			  INNER JOIN users u ON u.id = CASE WHEN f.requester_id = ? THEN f.addressee_id ELSE f.requester_id END
+			  WHERE (f.requester_id = ? OR f.addressee_id = ?) AND f.status = 'accepted' AND u.deactivated_at IS NULL`
+	var count int
+	err := r.db.QueryRow(query, userID, userID, userID).Scan(&count)
+	return count, err
+}
+
+func (r *FriendshipRepository) GetMutualFriends(userID1, userID2 int64) ([]*model.User, error) {
+	query := `SELECT u.id, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at
+			  FROM users u
+			  WHERE u.deactivated_at IS NULL AND u.id NOT IN (?, ?)
+			  AND EXISTS(SELECT 1 FROM friendships f WHERE f.status = 'accepted'
+				AND ((f.requester_id = ? AND f.addressee_id = u.id) OR (f.addressee_id = ? AND f.requester_id = u.id)))
+			  AND EXISTS(SELECT 1 FROM friendships f WHERE f.status = 'accepted'
+				AND ((f.requester_id = ? AND f.addressee_id = u.id) OR (f.addressee_id = ? AND f.requester_id = u.id)))
+			  ORDER BY u.username`
+	rows, err := r.db.Query(query, userID1, userID2, userID1, userID1, userID2, userID2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user := &model.User{}
+		err := rows.Scan(&user.ID, &user.Username, &user.FullName,
+			&user.Bio, &user.AvatarURL, &user.IsAdmin, &user.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
@@ -70,6 +70,13 @@ func (r *GroupRepository) GetMemberCount(groupID int64) (int, error) {
 	return count, err
 }
 
+func (r *GroupRepository) CountUserGroups(userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM group_members WHERE user_id = ?`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
 func (r *GroupRepository) CreatePost(post *model.GroupPost) (int64, error) {
 	query := `INSERT INTO group_posts (group_id, user_id, content) VALUES (?, ?, ?)`
 	result, err := r.db.Exec(query, post.GroupID, post.UserID, post.Content)
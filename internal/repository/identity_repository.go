@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"socialnet/internal/model"
+	"time"
+)
+
+// ErrIdentityNotFound is returned when no linked identity matches the lookup.
+var ErrIdentityNotFound = errors.New("identity not found")
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) Create(identity *model.Identity) (int64, error) {
+	query := `INSERT INTO identities (user_id, provider, subject, email) VALUES (?, ?, ?, ?)`
+	result, err := r.db.Exec(query, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *IdentityRepository) GetBySubject(provider, subject string) (*model.Identity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at, last_login_at
+			  FROM identities WHERE provider = ? AND subject = ?`
+	identity, err := scanIdentity(r.db.QueryRow(query, provider, subject))
+	if err == sql.ErrNoRows {
+		return nil, ErrIdentityNotFound
+	}
+	return identity, err
+}
+
+// GetByUserAndProvider returns the identity the user linked from the
+// provider, or nil if they have not linked one.
+func (r *IdentityRepository) GetByUserAndProvider(userID int64, provider string) (*model.Identity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at, last_login_at
+			  FROM identities WHERE user_id = ? AND provider = ?`
+	identity, err := scanIdentity(r.db.QueryRow(query, userID, provider))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return identity, err
+}
+
+func (r *IdentityRepository) GetByUser(userID int64) ([]*model.Identity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at, last_login_at
+			  FROM identities WHERE user_id = ? ORDER BY created_at`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*model.Identity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func (r *IdentityRepository) CountByUser(userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM identities WHERE user_id = ?`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *IdentityRepository) TouchLogin(id int64, email string) error {
+	query := `UPDATE identities SET last_login_at = CURRENT_TIMESTAMP, email = ? WHERE id = ?`
+	_, err := r.db.Exec(query, email, id)
+	return err
+}
+
+func (r *IdentityRepository) Delete(id, userID int64) error {
+	query := `DELETE FROM identities WHERE id = ? AND user_id = ?`
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (r *IdentityRepository) CreateState(state *model.OIDCState, ttl time.Duration) error {
+	if _, err := r.db.Exec(`DELETE FROM oidc_states WHERE expires_at <= datetime('now')`); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO oidc_states (state, provider, nonce, code_verifier, user_id, expires_at)
+			  VALUES (?, ?, ?, ?, ?, datetime('now', ?))`
+	var userID sql.NullInt64
+	if state.UserID != 0 {
+		userID = sql.NullInt64{Int64: state.UserID, Valid: true}
+	}
+	modifier := fmt.Sprintf("+%d seconds", int64(ttl.Seconds()))
+	_, err := r.db.Exec(query, state.State, state.Provider, state.Nonce, state.CodeVerifier, userID, modifier)
+	return err
+}
+
+// ConsumeState removes and returns a pending authorization, so each state
+// value can complete at most one callback.
+func (r *IdentityRepository) ConsumeState(state string) (*model.OIDCState, error) {
+	query := `DELETE FROM oidc_states WHERE state = ? AND expires_at > datetime('now')
+			  RETURNING state, provider, nonce, code_verifier, user_id`
+	pending := &model.OIDCState{}
+	var userID sql.NullInt64
+	err := r.db.QueryRow(query, state).Scan(&pending.State, &pending.Provider, &pending.Nonce,
+		&pending.CodeVerifier, &userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid or expired state")
+	}
+	if err != nil {
+		return nil, err
+	}
+	pending.UserID = userID.Int64
+	return pending, nil
+}
+
+type identityScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanIdentity(scanner identityScanner) (*model.Identity, error) {
+	identity := &model.Identity{}
+	var email sql.NullString
+	var lastLoginAt sql.NullTime
+	err := scanner.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&email, &identity.CreatedAt, &lastLoginAt)
+	if err != nil {
+		return nil, err
+	}
+	identity.Email = email.String
+	if lastLoginAt.Valid {
+		identity.LastLoginAt = &lastLoginAt.Time
+	}
+	return identity, nil
+}
@@ -14,8 +14,14 @@ func NewLikeRepository(db *sql.DB) *LikeRepository {
 }
 
 func (r *LikeRepository) Create(like *model.Like) error {
-	query := `INSERT INTO likes (post_id, user_id) VALUES (?, ?)`
-	_, err := r.db.Exec(query, like.PostID, like.UserID)
+	query := `INSERT INTO likes (post_id, user_id, reaction) VALUES (?, ?, ?)`
+	_, err := r.db.Exec(query, like.PostID, like.UserID, like.Reaction)
+	return err
+}
+
+func (r *LikeRepository) UpdateReaction(postID, userID int64, reaction model.ReactionType) error {
+	query := `UPDATE likes SET reaction = ? WHERE post_id = ? AND user_id = ?`
+	_, err := r.db.Exec(query, reaction, postID, userID)
 	return err
 }
 
@@ -25,16 +31,68 @@ func (r *LikeRepository) Delete(postID, userID int64) error {
 	return err
 }
 
-func (r *LikeRepository) GetCountByPostID(postID int64) (int, error) {
-	query := `SELECT COUNT(*) FROM likes WHERE post_id = ?`
-	var count int
-	err := r.db.QueryRow(query, postID).Scan(&count)
-	return count, err
+// GetReactionCounts returns how many users reacted to the post with each
+// type of reaction. Types nobody used are left out.
+func (r *LikeRepository) GetReactionCounts(postID int64) (map[model.ReactionType]int, error) {
+	query := `SELECT l.reaction, COUNT(*) FROM likes l
+			  INNER JOIN users u ON u.id = l.user_id
+			  WHERE l.post_id = ? AND u.deactivated_at IS NULL
+			  GROUP BY l.reaction`
+	rows, err := r.db.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[model.ReactionType]int)
+	for rows.Next() {
+		var reaction model.ReactionType
+		var count int
+		if err := rows.Scan(&reaction, &count); err != nil {
+			return nil, err
+		}
+		counts[reaction] = count
+	}
+	return counts, rows.Err()
 }
 
-func (r *LikeRepository) HasUserLiked(postID, userID int64) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM likes WHERE post_id = ? AND user_id = ?)`
-	var exists bool
-	err := r.db.QueryRow(query, postID, userID).Scan(&exists)
-	return exists, err
+// GetReaction returns the user's reaction to the post, or "" if they have
+// not reacted.
+func (r *LikeRepository) GetReaction(postID, userID int64) (model.ReactionType, error) {
+	query := `SELECT reaction FROM likes WHERE post_id = ? AND user_id = ?`
+	var reaction model.ReactionType
+	err := r.db.QueryRow(query, postID, userID).Scan(&reaction)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return reaction, err
+}
+
+// GetReactions lists who reacted to the post, newest first, optionally only
+// with one type of reaction.
+func (r *LikeRepository) GetReactions(postID int64, reaction model.ReactionType) ([]*model.Like, error) {
+	query := `SELECT l.id, l.post_id, l.user_id, l.reaction, l.created_at,
+			  u.id, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at
+			  FROM likes l
+			  INNER JOIN users u ON u.id = l.user_id
+			  WHERE l.post_id = ? AND (? = '' OR l.reaction = ?) AND u.deactivated_at IS NULL
+			  ORDER BY l.created_at DESC, l.id DESC`
+	rows, err := r.db.Query(query, postID, reaction, reaction)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var likes []*model.Like
+	for rows.Next() {
+		like := &model.Like{User: &model.User{}}
+		err := rows.Scan(&like.ID, &like.PostID, &like.UserID, &like.Reaction, &like.CreatedAt,
+			&like.User.ID, &like.User.Username, &like.User.FullName, &like.User.Bio,
+			&like.User.AvatarURL, &like.User.IsAdmin, &like.User.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		likes = append(likes, like)
+	}
+	return likes, rows.Err()
 }
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"socialnet/internal/model"
+	"time"
+)
+
+type LoginFailureRepository struct {
+	db *sql.DB
+}
+
+func NewLoginFailureRepository(db *sql.DB) *LoginFailureRepository {
+	return &LoginFailureRepository{db: db}
+}
+
+// LockedFor returns how long the key stays locked, or zero when it is not.
+func (r *LoginFailureRepository) LockedFor(scope model.LockoutScope, key string) (time.Duration, error) {
+	query := `SELECT CAST(strftime('%s', locked_until) AS INTEGER) - CAST(strftime('%s', 'now') AS INTEGER)
+			  FROM login_failures
+			  WHERE scope = ? AND key = ? AND locked_until > datetime('now')`
+	var seconds int64
+	err := r.db.QueryRow(query, scope, key).Scan(&seconds)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// RecordFailure counts a failed attempt and returns the new total. Failures
+// older than window no longer count, so the counter starts over after a quiet
+// period.
+func (r *LoginFailureRepository) RecordFailure(scope model.LockoutScope, key string, window time.Duration) (int64, int, error) {
+	query := `INSERT INTO login_failures (scope, key, failures, last_failure_at) VALUES (?, ?, 1, CURRENT_TIMESTAMP)
+			  ON CONFLICT(scope, key) DO UPDATE SET
+			  failures = CASE WHEN login_failures.last_failure_at < datetime('now', ?) THEN 1
+			  ELSE login_failures.failures + 1 END,
+			  last_failure_at = CURRENT_TIMESTAMP
+			  RETURNING id, failures`
+	modifier := fmt.Sprintf("-%d seconds", int64(window.Seconds()))
+	var id int64
+	var failures int
+	err := r.db.QueryRow(query, scope, key, modifier).Scan(&id, &failures)
+	return id, failures, err
+}
+
+func (r *LoginFailureRepository) Lock(id int64, duration time.Duration) error {
+	query := `UPDATE login_failures SET locked_until = datetime('now', ?) WHERE id = ?`
+	modifier := fmt.Sprintf("+%d seconds", int64(duration.Seconds()))
+	_, err := r.db.Exec(query, modifier, id)
+	return err
+}
+
+func (r *LoginFailureRepository) Reset(scope model.LockoutScope, key string) error {
+	query := `DELETE FROM login_failures WHERE scope = ? AND key = ?`
+	_, err := r.db.Exec(query, scope, key)
+	return err
+}
+
+func (r *LoginFailureRepository) GetLocked(limit int) ([]*model.Lockout, error) {
+	query := `SELECT id, scope, key, failures, last_failure_at, locked_until
+			  FROM login_failures WHERE locked_until > datetime('now')
+			  ORDER BY locked_until DESC LIMIT ?`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lockouts []*model.Lockout
+	for rows.Next() {
+		lockout := &model.Lockout{}
+		var lockedUntil sql.NullTime
+		err := rows.Scan(&lockout.ID, &lockout.Scope, &lockout.Key, &lockout.Failures,
+			&lockout.LastFailureAt, &lockedUntil)
+		if err != nil {
+			return nil, err
+		}
+		if lockedUntil.Valid {
+			lockout.LockedUntil = &lockedUntil.Time
+		}
+		lockouts = append(lockouts, lockout)
+	}
+	return lockouts, rows.Err()
+}
+
+func (r *LoginFailureRepository) Delete(id int64) error {
+	query := `DELETE FROM login_failures WHERE id = ?`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("lockout not found")
+	}
+	return nil
+}
@@ -75,7 +75,7 @@ func (r *MessageRepository) GetMessages(conversationID int64, limit int) ([]*mod
 
 func (r *MessageRepository) GetUserConversations(userID int64) ([]*model.Conversation, error) {
 	query := `SELECT c.id, c.created_at,
-			  u.id, u.email, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at,
+			  u.id, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at,
 			  m.id, m.conversation_id, m.user_id, m.body, m.created_at, m.read_at
 			  FROM conversations c
 			  INNER JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = ?
@@ -96,7 +96,6 @@ func (r *MessageRepository) GetUserConversations(userID int64) ([]*model.Convers
 		conversation := &model.Conversation{}
 		participant := &model.User{}
 		var participantID sql.NullInt64
-		var participantEmail sql.NullString
 		var participantUsername sql.NullString
 		var participantFullName sql.NullString
 		var participantBio sql.NullString
@@ -114,7 +113,7 @@ func (r *MessageRepository) GetUserConversations(userID int64) ([]*model.Convers
 
 		err := rows.Scan(
 			&conversation.ID, &conversation.CreatedAt,
-			&participantID, &participantEmail, &participantUsername, &participantFullName,
+			&participantID, &participantUsername, &participantFullName,
 			&participantBio, &participantAvatarURL, &participantIsAdmin, &participantCreatedAt,
 			&messageID, &messageConversationID, &messageUserID, &messageBody, &messageCreatedAt, &messageReadAt,
 		)
@@ -124,7 +123,6 @@ func (r *MessageRepository) GetUserConversations(userID int64) ([]*model.Convers
 
 		if participantID.Valid {
 			participant.ID = participantID.Int64
-			participant.Email = participantEmail.String
 			participant.Username = participantUsername.String
 			participant.FullName = participantFullName.String
 			participant.Bio = participantBio.String
@@ -162,3 +160,22 @@ func (r *MessageRepository) IsMember(conversationID, userID int64) (bool, error)
 	err := r.db.QueryRow(query, conversationID, userID).Scan(&exists)
 	return exists, err
 }
+
+func (r *MessageRepository) GetMemberIDs(conversationID int64) ([]int64, error) {
+	query := `SELECT user_id FROM conversation_members WHERE conversation_id = ?`
+	rows, err := r.db.Query(query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"socialnet/internal/model"
+	"time"
+)
+
+type NotificationPreferenceRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationPreferenceRepository(db *sql.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+
+func (r *NotificationPreferenceRepository) GetPreferences(userID int64) ([]*model.NotificationPreference, error) {
+	query := `SELECT type, channel, enabled FROM notification_preferences WHERE user_id = ? ORDER BY type, channel`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var preferences []*model.NotificationPreference
+	for rows.Next() {
+		preference := &model.NotificationPreference{}
+		if err := rows.Scan(&preference.Type, &preference.Channel, &preference.Enabled); err != nil {
+			return nil, err
+		}
+		preferences = append(preferences, preference)
+	}
+	return preferences, rows.Err()
+}
+
+func (r *NotificationPreferenceRepository) SetPreference(userID int64, preference *model.NotificationPreference) error {
+	query := `INSERT INTO notification_preferences (user_id, type, channel, enabled) VALUES (?, ?, ?, ?)
+			  ON CONFLICT(user_id, type, channel) DO UPDATE SET enabled = excluded.enabled`
+	_, err := r.db.Exec(query, userID, preference.Type, preference.Channel, preference.Enabled)
+	return err
+}
+
+// IsEnabled reports whether the user receives the given type on the given
+// channel. A missing row means the user never opted out.
+func (r *NotificationPreferenceRepository) IsEnabled(userID int64, notifType model.NotificationType,
+	channel model.NotificationChannel) (bool, error) {
+	query := `SELECT enabled FROM notification_preferences WHERE user_id = ? AND type = ? AND channel = ?`
+	var enabled bool
+	err := r.db.QueryRow(query, userID, notifType, channel).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return enabled, err
+}
+
+func (r *NotificationPreferenceRepository) Mute(userID int64, targetType model.MuteTargetType, targetID int64) error {
+	query := `INSERT OR IGNORE INTO notification_mutes (user_id, target_type, target_id) VALUES (?, ?, ?)`
+	_, err := r.db.Exec(query, userID, targetType, targetID)
+	return err
+}
+
+func (r *NotificationPreferenceRepository) Unmute(userID int64, targetType model.MuteTargetType, targetID int64) error {
+	query := `DELETE FROM notification_mutes WHERE user_id = ? AND target_type = ? AND target_id = ?`
+	_, err := r.db.Exec(query, userID, targetType, targetID)
+	return err
+}
+
+func (r *NotificationPreferenceRepository) IsMuted(userID int64, targetType model.MuteTargetType, targetID int64) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM notification_mutes WHERE user_id = ? AND target_type = ? AND target_id = ?)`
+	var exists bool
+	err := r.db.QueryRow(query, userID, targetType, targetID).Scan(&exists)
+	return exists, err
+}
+
+func (r *NotificationPreferenceRepository) GetMutes(userID int64) ([]*model.NotificationMute, error) {
+	query := `SELECT id, user_id, target_type, target_id, created_at
+			  FROM notification_mutes WHERE user_id = ? ORDER BY created_at DESC`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mutes []*model.NotificationMute
+	for rows.Next() {
+		mute := &model.NotificationMute{}
+		err := rows.Scan(&mute.ID, &mute.UserID, &mute.TargetType, &mute.TargetID, &mute.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		mutes = append(mutes, mute)
+	}
+	return mutes, rows.Err()
+}
+
+func (r *NotificationPreferenceRepository) GetQuietHours(userID int64) (*model.QuietHours, error) {
+	query := `SELECT start_time, end_time, timezone FROM notification_quiet_hours WHERE user_id = ?`
+	quietHours := &model.QuietHours{}
+	err := r.db.QueryRow(query, userID).Scan(&quietHours.Start, &quietHours.End, &quietHours.Timezone)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return quietHours, err
+}
+
+func (r *NotificationPreferenceRepository) SetQuietHours(userID int64, quietHours *model.QuietHours) error {
+	query := `INSERT INTO notification_quiet_hours (user_id, start_time, end_time, timezone) VALUES (?, ?, ?, ?)
+			  ON CONFLICT(user_id) DO UPDATE SET start_time = excluded.start_time,
+			  end_time = excluded.end_time, timezone = excluded.timezone`
+	_, err := r.db.Exec(query, userID, quietHours.Start, quietHours.End, quietHours.Timezone)
+	return err
+}
+
+func (r *NotificationPreferenceRepository) DeleteQuietHours(userID int64) error {
+	query := `DELETE FROM notification_quiet_hours WHERE user_id = ?`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
+
+func (r *NotificationPreferenceRepository) GetDigestFrequency(userID int64) (model.DigestFrequency, error) {
+	query := `SELECT frequency FROM email_digest_settings WHERE user_id = ?`
+	var frequency model.DigestFrequency
+	err := r.db.QueryRow(query, userID).Scan(&frequency)
+	if err == sql.ErrNoRows {
+		return model.DigestOff, nil
+	}
+	return frequency, err
+}
+
+func (r *NotificationPreferenceRepository) SetDigestFrequency(userID int64, frequency model.DigestFrequency) error {
+	query := `INSERT INTO email_digest_settings (user_id, frequency) VALUES (?, ?)
+			  ON CONFLICT(user_id) DO UPDATE SET frequency = excluded.frequency`
+	_, err := r.db.Exec(query, userID, frequency)
+	return err
+}
+
+// GetDueDigests returns the users on the given frequency whose last digest is
+// older than interval, or who never received one.
+func (r *NotificationPreferenceRepository) GetDueDigests(frequency model.DigestFrequency,
+	interval time.Duration) ([]int64, error) {
+	query := `SELECT user_id FROM email_digest_settings
+			  WHERE frequency = ? AND (last_sent_at IS NULL OR last_sent_at <= datetime('now', ?))`
+	modifier := fmt.Sprintf("-%d seconds", int64(interval.Seconds()))
+	rows, err := r.db.Query(query, frequency, modifier)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (r *NotificationPreferenceRepository) MarkDigestSent(userID int64) error {
+	query := `UPDATE email_digest_settings SET last_sent_at = CURRENT_TIMESTAMP WHERE user_id = ?`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
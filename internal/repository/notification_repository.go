@@ -2,6 +2,8 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"socialnet/internal/model"
 	"time"
 )
@@ -15,7 +17,8 @@ func NewNotificationRepository(db *sql.DB) *NotificationRepository {
 }
 
 func (r *NotificationRepository) Create(notification *model.Notification) (int64, error) {
-	query := `INSERT INTO notifications (user_id, type, target_id, message) VALUES (?, ?, ?, ?)`
+	query := `INSERT INTO notifications (user_id, type, target_id, message, updated_at, seq)
+			  VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, (SELECT COALESCE(MAX(seq), 0) + 1 FROM notifications))`
 	result, err := r.db.Exec(query, notification.UserID, notification.Type,
 		notification.TargetID, notification.Message)
 	if err != nil {
@@ -24,10 +27,56 @@ func (r *NotificationRepository) Create(notification *model.Notification) (int64
 	return result.LastInsertId()
 }
 
-func (r *NotificationRepository) GetByUser(userID int64, limit int) ([]*model.Notification, error) {
-	query := `SELECT id, user_id, type, target_id, message, read, created_at 
-			  FROM notifications WHERE user_id = ? ORDER BY created_at DESC LIMIT ?`
-	rows, err := r.db.Query(query, userID, limit)
+func (r *NotificationRepository) GetByID(id int64) (*model.Notification, error) {
+	query := `SELECT id, user_id, type, target_id, message, read, actor_count, seq, created_at, updated_at
+			  FROM notifications WHERE id = ?`
+	row := r.db.QueryRow(query, id)
+	notification, err := scanNotification(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("notification not found")
+	}
+	return notification, err
+}
+
+func (r *NotificationRepository) GetByUser(userID int64, filter *model.NotificationFilter) ([]*model.Notification, error) {
+	query := `SELECT id, user_id, type, target_id, message, read, actor_count, seq, created_at, updated_at
+			  FROM notifications WHERE user_id = ?`
+	args := []interface{}{userID}
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.Read != nil {
+		query += ` AND read = ?`
+		args = append(args, *filter.Read)
+	}
+	query += ` ORDER BY COALESCE(updated_at, created_at) DESC, id DESC LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*model.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// GetSince returns the user's notifications created or updated after the
+// given sequence number, oldest first, for replaying a missed stream.
+func (r *NotificationRepository) GetSince(userID, seq int64, limit int) ([]*model.Notification, error) {
+	query := `SELECT id, user_id, type, target_id, message, read, actor_count, seq, created_at, updated_at
+			  FROM notifications WHERE user_id = ? AND seq > ?
+			  ORDER BY seq ASC LIMIT ?`
+	rows, err := r.db.Query(query, userID, seq, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -35,38 +84,170 @@ func (r *NotificationRepository) GetByUser(userID int64, limit int) ([]*model.No
 
 	var notifications []*model.Notification
 	for rows.Next() {
-		notification := &model.Notification{}
-		var targetID sql.NullInt64
-		err := rows.Scan(&notification.ID, &notification.UserID, &notification.Type,
-			&targetID, &notification.Message, &notification.Read, &notification.CreatedAt)
+		notification, err := scanNotification(rows)
 		if err != nil {
 			return nil, err
 		}
-		if targetID.Valid {
-			notification.TargetID = targetID.Int64
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// GetDigestItems returns the unread notifications that arrived since the
+// user's last digest.
+func (r *NotificationRepository) GetDigestItems(userID int64, limit int) ([]*model.Notification, error) {
+	query := `SELECT id, user_id, type, target_id, message, read, actor_count, seq, created_at, updated_at
+			  FROM notifications
+			  WHERE user_id = ? AND read = FALSE
+			  AND COALESCE(updated_at, created_at) > COALESCE(
+				(SELECT last_sent_at FROM email_digest_settings WHERE user_id = ?), '1970-01-01')
+			  ORDER BY COALESCE(updated_at, created_at) DESC LIMIT ?`
+	rows, err := r.db.Query(query, userID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*model.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
 		}
 		notifications = append(notifications, notification)
 	}
 	return notifications, rows.Err()
 }
 
-func (r *NotificationRepository) MarkAsRead(id int64) error {
-	query := `UPDATE notifications SET read = TRUE WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+// FindAggregate returns the notification that a new event of the same type
+// on the same target should be folded into, or nil when the last one is
+// older than the window.
+func (r *NotificationRepository) FindAggregate(userID int64, notifType model.NotificationType, targetID int64,
+	window time.Duration) (*model.Notification, error) {
+	query := `SELECT id, user_id, type, target_id, message, read, actor_count, seq, created_at, updated_at
+			  FROM notifications
+			  WHERE user_id = ? AND type = ? AND target_id = ?
+			  AND COALESCE(updated_at, created_at) >= datetime('now', ?)
+			  ORDER BY id DESC LIMIT 1`
+	modifier := fmt.Sprintf("-%d seconds", int64(window.Seconds()))
+	row := r.db.QueryRow(query, userID, notifType, targetID, modifier)
+	notification, err := scanNotification(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return notification, err
+}
+
+// AddActor records that actorID contributed to the notification. An actor
+// acting again is moved to the front of the latest actors.
+func (r *NotificationRepository) AddActor(notificationID, actorID int64) error {
+	query := `INSERT INTO notification_actors (notification_id, actor_id) VALUES (?, ?)
+			  ON CONFLICT(notification_id, actor_id) DO UPDATE SET created_at = CURRENT_TIMESTAMP`
+	_, err := r.db.Exec(query, notificationID, actorID)
+	return err
+}
+
+func (r *NotificationRepository) GetActorCount(notificationID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM notification_actors WHERE notification_id = ?`
+	var count int
+	err := r.db.QueryRow(query, notificationID).Scan(&count)
+	return count, err
+}
+
+func (r *NotificationRepository) GetActors(notificationID int64, limit int) ([]*model.NotificationActor, error) {
+	query := `SELECT u.id, u.username, u.full_name, u.avatar_url, na.created_at
+			  FROM notification_actors na
+			  INNER JOIN users u ON u.id = na.actor_id
+			  WHERE na.notification_id = ?
+			  ORDER BY na.created_at DESC, na.id DESC LIMIT ?`
+	rows, err := r.db.Query(query, notificationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actors []*model.NotificationActor
+	for rows.Next() {
+		actor := &model.NotificationActor{}
+		var fullName, avatarURL sql.NullString
+		if err := rows.Scan(&actor.ID, &actor.Username, &fullName, &avatarURL, &actor.ActedAt); err != nil {
+			return nil, err
+		}
+		actor.FullName = fullName.String
+		actor.AvatarURL = avatarURL.String
+		actors = append(actors, actor)
+	}
+	return actors, rows.Err()
+}
+
+// UpdateAggregate rewrites an aggregated notification after a new actor
+// joined it and puts it back at the top of the unread list.
+func (r *NotificationRepository) UpdateAggregate(id int64, actorCount int, message string) error {
+	query := `UPDATE notifications SET actor_count = ?, message = ?, read = FALSE, updated_at = CURRENT_TIMESTAMP,
+			  seq = (SELECT COALESCE(MAX(seq), 0) + 1 FROM notifications)
+			  WHERE id = ?`
+	_, err := r.db.Exec(query, actorCount, message, id)
 	return err
 }
 
+func (r *NotificationRepository) MarkAsRead(id, userID int64) error {
+	query := `UPDATE notifications SET read = TRUE WHERE id = ? AND user_id = ?`
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("notification not found")
+	}
+	return nil
+}
+
+// MarkAllAsRead marks the user's notifications as read. When upToID is
+// positive only notifications with an ID up to and including it are touched,
+// so a client can acknowledge exactly what it has displayed.
+func (r *NotificationRepository) MarkAllAsRead(userID, upToID int64) (int64, error) {
+	query := `UPDATE notifications SET read = TRUE WHERE user_id = ? AND read = FALSE`
+	args := []interface{}{userID}
+	if upToID > 0 {
+		query += ` AND id <= ?`
+		args = append(args, upToID)
+	}
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *NotificationRepository) Delete(id, userID int64) error {
+	query := `DELETE FROM notifications WHERE id = ? AND user_id = ?`
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("notification not found")
+	}
+	return r.deleteOrphanActors()
+}
+
 func (r *NotificationRepository) DeleteByUser(userID int64) error {
 	query := `DELETE FROM notifications WHERE user_id = ?`
-	_, err := r.db.Exec(query, userID)
-	return err
+	if _, err := r.db.Exec(query, userID); err != nil {
+		return err
+	}
+	return r.deleteOrphanActors()
 }
 
 func (r *NotificationRepository) DeleteOld(olderThan time.Duration) error {
 	query := `DELETE FROM notifications WHERE read = TRUE AND created_at < ?`
 	cutoff := time.Now().Add(-olderThan)
-	_, err := r.db.Exec(query, cutoff)
-	return err
+	if _, err := r.db.Exec(query, cutoff); err != nil {
+		return err
+	}
+	return r.deleteOrphanActors()
 }
 
 func (r *NotificationRepository) GetUnreadCount(userID int64) (int, error) {
@@ -75,3 +256,35 @@ func (r *NotificationRepository) GetUnreadCount(userID int64) (int, error) {
 	err := r.db.QueryRow(query, userID).Scan(&count)
 	return count, err
 }
+
+func (r *NotificationRepository) deleteOrphanActors() error {
+	query := `DELETE FROM notification_actors WHERE notification_id NOT IN (SELECT id FROM notifications)`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+type notificationScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanNotification(scanner notificationScanner) (*model.Notification, error) {
+	notification := &model.Notification{}
+	var targetID sql.NullInt64
+	var seq sql.NullInt64
+	var updatedAt sql.NullTime
+	err := scanner.Scan(&notification.ID, &notification.UserID, &notification.Type,
+		&targetID, &notification.Message, &notification.Read, &notification.ActorCount,
+		&seq, &notification.CreatedAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if targetID.Valid {
+		notification.TargetID = targetID.Int64
+	}
+	notification.Seq = seq.Int64
+	notification.UpdatedAt = notification.CreatedAt
+	if updatedAt.Valid {
+		notification.UpdatedAt = updatedAt.Time
+	}
+	return notification, nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"socialnet/internal/model"
+	"time"
+)
+
+type PollRepository struct {
+	db *sql.DB
+}
+
+func NewPollRepository(db *sql.DB) *PollRepository {
+	return &PollRepository{db: db}
+}
+
+// Create stores the poll of a post together with its options, in the
+// order given.
+func (r *PollRepository) Create(postID int64, create *model.PollCreate) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO polls (post_id, multiple_choice, anonymous, hide_results, ends_at)
+			  VALUES (?, ?, ?, ?, datetime(?))`
+	result, err := tx.Exec(query, postID, create.MultipleChoice, create.Anonymous, create.HideResults,
+		timestamp(create.EndsAt))
+	if err != nil {
+		return 0, err
+	}
+	pollID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, text := range create.Options {
+		query := `INSERT INTO poll_options (poll_id, position, text) VALUES (?, ?, ?)`
+		if _, err := tx.Exec(query, pollID, i, text); err != nil {
+			return 0, err
+		}
+	}
+
+	return pollID, tx.Commit()
+}
+
+// GetByPostID returns the post's poll with the vote count of each option,
+// or nil if the post has no poll. Votes of deactivated users are not
+// counted.
+func (r *PollRepository) GetByPostID(postID int64) (*model.Poll, error) {
+	query := `SELECT id, post_id, multiple_choice, anonymous, hide_results, ends_at, created_at
+			  FROM polls WHERE post_id = ?`
+	poll := &model.Poll{}
+	var endsAt sql.NullTime
+	err := r.db.QueryRow(query, postID).Scan(&poll.ID, &poll.PostID, &poll.MultipleChoice,
+		&poll.Anonymous, &poll.HideResults, &endsAt, &poll.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if endsAt.Valid {
+		poll.EndsAt = &endsAt.Time
+		poll.Closed = !endsAt.Time.After(time.Now())
+	}
+
+	poll.Options, err = r.getOptions(poll.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var voters int
+	query = `SELECT COUNT(DISTINCT v.user_id) FROM poll_votes v
+			 INNER JOIN users u ON u.id = v.user_id
+			 WHERE v.poll_id = ? AND u.deactivated_at IS NULL`
+	if err := r.db.QueryRow(query, poll.ID).Scan(&voters); err != nil {
+		return nil, err
+	}
+	poll.VoterCount = &voters
+
+	return poll, nil
+}
+
+func (r *PollRepository) getOptions(pollID int64) ([]*model.PollOption, error) {
+	query := `SELECT o.id, o.text, COUNT(u.id) FROM poll_options o
+			  LEFT JOIN poll_votes v ON v.option_id = o.id
+			  LEFT JOIN users u ON u.id = v.user_id AND u.deactivated_at IS NULL
+			  WHERE o.poll_id = ?
+			  GROUP BY o.id ORDER BY o.position`
+	rows, err := r.db.Query(query, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var options []*model.PollOption
+	for rows.Next() {
+		option := &model.PollOption{}
+		var votes int
+		if err := rows.Scan(&option.ID, &option.Text, &votes); err != nil {
+			return nil, err
+		}
+		option.Votes = &votes
+		options = append(options, option)
+	}
+	return options, rows.Err()
+}
+
+// GetUserVotes returns the IDs of the options the user voted for.
+func (r *PollRepository) GetUserVotes(pollID, userID int64) ([]int64, error) {
+	query := `SELECT option_id FROM poll_votes WHERE poll_id = ? AND user_id = ? ORDER BY option_id`
+	rows, err := r.db.Query(query, pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	optionIDs := []int64{}
+	for rows.Next() {
+		var optionID int64
+		if err := rows.Scan(&optionID); err != nil {
+			return nil, err
+		}
+		optionIDs = append(optionIDs, optionID)
+	}
+	return optionIDs, rows.Err()
+}
+
+// SetVotes replaces the user's votes on the poll.
+func (r *PollRepository) SetVotes(pollID, userID int64, optionIDs []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?`, pollID, userID); err != nil {
+		return err
+	}
+	for _, optionID := range optionIDs {
+		query := `INSERT INTO poll_votes (poll_id, option_id, user_id) VALUES (?, ?, ?)`
+		if _, err := tx.Exec(query, pollID, optionID, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteVotes withdraws the user's votes and reports whether there were
+// any.
+func (r *PollRepository) DeleteVotes(pollID, userID int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?`, pollID, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// GetVoters returns the active users who voted for the option, latest
+// first.
+func (r *PollRepository) GetVoters(optionID int64) ([]*model.User, error) {
+	query := `SELECT u.id, u.username, u.full_name, u.bio, u.avatar_url, u.is_admin, u.created_at
+			  FROM poll_votes v
+			  INNER JOIN users u ON u.id = v.user_id
+			  WHERE v.option_id = ? AND u.deactivated_at IS NULL
+			  ORDER BY v.created_at DESC, v.id DESC`
+	rows, err := r.db.Query(query, optionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user := &model.User{}
+		err := rows.Scan(&user.ID, &user.Username, &user.FullName,
+			&user.Bio, &user.AvatarURL, &user.IsAdmin, &user.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// GetClosedUnnotified returns the IDs of posts whose poll has ended and
+// whose author has not been told yet. Polls of unpublished posts wait
+// until the post is published.
+func (r *PollRepository) GetClosedUnnotified(limit int) ([]int64, error) {
+	query := `SELECT pl.post_id FROM polls pl
+			  INNER JOIN posts p ON p.id = pl.post_id
+			  WHERE pl.ends_at <= datetime('now') AND pl.closed_notified_at IS NULL AND p.status = 'published'
+			  ORDER BY pl.ends_at, pl.id LIMIT ?`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var postIDs []int64
+	for rows.Next() {
+		var postID int64
+		if err := rows.Scan(&postID); err != nil {
+			return nil, err
+		}
+		postIDs = append(postIDs, postID)
+	}
+	return postIDs, rows.Err()
+}
+
+// MarkClosedNotified records that the author was told the poll closed. It
+// reports false if that was already recorded, e.g. by a concurrent worker.
+func (r *PollRepository) MarkClosedNotified(pollID int64) (bool, error) {
+	query := `UPDATE polls SET closed_notified_at = CURRENT_TIMESTAMP WHERE id = ? AND closed_notified_at IS NULL`
+	result, err := r.db.Exec(query, pollID)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// SetEndsAt moves the end of a poll.
+func (r *PollRepository) SetEndsAt(pollID int64, endsAt time.Time) error {
+	query := `UPDATE polls SET ends_at = datetime(?) WHERE id = ?`
+	_, err := r.db.Exec(query, timestamp(&endsAt), pollID)
+	return err
+}
@@ -4,8 +4,12 @@ import (
 	"database/sql"
 	"errors"
 	"socialnet/internal/model"
+	"time"
 )
 
+// ErrPostNotFound is returned when no post matches the lookup.
+var ErrPostNotFound = errors.New("post not found")
+
 type PostRepository struct {
 	db *sql.DB
 }
@@ -15,8 +19,12 @@ func NewPostRepository(db *sql.DB) *PostRepository {
 }
 
 func (r *PostRepository) Create(post *model.Post) (int64, error) {
-	query := `INSERT INTO posts (user_id, content, media_url) VALUES (?, ?, ?)`
-	result, err := r.db.Exec(query, post.UserID, post.Content, post.MediaURL)
+	query := `INSERT INTO posts (user_id, content, media_url, shared_post_id, share_type, status, publish_at)
+			  VALUES (?, ?, ?, ?, ?, ?, datetime(?))`
+	sharedPostID := sql.NullInt64{Int64: post.SharedPostID, Valid: post.SharedPostID != 0}
+	shareType := sql.NullString{String: string(post.ShareType), Valid: post.ShareType != ""}
+	result, err := r.db.Exec(query, post.UserID, post.Content, post.MediaURL, sharedPostID, shareType,
+		post.Status, timestamp(post.PublishAt))
 	if err != nil {
 		return 0, err
 	}
@@ -24,47 +32,252 @@ func (r *PostRepository) Create(post *model.Post) (int64, error) {
 }
 
 func (r *PostRepository) GetByID(id int64) (*model.Post, error) {
-	query := `SELECT id, user_id, content, media_url, created_at, updated_at FROM posts WHERE id = ?`
-	post := &model.Post{}
-	err := r.db.QueryRow(query, id).Scan(
-		&post.ID, &post.UserID, &post.Content, &post.MediaURL, &post.CreatedAt, &post.UpdatedAt,
-	)
+	query := `SELECT id, user_id, content, media_url, shared_post_id, share_type, status, publish_at,
+			  created_at, updated_at FROM posts WHERE id = ?`
+	post, err := scanPost(r.db.QueryRow(query, id))
 	if err == sql.ErrNoRows {
-		return nil, errors.New("post not found")
+		return nil, ErrPostNotFound
 	}
 	return post, err
 }
 
+// Update replaces the post's content, keeping the version it replaces as a
+// revision.
 func (r *PostRepository) Update(post *model.Post) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	revision := `INSERT INTO post_revisions (post_id, content, media_url, created_at)
+				 SELECT id, content, media_url, updated_at FROM posts WHERE id = ?`
+	result, err := tx.Exec(revision, post.ID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrPostNotFound
+	}
+
 	query := `UPDATE posts SET content = ?, media_url = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := tx.Exec(query, post.Content, post.MediaURL, post.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateDraft replaces the content of a post that is not published yet.
+// Nobody else has seen it, so no revision is kept.
+func (r *PostRepository) UpdateDraft(post *model.Post) error {
+	query := `UPDATE posts SET content = ?, media_url = ?, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = ? AND status != 'published'`
 	result, err := r.db.Exec(query, post.Content, post.MediaURL, post.ID)
 	if err != nil {
 		return err
 	}
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return errors.New("post not found")
+		return ErrPostNotFound
 	}
 	return nil
 }
 
-func (r *PostRepository) Delete(id int64) error {
-	query := `DELETE FROM posts WHERE id = ?`
-	result, err := r.db.Exec(query, id)
+// Schedule sets an unpublished post to be published at publishAt, or turns
+// it back into a draft when publishAt is nil.
+func (r *PostRepository) Schedule(id int64, publishAt *time.Time) error {
+	query := `UPDATE posts SET status = CASE WHEN ?1 IS NULL THEN 'draft' ELSE 'scheduled' END,
+			  publish_at = datetime(?1), updated_at = CURRENT_TIMESTAMP
+			  WHERE id = ?2 AND status != 'published'`
+	result, err := r.db.Exec(query, timestamp(publishAt), id)
 	if err != nil {
 		return err
 	}
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return errors.New("post not found")
+		return ErrPostNotFound
 	}
 	return nil
 }
 
-func (r *PostRepository) GetUserPosts(userID int64, limit int) ([]*model.Post, error) {
-	query := `SELECT id, user_id, content, media_url, created_at, updated_at 
-			  FROM posts WHERE user_id = ? ORDER BY created_at DESC LIMIT ?`
-	rows, err := r.db.Query(query, userID, limit)
+// Publish publishes a draft or scheduled post, dating it to now so it takes
+// its place in feeds. It reports false if the post was already published,
+// e.g. by a concurrent worker.
+func (r *PostRepository) Publish(id int64) (bool, error) {
+	query := `UPDATE posts SET status = 'published', publish_at = NULL,
+			  created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = ? AND status != 'published'`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// GetUnpublished returns the user's drafts and scheduled posts, the next
+// to be published first and drafts last.
+func (r *PostRepository) GetUnpublished(userID int64) ([]*model.Post, error) {
+	query := `SELECT id, user_id, content, media_url, shared_post_id, share_type, status, publish_at,
+			  created_at, updated_at
+			  FROM posts WHERE user_id = ? AND status != 'published'
+			  ORDER BY publish_at IS NULL, publish_at, updated_at DESC, id DESC`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanPosts(rows)
+}
+
+// GetDueScheduled returns scheduled posts whose publish time has come.
+func (r *PostRepository) GetDueScheduled(limit int) ([]*model.Post, error) {
+	query := `SELECT id, user_id, content, media_url, shared_post_id, share_type, status, publish_at,
+			  created_at, updated_at
+			  FROM posts WHERE status = 'scheduled' AND publish_at <= datetime('now')
+			  ORDER BY publish_at, id LIMIT ?`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanPosts(rows)
+}
+
+// GetRevisions returns the post's earlier versions, oldest first.
+func (r *PostRepository) GetRevisions(postID int64) ([]*model.PostRevision, error) {
+	query := `SELECT id, post_id, content, COALESCE(media_url, ''), created_at, replaced_at
+			  FROM post_revisions WHERE post_id = ? ORDER BY id`
+	rows, err := r.db.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*model.PostRevision
+	for rows.Next() {
+		revision := &model.PostRevision{}
+		err := rows.Scan(&revision.ID, &revision.PostID, &revision.Content, &revision.MediaURL,
+			&revision.CreatedAt, &revision.ReplacedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (r *PostRepository) HasRevisions(postID int64) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM post_revisions WHERE post_id = ?)`
+	var exists bool
+	err := r.db.QueryRow(query, postID).Scan(&exists)
+	return exists, err
+}
+
+// GetReportedRevision returns the version of the post that was current when
+// the report was filed, or nil if the post has not been edited since.
+func (r *PostRepository) GetReportedRevision(postID, reportID int64) (*model.PostRevision, error) {
+	query := `SELECT id, post_id, content, COALESCE(media_url, ''), created_at, replaced_at
+			  FROM post_revisions
+			  WHERE post_id = ? AND replaced_at >= (SELECT created_at FROM reports WHERE id = ?)
+			  ORDER BY id LIMIT 1`
+	revision := &model.PostRevision{}
+	err := r.db.QueryRow(query, postID, reportID).Scan(&revision.ID, &revision.PostID,
+		&revision.Content, &revision.MediaURL, &revision.CreatedAt, &revision.ReplacedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return revision, err
+}
+
+// Delete removes the post along with its revisions, poll, bookmarks and reposts. Quotes
+// of the post stay and show it as unavailable.
+func (r *PostRepository) Delete(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM posts WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrPostNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM post_revisions WHERE post_id = ?`, id); err != nil {
+		return err
+	}
+
+	for _, statement := range []string{
+		`DELETE FROM poll_votes WHERE poll_id IN (SELECT id FROM polls WHERE post_id = ?)`,
+		`DELETE FROM poll_options WHERE poll_id IN (SELECT id FROM polls WHERE post_id = ?)`,
+		`DELETE FROM polls WHERE post_id = ?`,
+		`DELETE FROM bookmarks WHERE post_id = ?`,
+	} {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+
+	// Reposts go with the post, along with anything attached to them.
+	reposts := `SELECT id FROM posts WHERE shared_post_id = ? AND share_type = 'repost'`
+	for _, statement := range []string{
+		`DELETE FROM likes WHERE post_id IN (` + reposts + `)`,
+		`DELETE FROM comments WHERE post_id IN (` + reposts + `)`,
+		`DELETE FROM bookmarks WHERE post_id IN (` + reposts + `)`,
+		`DELETE FROM notification_actors WHERE notification_id IN (SELECT id FROM notifications
+			WHERE type IN ('like', 'comment', 'mention') AND target_id IN (` + reposts + `))`,
+		`DELETE FROM notifications WHERE type IN ('like', 'comment', 'mention') AND target_id IN (` + reposts + `)`,
+		`DELETE FROM posts WHERE shared_post_id = ? AND share_type = 'repost'`,
+	} {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetRepostID returns the ID of the user's repost of the post, or 0 if they
+// have not reposted it.
+func (r *PostRepository) GetRepostID(userID, postID int64) (int64, error) {
+	query := `SELECT id FROM posts WHERE user_id = ? AND shared_post_id = ? AND share_type = 'repost'`
+	var id int64
+	err := r.db.QueryRow(query, userID, postID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// CountShares returns how many times active users reposted and quoted the
+// post.
+func (r *PostRepository) CountShares(postID int64) (int, int, error) {
+	query := `SELECT COALESCE(SUM(p.share_type = 'repost'), 0), COALESCE(SUM(p.share_type = 'quote'), 0)
+			  FROM posts p
+			  INNER JOIN users u ON u.id = p.user_id
+			  WHERE p.shared_post_id = ? AND u.deactivated_at IS NULL`
+	var reposts, quotes int
+	err := r.db.QueryRow(query, postID).Scan(&reposts, &quotes)
+	return reposts, quotes, err
+}
+
+// GetUserPosts returns the user's posts, newest first. With beforeID set,
+// only posts older than that post are returned, so pages can be fetched by
+// passing the last post of the previous page.
+func (r *PostRepository) GetUserPosts(userID, beforeID int64, limit int) ([]*model.Post, error) {
+	query := `SELECT id, user_id, content, media_url, shared_post_id, share_type, status, publish_at,
+			  created_at, updated_at
+			  FROM posts WHERE user_id = ? AND status = 'published'
+			  AND (? = 0 OR (created_at, id) < (SELECT created_at, id FROM posts WHERE id = ?))
+			  ORDER BY created_at DESC, id DESC LIMIT ?`
+	rows, err := r.db.Query(query, userID, beforeID, beforeID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -73,14 +286,28 @@ func (r *PostRepository) GetUserPosts(userID int64, limit int) ([]*model.Post, e
 	return r.scanPosts(rows)
 }
 
+func (r *PostRepository) CountByUser(userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM posts WHERE user_id = ? AND status = 'published'`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+// GetFeed returns the user's own posts, their friends' posts and the public
+// posts of accounts they follow.
 func (r *PostRepository) GetFeed(userID int64, limit int) ([]*model.Post, error) {
-	query := `SELECT DISTINCT p.id, p.user_id, p.content, p.media_url, p.created_at, p.updated_at
+	query := `SELECT p.id, p.user_id, p.content, p.media_url, p.shared_post_id, p.share_type, p.status, p.publish_at,
+			  p.created_at, p.updated_at
 			  FROM posts p
-			  LEFT JOIN friendships f ON (f.requester_id = ? OR f.addressee_id = ?)
-			  WHERE (p.user_id = ? OR p.user_id = f.requester_id OR p.user_id = f.addressee_id)
-			  AND (f.status = 'accepted' OR p.user_id = ?)
-			  ORDER BY p.created_at DESC LIMIT ?`
-	rows, err := r.db.Query(query, userID, userID, userID, userID, limit)
+			  WHERE p.status = 'published' AND p.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+			  AND (p.user_id = ?1
+			    OR (p.user_id IN (SELECT CASE WHEN requester_id = ?1 THEN addressee_id ELSE requester_id END
+			          FROM friendships WHERE (requester_id = ?1 OR addressee_id = ?1) AND status = 'accepted')
+			        AND p.user_id NOT IN (SELECT user_id FROM privacy_settings WHERE posts_visibility = 'only_me'))
+			    OR (p.user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?1 AND status = 'accepted')
+			        AND p.user_id IN (SELECT user_id FROM privacy_settings WHERE posts_visibility = 'everyone')))
+			  ORDER BY p.created_at DESC LIMIT ?2`
+	rows, err := r.db.Query(query, userID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -92,9 +319,7 @@ func (r *PostRepository) GetFeed(userID int64, limit int) ([]*model.Post, error)
 func (r *PostRepository) scanPosts(rows *sql.Rows) ([]*model.Post, error) {
 	var posts []*model.Post
 	for rows.Next() {
-		post := &model.Post{}
-		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.MediaURL,
-			&post.CreatedAt, &post.UpdatedAt)
+		post, err := scanPost(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -102,3 +327,34 @@ func (r *PostRepository) scanPosts(rows *sql.Rows) ([]*model.Post, error) {
 	}
 	return posts, rows.Err()
 }
+
+type postScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPost(scanner postScanner) (*model.Post, error) {
+	post := &model.Post{}
+	var sharedPostID sql.NullInt64
+	var shareType sql.NullString
+	var publishAt sql.NullTime
+	err := scanner.Scan(&post.ID, &post.UserID, &post.Content, &post.MediaURL,
+		&sharedPostID, &shareType, &post.Status, &publishAt, &post.CreatedAt, &post.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	post.SharedPostID = sharedPostID.Int64
+	post.ShareType = model.ShareType(shareType.String)
+	if publishAt.Valid {
+		post.PublishAt = &publishAt.Time
+	}
+	return post, nil
+}
+
+// timestamp formats an optional time for SQLite's datetime(), which turns
+// nil into NULL.
+func timestamp(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"database/sql"
+	"socialnet/internal/model"
+)
+
+type PrivacyRepository struct {
+	db *sql.DB
+}
+
+func NewPrivacyRepository(db *sql.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+// Get returns the user's privacy settings, or the defaults if they never
+// changed them.
+func (r *PrivacyRepository) Get(userID int64) (*model.PrivacySettings, error) {
+	query := `SELECT email_visibility, friends_visibility, posts_visibility, groups_visibility,
+			  location_visibility, website_visibility, birthday_visibility, friend_requests, searchable,
+			  approve_followers, presence_visibility
+			  FROM privacy_settings WHERE user_id = ?`
+	settings := &model.PrivacySettings{}
+	err := r.db.QueryRow(query, userID).Scan(&settings.EmailVisibility, &settings.FriendsVisibility,
+		&settings.PostsVisibility, &settings.GroupsVisibility, &settings.LocationVisibility,
+		&settings.WebsiteVisibility, &settings.BirthdayVisibility, &settings.FriendRequests, &settings.Searchable,
+		&settings.ApproveFollowers, &settings.PresenceVisibility)
+	if err == sql.ErrNoRows {
+		return model.DefaultPrivacySettings(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *PrivacyRepository) Set(userID int64, settings *model.PrivacySettings) error {
+	query := `INSERT INTO privacy_settings (user_id, email_visibility, friends_visibility, posts_visibility,
+			  groups_visibility, location_visibility, website_visibility, birthday_visibility, friend_requests, searchable,
+			  approve_followers, presence_visibility)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			  ON CONFLICT(user_id) DO UPDATE SET email_visibility = excluded.email_visibility,
+			  friends_visibility = excluded.friends_visibility, posts_visibility = excluded.posts_visibility,
+			  groups_visibility = excluded.groups_visibility, location_visibility = excluded.location_visibility,
+			  website_visibility = excluded.website_visibility, birthday_visibility = excluded.birthday_visibility,
+			  friend_requests = excluded.friend_requests, searchable = excluded.searchable,
+			  approve_followers = excluded.approve_followers, presence_visibility = excluded.presence_visibility`
+	_, err := r.db.Exec(query, userID, settings.EmailVisibility, settings.FriendsVisibility,
+		settings.PostsVisibility, settings.GroupsVisibility, settings.LocationVisibility, settings.WebsiteVisibility,
+		settings.BirthdayVisibility, settings.FriendRequests, settings.Searchable, settings.ApproveFollowers,
+		settings.PresenceVisibility)
+	return err
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"socialnet/internal/model"
+	"time"
+)
+
+type SigningKeyRepository struct {
+	db *sql.DB
+}
+
+func NewSigningKeyRepository(db *sql.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// GetValid returns the keys that have not expired, including keys that are
+// not active yet.
+func (r *SigningKeyRepository) GetValid() ([]*model.SigningKey, error) {
+	query := `SELECT kid, algorithm, private_key, created_at, activates_at, expires_at
+			  FROM signing_keys WHERE expires_at IS NULL OR expires_at > datetime('now')
+			  ORDER BY activates_at`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.SigningKey
+	for rows.Next() {
+		key := &model.SigningKey{}
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt,
+			&key.ActivatesAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Rotate adds key, to be used after activationDelay, unless another key was
+// created within interval (e.g. by a second server instance). Keys it
+// replaces stay valid for retention so tokens they signed can still be
+// verified. It reports whether the key was added.
+func (r *SigningKeyRepository) Rotate(key *model.SigningKey, activationDelay, retention, interval time.Duration) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO signing_keys (kid, algorithm, private_key, activates_at)
+			  SELECT ?, ?, ?, datetime('now', ?)
+			  WHERE NOT EXISTS (SELECT 1 FROM signing_keys WHERE created_at > datetime('now', ?))`
+	result, err := tx.Exec(query, key.ID, key.Algorithm, key.PrivateKey,
+		fmt.Sprintf("+%d seconds", int64(activationDelay.Seconds())),
+		fmt.Sprintf("-%d seconds", int64(interval.Seconds())))
+	if err != nil {
+		return false, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
+	}
+
+	query = `UPDATE signing_keys SET expires_at = datetime('now', ?) WHERE kid != ? AND expires_at IS NULL`
+	if _, err := tx.Exec(query, fmt.Sprintf("+%d seconds", int64(retention.Seconds())), key.ID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *SigningKeyRepository) DeleteExpired() error {
+	_, err := r.db.Exec(`DELETE FROM signing_keys WHERE expires_at <= datetime('now')`)
+	return err
+}
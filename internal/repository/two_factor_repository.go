@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+)
+
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// SaveSecret stores a secret awaiting confirmation. Starting setup again
+// replaces a pending secret but never one that is already enabled.
+func (r *TwoFactorRepository) SaveSecret(userID int64, secret string) error {
+	query := `INSERT INTO user_totp (user_id, secret, enabled) VALUES (?, ?, FALSE)
+			  ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, last_used_step = 0,
+			  created_at = CURRENT_TIMESTAMP
+			  WHERE user_totp.enabled = FALSE`
+	_, err := r.db.Exec(query, userID, secret)
+	return err
+}
+
+func (r *TwoFactorRepository) GetSecret(userID int64) (string, bool, error) {
+	query := `SELECT secret, enabled FROM user_totp WHERE user_id = ?`
+	var secret string
+	var enabled bool
+	err := r.db.QueryRow(query, userID).Scan(&secret, &enabled)
+	if err == sql.ErrNoRows {
+		return "", false, errors.New("two-factor authentication not set up")
+	}
+	return secret, enabled, err
+}
+
+func (r *TwoFactorRepository) IsEnabled(userID int64) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM user_totp WHERE user_id = ? AND enabled = TRUE)`
+	var enabled bool
+	err := r.db.QueryRow(query, userID).Scan(&enabled)
+	return enabled, err
+}
+
+func (r *TwoFactorRepository) Enable(userID int64) error {
+	query := `UPDATE user_totp SET enabled = TRUE, enabled_at = CURRENT_TIMESTAMP WHERE user_id = ?`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
+
+// UseStep records the time step of an accepted code. It fails when that step
+// or a later one was already used, so a code cannot be replayed.
+func (r *TwoFactorRepository) UseStep(userID, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?`
+	result, err := r.db.Exec(query, step, userID, step)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("code already used")
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) Delete(userID int64) error {
+	if _, err := r.db.Exec(`DELETE FROM user_totp WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+	return err
+}
+
+// ReplaceRecoveryCodes drops every existing code of the user and stores the
+// given hashes in one transaction.
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(userID int64, codeHash string) error {
+	query := `UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+			  WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("invalid recovery code")
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) CountRecoveryCodes(userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *TwoFactorRepository) IsRequired(userID int64) (bool, error) {
+	query := `SELECT two_factor_required FROM users WHERE id = ?`
+	var required bool
+	err := r.db.QueryRow(query, userID).Scan(&required)
+	if err == sql.ErrNoRows {
+		return false, ErrUserNotFound
+	}
+	return required, err
+}
+
+func (r *TwoFactorRepository) SetRequired(userID int64, required bool) error {
+	query := `UPDATE users SET two_factor_required = ? WHERE id = ?`
+	_, err := r.db.Exec(query, required, userID)
+	return err
+}
@@ -3,9 +3,15 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"socialnet/internal/model"
+	"strings"
+	"time"
 )
 
+// ErrUserNotFound is returned when no account matches the lookup.
+var ErrUserNotFound = errors.New("user not found")
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -26,43 +32,49 @@ func (r *UserRepository) Create(user *model.User) (int64, error) {
 }
 
 func (r *UserRepository) GetByID(id int64) (*model.User, error) {
-	query := `SELECT id, email, username, password_hash, full_name, bio, avatar_url, is_admin, created_at 
+	query := `SELECT id, email, username, password_hash, full_name, bio, avatar_url, is_admin,
+			  email_verified, token_version, created_at
 			  FROM users WHERE id = ?`
 	user := &model.User{}
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID, &user.Email, &user.Username, &user.PasswordHash,
-		&user.FullName, &user.Bio, &user.AvatarURL, &user.IsAdmin, &user.CreatedAt,
+		&user.FullName, &user.Bio, &user.AvatarURL, &user.IsAdmin,
+		&user.EmailVerified, &user.TokenVersion, &user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	return user, err
 }
 
 func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
-	query := `SELECT id, email, username, password_hash, full_name, bio, avatar_url, is_admin, created_at 
+	query := `SELECT id, email, username, password_hash, full_name, bio, avatar_url, is_admin,
+			  email_verified, token_version, created_at
 			  FROM users WHERE email = ?`
 	user := &model.User{}
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID, &user.Email, &user.Username, &user.PasswordHash,
-		&user.FullName, &user.Bio, &user.AvatarURL, &user.IsAdmin, &user.CreatedAt,
+		&user.FullName, &user.Bio, &user.AvatarURL, &user.IsAdmin,
+		&user.EmailVerified, &user.TokenVersion, &user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	return user, err
 }
 
 func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
-	query := `SELECT id, email, username, password_hash, full_name, bio, avatar_url, is_admin, created_at 
-			  FROM users WHERE username = ?`
+	query := `SELECT id, email, username, password_hash, full_name, bio, avatar_url, is_admin,
+			  email_verified, token_version, created_at
+			  FROM users WHERE username = ? COLLATE NOCASE`
 	user := &model.User{}
 	err := r.db.QueryRow(query, username).Scan(
 		&user.ID, &user.Email, &user.Username, &user.PasswordHash,
-		&user.FullName, &user.Bio, &user.AvatarURL, &user.IsAdmin, &user.CreatedAt,
+		&user.FullName, &user.Bio, &user.AvatarURL, &user.IsAdmin,
+		&user.EmailVerified, &user.TokenVersion, &user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	return user, err
 }
@@ -73,9 +85,237 @@ func (r *UserRepository) Update(user *model.User) error {
 	return err
 }
 
+func (r *UserRepository) GetDetails(id int64) (*model.ProfileDetails, error) {
+	query := `SELECT COALESCE(cover_url, ''), COALESCE(location, ''), COALESCE(website, ''), COALESCE(birthday, '')
+			  FROM users WHERE id = ?`
+	details := &model.ProfileDetails{}
+	err := r.db.QueryRow(query, id).Scan(&details.CoverURL, &details.Location, &details.Website, &details.Birthday)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	return details, err
+}
+
+func (r *UserRepository) UpdateDetails(id int64, details *model.ProfileDetails) error {
+	query := `UPDATE users SET cover_url = ?, location = ?, website = ?, birthday = NULLIF(?, '') WHERE id = ?`
+	_, err := r.db.Exec(query, details.CoverURL, details.Location, details.Website, details.Birthday, id)
+	return err
+}
+
+func (r *UserRepository) SetEmailVerified(id int64) error {
+	query := `UPDATE users SET email_verified = TRUE WHERE id = ?`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// UpdatePassword replaces the password hash and bumps the token version,
+// which invalidates every session issued before the change.
+func (r *UserRepository) UpdatePassword(id int64, passwordHash string) error {
+	query := `UPDATE users SET password_hash = ?, password_set = TRUE, token_version = token_version + 1 WHERE id = ?`
+	_, err := r.db.Exec(query, passwordHash, id)
+	return err
+}
+
+// UpdatePasswordHash replaces the stored hash of an unchanged password, e.g.
+// after rehashing at a new cost, without revoking sessions. It only applies
+// while oldHash is still stored and reports whether it did, so a password
+// changed in the meantime is never overwritten.
+func (r *UserRepository) UpdatePasswordHash(id int64, oldHash, passwordHash string) (bool, error) {
+	query := `UPDATE users SET password_hash = ? WHERE id = ? AND password_hash = ?`
+	result, err := r.db.Exec(query, passwordHash, id, oldHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// MarkPasswordUnset flags an account created through an identity provider,
+// whose stored hash belongs to a random password nobody knows.
+func (r *UserRepository) MarkPasswordUnset(id int64) error {
+	query := `UPDATE users SET password_set = FALSE WHERE id = ?`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+func (r *UserRepository) HasPassword(id int64) (bool, error) {
+	query := `SELECT password_set FROM users WHERE id = ?`
+	var set bool
+	err := r.db.QueryRow(query, id).Scan(&set)
+	if err == sql.ErrNoRows {
+		return false, ErrUserNotFound
+	}
+	return set, err
+}
+
+// IsUsernameTaken reports whether a username belongs to an account or is
+// still redirecting to one after a rename. userID's own redirects are
+// ignored so users can switch back to a previous name.
+func (r *UserRepository) IsUsernameTaken(username string, userID int64) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE username = ? COLLATE NOCASE AND id != ?)
+			  OR EXISTS(SELECT 1 FROM username_redirects
+			            WHERE username = ? COLLATE NOCASE AND user_id != ? AND expires_at > datetime('now'))`
+	var taken bool
+	err := r.db.QueryRow(query, username, userID, username, userID).Scan(&taken)
+	return taken, err
+}
+
+// GetByPreviousUsername returns the account a former username still
+// redirects to.
+func (r *UserRepository) GetByPreviousUsername(username string) (*model.User, error) {
+	query := `SELECT user_id FROM username_redirects
+			  WHERE username = ? COLLATE NOCASE AND expires_at > datetime('now')`
+	var userID int64
+	err := r.db.QueryRow(query, username).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(userID)
+}
+
+func (r *UserRepository) GetUsernameChangedAt(id int64) (*time.Time, error) {
+	query := `SELECT username_changed_at FROM users WHERE id = ?`
+	var changedAt sql.NullTime
+	err := r.db.QueryRow(query, id).Scan(&changedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil || !changedAt.Valid {
+		return nil, err
+	}
+	return &changedAt.Time, nil
+}
+
+// ChangeUsername renames the user and keeps the old name redirecting to the
+// account for redirectTTL.
+func (r *UserRepository) ChangeUsername(id int64, oldUsername, newUsername string, redirectTTL time.Duration) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET username = ?, username_changed_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := tx.Exec(query, newUsername, id); err != nil {
+		return err
+	}
+
+	// Taking back a previous name ends its redirect.
+	query = `DELETE FROM username_redirects WHERE username = ? COLLATE NOCASE`
+	if _, err := tx.Exec(query, newUsername); err != nil {
+		return err
+	}
+
+	// Changing only the case keeps the same name, so there is nothing to
+	// redirect.
+	if strings.EqualFold(oldUsername, newUsername) {
+		return tx.Commit()
+	}
+
+	query = `INSERT OR REPLACE INTO username_redirects (username, user_id, expires_at)
+			  VALUES (?, ?, datetime('now', ?))`
+	modifier := fmt.Sprintf("+%d seconds", int64(redirectTTL.Seconds()))
+	if _, err := tx.Exec(query, oldUsername, id, modifier); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateEmail switches to a confirmed new address, which counts as verified.
+func (r *UserRepository) UpdateEmail(id int64, email string) error {
+	query := `UPDATE users SET email = ?, email_verified = TRUE WHERE id = ?`
+	_, err := r.db.Exec(query, email, id)
+	return err
+}
+
+// Deactivate hides the account and signs it out everywhere. A non-zero
+// deletionDelay also schedules the account for deletion.
+func (r *UserRepository) Deactivate(id int64, deletionDelay time.Duration) error {
+	query := `UPDATE users SET deactivated_at = COALESCE(deactivated_at, CURRENT_TIMESTAMP),
+			  deletion_scheduled_at = CASE WHEN ? THEN datetime('now', ?) END,
+			  token_version = token_version + 1
+			  WHERE id = ? AND deleted_at IS NULL`
+	modifier := fmt.Sprintf("+%d seconds", int64(deletionDelay.Seconds()))
+	_, err := r.db.Exec(query, deletionDelay > 0, modifier, id)
+	return err
+}
+
+// Reactivate restores a deactivated account and cancels a pending deletion.
+// It reports whether there was anything to restore.
+func (r *UserRepository) Reactivate(id int64) (bool, error) {
+	query := `UPDATE users SET deactivated_at = NULL, deletion_scheduled_at = NULL
+			  WHERE id = ? AND deactivated_at IS NOT NULL AND deleted_at IS NULL`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *UserRepository) GetDeletionScheduledAt(id int64) (*time.Time, error) {
+	query := `SELECT deletion_scheduled_at FROM users WHERE id = ?`
+	var scheduledAt sql.NullTime
+	err := r.db.QueryRow(query, id).Scan(&scheduledAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil || !scheduledAt.Valid {
+		return nil, err
+	}
+	return &scheduledAt.Time, nil
+}
+
+// IsActive reports whether the account is neither deactivated nor deleted.
+func (r *UserRepository) IsActive(id int64) (bool, error) {
+	query := `SELECT deactivated_at IS NULL FROM users WHERE id = ?`
+	var active bool
+	err := r.db.QueryRow(query, id).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, ErrUserNotFound
+	}
+	return active, err
+}
+
+func (r *UserRepository) UpdateLastSeen(id int64) error {
+	query := `UPDATE users SET last_seen_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+func (r *UserRepository) GetLastSeen(id int64) (*time.Time, error) {
+	query := `SELECT last_seen_at FROM users WHERE id = ?`
+	var lastSeen sql.NullTime
+	err := r.db.QueryRow(query, id).Scan(&lastSeen)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil || !lastSeen.Valid {
+		return nil, err
+	}
+	return &lastSeen.Time, nil
+}
+
+func (r *UserRepository) GetTokenVersion(id int64) (int, error) {
+	query := `SELECT token_version FROM users WHERE id = ?`
+	var version int
+	err := r.db.QueryRow(query, id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	return version, err
+}
+
+// Search leaves out users who opted out of search. Email addresses are not
+// part of the results.
 func (r *UserRepository) Search(searchTerm string, limit int) ([]*model.User, error) {
-	query := `SELECT id, email, username, full_name, bio, avatar_url, is_admin, created_at 
-			  FROM users WHERE username LIKE ? OR full_name LIKE ? LIMIT ?`
+	query := `SELECT id, username, full_name, bio, avatar_url, is_admin, created_at 
+			  FROM users WHERE (username LIKE ? OR full_name LIKE ?) AND deactivated_at IS NULL
+			  AND id NOT IN (SELECT user_id FROM privacy_settings WHERE searchable = FALSE) LIMIT ?`
 	pattern := "%" + searchTerm + "%"
 	rows, err := r.db.Query(query, pattern, pattern, limit)
 	if err != nil {
@@ -86,7 +326,7 @@ func (r *UserRepository) Search(searchTerm string, limit int) ([]*model.User, er
 	var users []*model.User
 	for rows.Next() {
 		user := &model.User{}
-		err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.FullName,
+		err := rows.Scan(&user.ID, &user.Username, &user.FullName,
 			&user.Bio, &user.AvatarURL, &user.IsAdmin, &user.CreatedAt)
 		if err != nil {
 			return nil, err
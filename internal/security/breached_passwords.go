@@ -0,0 +1,146 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedPasswords looks passwords up in a local copy of a breached
+// password corpus such as Have I Been Pwned's Pwned Passwords, keyed by
+// upper-case SHA-1. Two layouts are supported:
+//
+//   - a directory of k-anonymity range files, one per five-character hash
+//     prefix (e.g. 21BD1.txt), each holding SUFFIX:COUNT lines;
+//   - a single file of HASH:COUNT lines sorted by hash, which is binary
+//     searched so it never has to be loaded into memory.
+type BreachedPasswords struct {
+	path  string
+	isDir bool
+}
+
+func NewBreachedPasswords(path string) (*BreachedPasswords, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BreachedPasswords{path: path, isDir: info.IsDir()}, nil
+}
+
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if b.isDir {
+		return b.searchRangeFile(hash[:5], hash[5:])
+	}
+	return b.searchSortedFile(hash)
+}
+
+func (b *BreachedPasswords) searchRangeFile(prefix, suffix string) (bool, error) {
+	f, err := os.Open(filepath.Join(b.path, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		f, err = os.Open(filepath.Join(b.path, prefix))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.EqualFold(hashField(scanner.Text()), suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// searchSortedFile narrows the byte range that could hold the hash by
+// bisection and scans the last few kilobytes line by line. The target is
+// always on a line that starts within [lo, hi).
+func (b *BreachedPasswords) searchSortedFile(hash string) (bool, error) {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	lo, hi := int64(0), info.Size()
+	for hi-lo > 4096 {
+		mid := lo + (hi-lo)/2
+		start, line, err := lineAfter(f, mid, info.Size())
+		if err == io.EOF || start >= hi {
+			hi = mid
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		switch lineHash := strings.ToUpper(hashField(line)); {
+		case lineHash == hash:
+			return true, nil
+		case lineHash < hash:
+			lo = start + int64(len(line))
+		default:
+			hi = start
+		}
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(f, lo, info.Size()-lo))
+	for offset := lo; offset < hi; {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		if strings.EqualFold(hashField(line), hash) {
+			return true, nil
+		}
+		offset += int64(len(line))
+	}
+	return false, nil
+}
+
+// lineAfter returns the first line starting at or after offset, including
+// its newline.
+func lineAfter(f *os.File, offset, size int64) (int64, string, error) {
+	if offset > 0 {
+		reader := bufio.NewReader(io.NewSectionReader(f, offset-1, size-offset+1))
+		skipped, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, "", io.EOF
+		}
+		offset += int64(len(skipped)) - 1
+	}
+	if offset >= size {
+		return 0, "", io.EOF
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(f, offset, size-offset))
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	return offset, line, nil
+}
+
+func hashField(line string) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return hash
+}
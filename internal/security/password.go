@@ -1,17 +1,51 @@
 package security
 
 import (
+	"errors"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
-const bcryptCost = 12
+// PasswordHasher hashes passwords with bcrypt at the configured cost.
+type PasswordHasher struct {
+	cost      int
+	dummyHash []byte
+}
+
+func NewPasswordHasher(cost int) (*PasswordHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, errors.New("bcrypt cost out of range")
+	}
+
+	// The dummy hash is compared against when no account matches a login, so
+	// unknown emails cost the same bcrypt work as real ones.
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), cost)
+	if err != nil {
+		return nil, err
+	}
+	return &PasswordHasher{cost: cost, dummyHash: dummyHash}, nil
+}
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
 	return string(bytes), err
 }
 
-func ComparePassword(hashedPassword, password string) bool {
+func (h *PasswordHasher) Compare(hashedPassword, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err == nil
 }
+
+// CompareDummy spends the same time as Compare and always fails.
+func (h *PasswordHasher) CompareDummy(password string) bool {
+	bcrypt.CompareHashAndPassword(h.dummyHash, []byte(password))
+	return false
+}
+
+// NeedsRehash reports whether a stored hash was made with a lower cost than
+// the current one. Hashes with a higher cost are left alone, so lowering the
+// cost never weakens them.
+func (h *PasswordHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err == nil && cost < h.cost
+}
@@ -0,0 +1,88 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"unicode"
+)
+
+// maxPasswordBytes is bcrypt's input limit; longer passwords cannot be hashed.
+const maxPasswordBytes = 72
+
+// PasswordPolicy is what a new password has to satisfy. MinStrength is a
+// score from 0 to 4 as computed by EstimatePasswordStrength; Breached is
+// optional.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	MinStrength   int
+	Breached      *BreachedPasswords
+}
+
+// Validate checks a new password. userInputs are the account's own details,
+// such as email, username and name, which make a password easier to guess.
+func (p PasswordPolicy) Validate(password string, userInputs ...string) error {
+	if password == "" {
+		return errors.New("password is required")
+	}
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	hasUpper := false
+	hasLower := false
+	hasDigit := false
+	hasSymbol := false
+
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		return errors.New("password must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		return errors.New("password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		return errors.New("password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		return errors.New("password must contain a symbol")
+	}
+
+	if strength := EstimatePasswordStrength(password, userInputs...); strength.Score < p.MinStrength {
+		if strength.Warning == "" {
+			return errors.New("password is too weak: add another word or two")
+		}
+		return errors.New("password is too weak: " + strength.Warning)
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			// An unreadable list must not lock everyone out of signing up.
+			log.Printf("Failed to check breached passwords: %v", err)
+		} else if breached {
+			return errors.New("this password has appeared in a data breach, please choose another one")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,509 @@
+package security
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = rankedDictionary(strings.Fields(commonPasswordList))
+
+const (
+	patternDictionary = "dictionary"
+	patternUserInput  = "user_input"
+	patternSequence   = "sequence"
+	patternRepeat     = "repeat"
+	patternSpatial    = "spatial"
+	patternYear       = "year"
+	patternBruteforce = "bruteforce"
+)
+
+const (
+	bruteforceCardinality           = 10
+	minGuessesBeforeGrowingSequence = 10000
+	maxDictionaryWordLength         = 20
+)
+
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+var leetSubstitutions = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '3': {'e'}, '6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'}, '0': {'o'}, '5': {'s'}, '$': {'s'},
+	'7': {'t'}, '+': {'t'}, '2': {'z'},
+}
+
+// PasswordStrength is an estimate in the manner of zxcvbn: the password is
+// split into the cheapest sequence of known patterns (common passwords,
+// personal details, sequences, repeats, keyboard rows, years and brute force
+// for the rest), and the number of guesses that sequence needs is bucketed
+// into a score from 0 (trivial) to 4 (strong).
+type PasswordStrength struct {
+	Score   int
+	Guesses float64
+	Warning string
+}
+
+type strengthMatch struct {
+	i, j    int
+	pattern string
+	guesses float64
+	rank    int
+}
+
+func EstimatePasswordStrength(password string, userInputs ...string) *PasswordStrength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return &PasswordStrength{Score: 0, Guesses: 1, Warning: "password is empty"}
+	}
+
+	guesses, sequence := mostGuessableSequence(runes, userDictionary(userInputs), make(map[string]float64))
+	strength := &PasswordStrength{Guesses: guesses, Score: scoreGuesses(guesses)}
+	if strength.Score <= 2 {
+		strength.Warning = strengthWarning(sequence)
+	}
+	return strength
+}
+
+func rankedDictionary(words []string) map[string]int {
+	ranks := make(map[string]int, len(words))
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}
+
+// userDictionary ranks the words of the user's own details, such as the
+// email address and name, as the very first guesses.
+func userDictionary(inputs []string) map[string]int {
+	var words []string
+	for _, input := range inputs {
+		input = strings.ToLower(input)
+		words = append(words, input)
+		words = append(words, strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	var kept []string
+	for _, word := range words {
+		if len([]rune(word)) >= 3 {
+			kept = append(kept, word)
+		}
+	}
+	return rankedDictionary(kept)
+}
+
+func scoreGuesses(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	}
+	return 4
+}
+
+// mostGuessableSequence finds the cover of the password by matches that an
+// attacker trying patterns in order of likelihood would need the fewest
+// guesses for. As in zxcvbn, a sequence of l matches costs l! times the
+// product of their guesses, plus a penalty for each additional match.
+// unitGuesses caches the guesses for the groups of repeats across the whole
+// estimate.
+func mostGuessableSequence(runes []rune, userWords map[string]int, unitGuesses map[string]float64) (float64, []*strengthMatch) {
+	n := len(runes)
+	matchesByEnd := make([][]*strengthMatch, n)
+	for _, m := range findMatches(runes, userWords, unitGuesses) {
+		if m.j-m.i+1 < n {
+			m.guesses = math.Max(m.guesses, minSubmatchGuesses(m.j-m.i+1))
+		}
+		matchesByEnd[m.j] = append(matchesByEnd[m.j], m)
+	}
+	for j := 0; j < n; j++ {
+		for i := 0; i <= j; i++ {
+			matchesByEnd[j] = append(matchesByEnd[j], &strengthMatch{
+				i: i, j: j, pattern: patternBruteforce, guesses: bruteforceGuesses(j - i + 1),
+			})
+		}
+	}
+
+	type step struct {
+		product float64
+		match   *strengthMatch
+	}
+	// best[k][l] is the cheapest cover of runes[:k+1] by l matches.
+	best := make([]map[int]*step, n)
+	for k := 0; k < n; k++ {
+		best[k] = make(map[int]*step)
+		for _, m := range matchesByEnd[k] {
+			if m.i == 0 {
+				if current, ok := best[k][1]; !ok || m.guesses < current.product {
+					best[k][1] = &step{product: m.guesses, match: m}
+				}
+				continue
+			}
+			for l, prev := range best[m.i-1] {
+				product := prev.product * m.guesses
+				if current, ok := best[k][l+1]; !ok || product < current.product {
+					best[k][l+1] = &step{product: product, match: m}
+				}
+			}
+		}
+	}
+
+	guesses := math.Inf(1)
+	length := 0
+	for l, s := range best[n-1] {
+		total := factorial(l)*s.product + math.Pow(minGuessesBeforeGrowingSequence, float64(l-1))
+		if total < guesses {
+			guesses = total
+			length = l
+		}
+	}
+
+	sequence := make([]*strengthMatch, 0, length)
+	for k, l := n-1, length; l > 0; l-- {
+		m := best[k][l].match
+		sequence = append(sequence, m)
+		k = m.i - 1
+	}
+	return guesses, sequence
+}
+
+func findMatches(runes []rune, userWords map[string]int, unitGuesses map[string]float64) []*strengthMatch {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var matches []*strengthMatch
+	matches = append(matches, dictionaryMatches(runes, lower, commonPasswords, patternDictionary)...)
+	matches = append(matches, dictionaryMatches(runes, lower, userWords, patternUserInput)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, userWords, unitGuesses)...)
+	matches = append(matches, spatialMatches(lower)...)
+	matches = append(matches, yearMatches(runes)...)
+	return matches
+}
+
+// dictionaryMatches finds words of the dictionary, also when capitalised,
+// reversed or written with look-alike substitutions such as p@ssw0rd.
+func dictionaryMatches(runes, lower []rune, dictionary map[string]int, pattern string) []*strengthMatch {
+	var matches []*strengthMatch
+	for i := 0; i < len(lower); i++ {
+		for j := i + 2; j < len(lower) && j-i < maxDictionaryWordLength; j++ {
+			word := lower[i : j+1]
+			variations := uppercaseVariations(runes[i : j+1])
+
+			best := math.Inf(1)
+			rank := 0
+			if r, ok := dictionary[string(word)]; ok {
+				best, rank = float64(r)*variations, r
+			}
+			if r, ok := dictionary[string(reverseRunes(word))]; ok && float64(r)*variations*2 < best {
+				best, rank = float64(r)*variations*2, r
+			}
+			for _, candidate := range unleet(word) {
+				if r, ok := dictionary[candidate.word]; ok {
+					if g := float64(r) * variations * candidate.variations; g < best {
+						best, rank = g, r
+					}
+				}
+			}
+
+			if rank > 0 {
+				matches = append(matches, &strengthMatch{i: i, j: j, pattern: pattern, guesses: best, rank: rank})
+			}
+		}
+	}
+	return matches
+}
+
+type leetCandidate struct {
+	word       string
+	variations float64
+}
+
+// unleet lists the readings of word with substitutions undone. Each
+// substituted character roughly doubles the guesses.
+func unleet(word []rune) []leetCandidate {
+	candidates := []leetCandidate{{variations: 1}}
+	substituted := false
+	for _, r := range word {
+		options, ok := leetSubstitutions[r]
+		if !ok {
+			for i := range candidates {
+				candidates[i].word += string(r)
+			}
+			continue
+		}
+
+		substituted = true
+		var next []leetCandidate
+		for _, c := range candidates {
+			for _, option := range options {
+				next = append(next, leetCandidate{word: c.word + string(option), variations: c.variations * 2})
+			}
+		}
+		if len(next) > 16 {
+			next = next[:16]
+		}
+		candidates = next
+	}
+
+	if !substituted {
+		return nil
+	}
+	return candidates
+}
+
+func uppercaseVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(word[0]) || unicode.IsUpper(word[len(word)-1]))) {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= upper && i <= lower; i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// sequenceMatches finds runs such as abc, 7531 or zyx with a constant step.
+func sequenceMatches(runes []rune) []*strengthMatch {
+	var matches []*strengthMatch
+	for i := 0; i+2 < len(runes); {
+		delta := runes[i+1] - runes[i]
+		j := i + 1
+		for j+1 < len(runes) && runes[j+1]-runes[j] == delta {
+			j++
+		}
+
+		if j-i >= 2 && delta != 0 && delta >= -5 && delta <= 5 {
+			var base float64
+			switch {
+			case strings.ContainsRune("aAzZ019", runes[i]):
+				base = 4
+			case unicode.IsDigit(runes[i]):
+				base = 10
+			default:
+				base = 26
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			matches = append(matches, &strengthMatch{
+				i: i, j: j, pattern: patternSequence, guesses: base * float64(j-i+1),
+			})
+		}
+
+		if j > i+1 {
+			i = j
+		} else {
+			i++
+		}
+	}
+	return matches
+}
+
+// repeatMatches finds a character or a group repeated back to back, such
+// as aaa or abcabc. It costs the guesses for the group times the repeats.
+// As in zxcvbn, only the longest repeat starting at each position is kept,
+// made of the shortest group that covers it.
+func repeatMatches(runes []rune, userWords map[string]int, unitGuesses map[string]float64) []*strengthMatch {
+	var matches []*strengthMatch
+	for i := 0; i < len(runes); i++ {
+		bestUnit, bestCount := 0, 0
+		for unit := 1; i+2*unit <= len(runes); unit++ {
+			count := 1
+			for i+(count+1)*unit <= len(runes) &&
+				string(runes[i+count*unit:i+(count+1)*unit]) == string(runes[i:i+unit]) {
+				count++
+			}
+			if count < 2 || (unit == 1 && count < 3) {
+				continue
+			}
+			if count*unit > bestCount*bestUnit {
+				bestUnit, bestCount = unit, count
+			}
+		}
+		if bestUnit == 0 {
+			continue
+		}
+
+		group := string(runes[i : i+bestUnit])
+		guesses, ok := unitGuesses[group]
+		if !ok {
+			if bestUnit == 1 {
+				guesses = float64(cardinality(runes[i]))
+			} else {
+				guesses, _ = mostGuessableSequence(runes[i:i+bestUnit], userWords, unitGuesses)
+			}
+			unitGuesses[group] = guesses
+		}
+		matches = append(matches, &strengthMatch{
+			i: i, j: i + bestCount*bestUnit - 1, pattern: patternRepeat, guesses: guesses * float64(bestCount),
+		})
+	}
+	return matches
+}
+
+// spatialMatches finds straight runs of four or more keys along a keyboard
+// row, in either direction.
+func spatialMatches(lower []rune) []*strengthMatch {
+	const startingPositions, averageDegree = 94, 4.6
+
+	var matches []*strengthMatch
+	for i := 0; i < len(lower); i++ {
+		longest := 0
+		for j := i + 3; j < len(lower); j++ {
+			run := string(lower[i : j+1])
+			reversed := string(reverseRunes(lower[i : j+1]))
+			for _, row := range keyboardRows {
+				if strings.Contains(row, run) || strings.Contains(row, reversed) {
+					longest = j
+					break
+				}
+			}
+		}
+		if longest > 0 {
+			length := longest - i + 1
+			matches = append(matches, &strengthMatch{
+				i: i, j: longest, pattern: patternSpatial,
+				guesses: startingPositions * averageDegree * float64(length-1),
+			})
+		}
+	}
+	return matches
+}
+
+// yearMatches finds years from 1900 to 2099. Years close to the current one
+// are among the first guesses.
+func yearMatches(runes []rune) []*strengthMatch {
+	currentYear := time.Now().Year()
+
+	var matches []*strengthMatch
+	for i := 0; i+4 <= len(runes); i++ {
+		year := 0
+		valid := true
+		for _, r := range runes[i : i+4] {
+			if r < '0' || r > '9' {
+				valid = false
+				break
+			}
+			year = year*10 + int(r-'0')
+		}
+		if !valid || year < 1900 || year > 2099 {
+			continue
+		}
+
+		distance := math.Abs(float64(year - currentYear))
+		matches = append(matches, &strengthMatch{
+			i: i, j: i + 3, pattern: patternYear, guesses: math.Max(distance, 20),
+		})
+	}
+	return matches
+}
+
+func strengthWarning(sequence []*strengthMatch) string {
+	var longest *strengthMatch
+	for _, m := range sequence {
+		if m.pattern == patternBruteforce {
+			continue
+		}
+		if longest == nil || m.j-m.i > longest.j-longest.i {
+			longest = m
+		}
+	}
+	if longest == nil {
+		return "use a longer password; a few unrelated words are easy to remember and hard to guess"
+	}
+
+	switch longest.pattern {
+	case patternDictionary:
+		if len(sequence) == 1 {
+			return "this is a commonly used password"
+		}
+		return "common passwords are easy to guess, even with capitals or symbols swapped in"
+	case patternUserInput:
+		return "avoid your name, username or email address"
+	case patternSequence:
+		return "sequences like abc or 6543 are easy to guess"
+	case patternRepeat:
+		return "repeats like aaa or abcabc are easy to guess"
+	case patternSpatial:
+		return "straight rows of keys are easy to guess"
+	case patternYear:
+		return "years are easy to guess"
+	}
+	return ""
+}
+
+func minSubmatchGuesses(length int) float64 {
+	if length == 1 {
+		return 10
+	}
+	return 50
+}
+
+func bruteforceGuesses(length int) float64 {
+	guesses := math.Pow(bruteforceCardinality, float64(length))
+	return math.Max(guesses, minSubmatchGuesses(length)+1)
+}
+
+func cardinality(r rune) int {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLetter(r):
+		return 26
+	}
+	return 33
+}
+
+func reverseRunes(runes []rune) []rune {
+	reversed := make([]rune, len(runes))
+	for i, r := range runes {
+		reversed[len(runes)-1-i] = r
+	}
+	return reversed
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+	return result
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
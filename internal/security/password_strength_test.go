@@ -0,0 +1,38 @@
+package security
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Repeats used to be estimated once per start position and group length,
+// recursively, which took seconds for a password at the length limit.
+func TestEstimatePasswordStrengthRepeatRuntime(t *testing.T) {
+	const limit = 250 * time.Millisecond
+
+	policy := PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, MinStrength: 2}
+	passwords := []string{
+		strings.Repeat("a", maxPasswordBytes),
+		"A1" + strings.Repeat("a", maxPasswordBytes-2),
+		strings.Repeat("ab", maxPasswordBytes/2),
+		strings.Repeat("Ab1", maxPasswordBytes/3),
+		strings.Repeat("aab", maxPasswordBytes/3),
+	}
+
+	for _, password := range passwords {
+		start := time.Now()
+		EstimatePasswordStrength(password, "user@example.com", "username")
+		if elapsed := time.Since(start); elapsed > limit {
+			t.Errorf("EstimatePasswordStrength(%q) took %v, want at most %v", password, elapsed, limit)
+		}
+
+		start = time.Now()
+		if err := policy.Validate(password, "user@example.com", "username"); err == nil {
+			t.Errorf("Validate(%q) accepted a repeated password", password)
+		}
+		if elapsed := time.Since(start); elapsed > limit {
+			t.Errorf("Validate(%q) took %v, want at most %v", password, elapsed, limit)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// GenerateRandomToken returns a URL-safe token to hand to the user and the
+// hash to store in its place.
+func GenerateRandomToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// AccessTokenPrefix marks personal access tokens so they can be told apart
+// from JWTs and recognised by secret scanners.
+const AccessTokenPrefix = "snp_"
+
+// GenerateAccessToken returns a new personal access token and its hash.
+func GenerateAccessToken() (string, string, error) {
+	token, _, err := GenerateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = AccessTokenPrefix + token
+	return token, HashToken(token), nil
+}
+
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateRecoveryCode returns a one-time code in the form xxxxx-xxxxx that
+// is easy to copy down by hand.
+func GenerateRecoveryCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
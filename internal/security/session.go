@@ -2,35 +2,56 @@ package security
 
 import (
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type Claims struct {
-	UserID  int64 `json:"user_id"`
-	IsAdmin bool  `json:"is_admin"`
+	UserID       int64 `json:"user_id"`
+	IsAdmin      bool  `json:"is_admin"`
+	TokenVersion int   `json:"ver"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID int64, isAdmin bool, secret string, duration time.Duration) (string, error) {
+func GenerateToken(userID int64, isAdmin bool, tokenVersion int, keys *KeySet, duration time.Duration) (string, error) {
+	key, err := keys.Current()
+	if err != nil {
+		return "", err
+	}
+
 	claims := &Claims{
-		UserID:  userID,
-		IsAdmin: isAdmin,
+		UserID:       userID,
+		IsAdmin:      isAdmin,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    keys.issuer,
+			Subject:   strconv.FormatInt(userID, 10),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
 }
 
-func ValidateToken(tokenString, secret string) (*Claims, error) {
+// ValidateToken verifies a session token against the key named by its kid
+// header. The key's own algorithm is enforced, whatever the header claims.
+func ValidateToken(tokenString string, keys *KeySet) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.Key(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.PrivateKey.Public(), nil
+	}, jwt.WithValidMethods([]string{AlgorithmEdDSA, AlgorithmRS256}), jwt.WithIssuer(keys.issuer))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,200 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+// SigningKey is one of the keys session tokens are signed with. A key is
+// published from the moment it is loaded but only signs tokens once
+// ActivatesAt has passed, so verifiers can fetch it ahead of its first use.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  crypto.Signer
+	ActivatesAt time.Time
+}
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+func IsValidSigningAlgorithm(algorithm string) bool {
+	return algorithm == AlgorithmEdDSA || algorithm == AlgorithmRS256
+}
+
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, errors.New("unsupported signing algorithm: " + algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:         base64.RawURLEncoding.EncodeToString(b),
+		Algorithm:  algorithm,
+		PrivateKey: privateKey,
+	}, nil
+}
+
+// MarshalPrivateKey encodes the private key as PKCS #8 PEM for storage.
+func (k *SigningKey) MarshalPrivateKey() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func ParseSigningKey(id, algorithm, privateKeyPEM string, activatesAt time.Time) (*SigningKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("invalid signing key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var privateKey crypto.Signer
+	switch key := parsed.(type) {
+	case ed25519.PrivateKey:
+		if algorithm != AlgorithmEdDSA {
+			return nil, errors.New("signing key does not match its algorithm")
+		}
+		privateKey = key
+	case *rsa.PrivateKey:
+		if algorithm != AlgorithmRS256 {
+			return nil, errors.New("signing key does not match its algorithm")
+		}
+		privateKey = key
+	default:
+		return nil, errors.New("unsupported signing key type")
+	}
+
+	return &SigningKey{ID: id, Algorithm: algorithm, PrivateKey: privateKey, ActivatesAt: activatesAt}, nil
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+func (k *SigningKey) PublicJWK() JSONWebKey {
+	jwk := JSONWebKey{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch pub := k.PrivateKey.Public().(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	}
+	return jwk
+}
+
+// KeySet holds the keys session tokens are signed and verified with. It is
+// refreshed from storage as keys are rotated.
+type KeySet struct {
+	issuer string
+
+	mu   sync.RWMutex
+	keys map[string]*SigningKey
+}
+
+func NewKeySet(issuer string) *KeySet {
+	return &KeySet{issuer: issuer, keys: make(map[string]*SigningKey)}
+}
+
+func (s *KeySet) Replace(keys []*SigningKey) {
+	byID := make(map[string]*SigningKey, len(keys))
+	for _, key := range keys {
+		byID[key.ID] = key
+	}
+
+	s.mu.Lock()
+	s.keys = byID
+	s.mu.Unlock()
+}
+
+// Current returns the most recently activated key.
+func (s *KeySet) Current() (*SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var current *SigningKey
+	for _, key := range s.keys {
+		if key.ActivatesAt.After(now) {
+			continue
+		}
+		if current == nil || key.ActivatesAt.After(current.ActivatesAt) {
+			current = key
+		}
+	}
+	if current == nil {
+		return nil, errors.New("no active signing key")
+	}
+	return current, nil
+}
+
+func (s *KeySet) Key(id string) (*SigningKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	return key, ok
+}
+
+func (s *KeySet) JWKS() *JSONWebKeySet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := &JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(s.keys))}
+	for _, key := range s.keys {
+		set.Keys = append(set.Keys, key.PublicJWK())
+	}
+	return set
+}
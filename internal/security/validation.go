@@ -2,13 +2,26 @@ package security
 
 import (
 	"errors"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 	"unicode"
 )
 
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
+// reservedUsernames could be mistaken for the site itself or its staff, or
+// collide with the routes under /users/. Matching ignores case.
+var reservedUsernames = map[string]bool{
+	"about": true, "account": true, "admin": true, "administrator": true, "api": true,
+	"follow": true, "followers": true, "following": true, "friends": true, "groups": true,
+	"help": true, "login": true, "logout": true, "me": true, "moderator": true,
+	"notifications": true, "null": true, "posts": true, "register": true, "root": true,
+	"search": true, "security": true, "settings": true, "socialnet": true, "staff": true,
+	"support": true, "system": true, "undefined": true,
+}
+
 func ValidateEmail(email string) error {
 	if email == "" {
 		return errors.New("email is required")
@@ -32,41 +45,17 @@ func ValidateUsername(username string) error {
 	if !isAlphanumeric(username) {
 		return errors.New("username must contain only letters, numbers, and underscores")
 	}
+	if IsReservedUsername(username) {
+		return errors.New("this username is reserved")
+	}
 	return nil
 }
 
-func ValidatePassword(password string) error {
-	if password == "" {
-		return errors.New("password is required")
-	}
-	if len(password) < 6 {
-		return errors.New("password must be at least 6 characters")
-	}
-	if len(password) > 100 {
-		return errors.New("password must be at most 100 characters")
-	}
-
-	hasUpper := false
-	hasLower := false
-	hasDigit := false
-
-	for _, char := range password {
-		if unicode.IsUpper(char) {
-			hasUpper = true
-		}
-		if unicode.IsLower(char) {
-			hasLower = true
-		}
-		if unicode.IsDigit(char) {
-			hasDigit = true
-		}
-	}
-
-	if !hasUpper || !hasLower || !hasDigit {
-		return errors.New("password must contain uppercase, lowercase, and digit")
-	}
-
-	return nil
+// IsReservedUsername reports whether a username is kept from users. Names
+// starting with deleted_ are given to erased accounts.
+func IsReservedUsername(username string) bool {
+	username = strings.ToLower(username)
+	return reservedUsernames[username] || strings.HasPrefix(username, "deleted_")
 }
 
 func ValidateContent(content string, maxLength int) error {
@@ -80,6 +69,30 @@ func ValidateContent(content string, maxLength int) error {
 	return nil
 }
 
+// ValidateWebsite accepts absolute http and https URLs.
+func ValidateWebsite(website string) error {
+	u, err := url.Parse(website)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("website must be an http or https URL")
+	}
+	if len(website) > 200 {
+		return errors.New("website must be at most 200 characters")
+	}
+	return nil
+}
+
+// ValidateBirthday accepts a YYYY-MM-DD date that is not in the future.
+func ValidateBirthday(birthday string) error {
+	date, err := time.Parse("2006-01-02", birthday)
+	if err != nil {
+		return errors.New("birthday must be a date in YYYY-MM-DD format")
+	}
+	if date.After(time.Now()) || date.Year() < 1900 {
+		return errors.New("birthday is not a valid date of birth")
+	}
+	return nil
+}
+
 func isAlphanumeric(s string) bool {
 	for _, char := range s {
 		if !unicode.IsLetter(char) && !unicode.IsDigit(char) && char != '_' {
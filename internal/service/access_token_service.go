@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"socialnet/internal/security"
+	"strings"
+	"time"
+)
+
+const (
+	maxAccessTokens          = 50
+	maxAccessTokenExpiryDays = 365
+)
+
+type AccessTokenService struct {
+	accessTokenRepo *repository.AccessTokenRepository
+}
+
+func NewAccessTokenService(accessTokenRepo *repository.AccessTokenRepository) *AccessTokenService {
+	return &AccessTokenService{accessTokenRepo: accessTokenRepo}
+}
+
+func (s *AccessTokenService) Create(userID int64, req *model.PersonalAccessTokenCreate) (*model.PersonalAccessTokenCreated, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("name must be between 1 and 100 characters")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	seen := make(map[model.TokenScope]bool)
+	var scopes []model.TokenScope
+	for _, scope := range req.Scopes {
+		if !model.IsValidScope(scope) {
+			return nil, errors.New("invalid scope: " + string(scope))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAccessTokenExpiryDays {
+		return nil, errors.New("expires_in_days must be between 0 (never) and 365")
+	}
+
+	count, err := s.accessTokenRepo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAccessTokens {
+		return nil, errors.New("too many access tokens, revoke one first")
+	}
+
+	plain, hash, err := security.GenerateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &model.PersonalAccessToken{
+		UserID: userID,
+		Name:   name,
+		Prefix: plain[:len(security.AccessTokenPrefix)+4],
+		Scopes: scopes,
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	id, err := s.accessTokenRepo.Create(token, hash, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err = s.accessTokenRepo.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PersonalAccessTokenCreated{Token: plain, PersonalAccessToken: token}, nil
+}
+
+func (s *AccessTokenService) List(userID int64) ([]*model.PersonalAccessToken, error) {
+	return s.accessTokenRepo.GetByUser(userID)
+}
+
+func (s *AccessTokenService) Revoke(userID, tokenID int64) error {
+	return s.accessTokenRepo.Delete(tokenID, userID)
+}
+
+// Authenticate resolves a plain token presented by a client and records its
+// use.
+func (s *AccessTokenService) Authenticate(plain string) (*model.PersonalAccessToken, error) {
+	token, err := s.accessTokenRepo.GetByHash(security.HashToken(plain))
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+	if err := s.accessTokenRepo.TouchLastUsed(token.ID); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"socialnet/internal/security"
+	"time"
+)
+
+const (
+	// exportRequestInterval limits how often a user can ask for a data export.
+	exportRequestInterval = 24 * time.Hour
+	exportBatchSize       = 5
+	deletionBatchSize     = 20
+)
+
+type AccountService struct {
+	accountRepo    *repository.AccountRepository
+	exportRepo     *repository.DataExportRepository
+	userRepo       *repository.UserRepository
+	postRepo       *repository.PostRepository
+	messageRepo    *repository.MessageRepository
+	groupRepo      *repository.GroupRepository
+	emailService   *EmailService
+	passwordHasher *security.PasswordHasher
+	deletionGrace  time.Duration
+	exportDir      string
+	exportTTL      time.Duration
+}
+
+func NewAccountService(accountRepo *repository.AccountRepository, exportRepo *repository.DataExportRepository,
+	userRepo *repository.UserRepository, postRepo *repository.PostRepository,
+	messageRepo *repository.MessageRepository, groupRepo *repository.GroupRepository,
+	emailService *EmailService, passwordHasher *security.PasswordHasher,
+	deletionGrace time.Duration, exportDir string, exportTTL time.Duration) *AccountService {
+	return &AccountService{
+		accountRepo:    accountRepo,
+		exportRepo:     exportRepo,
+		userRepo:       userRepo,
+		postRepo:       postRepo,
+		messageRepo:    messageRepo,
+		groupRepo:      groupRepo,
+		emailService:   emailService,
+		passwordHasher: passwordHasher,
+		deletionGrace:  deletionGrace,
+		exportDir:      exportDir,
+		exportTTL:      exportTTL,
+	}
+}
+
+// Deactivate hides the account until its owner logs in again.
+func (s *AccountService) Deactivate(userID int64, confirmation *model.AccountConfirmation) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkCurrentPassword(s.userRepo, s.passwordHasher, user, confirmation.Password); err != nil {
+		return err
+	}
+	return s.userRepo.Deactivate(userID, 0)
+}
+
+// ScheduleDeletion deactivates the account and erases it once the grace
+// period is over. Logging in before then cancels the deletion.
+func (s *AccountService) ScheduleDeletion(userID int64, confirmation *model.AccountConfirmation) (*model.AccountDeletion, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCurrentPassword(s.userRepo, s.passwordHasher, user, confirmation.Password); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Deactivate(userID, s.deletionGrace); err != nil {
+		return nil, err
+	}
+	scheduledAt, err := s.userRepo.GetDeletionScheduledAt(userID)
+	if err != nil {
+		return nil, err
+	}
+	if scheduledAt == nil {
+		return nil, errors.New("account could not be scheduled for deletion")
+	}
+
+	s.emailService.SendAccountChangeNotice(user, user.Email,
+		"Your SocialNet account will be deleted permanently on "+scheduledAt.UTC().Format("Jan 2, 2006 15:04 MST")+
+			". Until then it is hidden from other users. Log in before that date to keep your account.")
+
+	return &model.AccountDeletion{DeletionScheduledAt: *scheduledAt}, nil
+}
+
+// PurgeDueAccounts erases every account whose grace period has ended.
+func (s *AccountService) PurgeDueAccounts() error {
+	ids, err := s.accountRepo.GetDueDeletions(deletionBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		exports, err := s.exportRepo.GetByUser(id)
+		if err != nil {
+			return err
+		}
+		for _, export := range exports {
+			s.removeExportFile(export)
+		}
+
+		if err := s.accountRepo.Purge(id); err != nil {
+			log.Printf("Failed to delete user %d: %v", id, err)
+			continue
+		}
+		log.Printf("Deleted user %d", id)
+	}
+	return nil
+}
+
+// RequestExport queues a data export, which the export worker assembles in
+// the background.
+func (s *AccountService) RequestExport(userID int64) (*model.DataExport, error) {
+	recent, err := s.exportRepo.RequestedWithin(userID, exportRequestInterval)
+	if err != nil {
+		return nil, err
+	}
+	if recent {
+		return nil, &ThrottledError{Message: "an export was already requested in the last 24 hours", RetryAfter: exportRequestInterval}
+	}
+
+	id, err := s.exportRepo.Create(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.exportRepo.GetByID(id, userID)
+}
+
+func (s *AccountService) GetExports(userID int64) ([]*model.DataExport, error) {
+	return s.exportRepo.GetByUser(userID)
+}
+
+// GetExportFile returns the archive of a finished export.
+func (s *AccountService) GetExportFile(id, userID int64) (*model.DataExport, error) {
+	export, err := s.exportRepo.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != model.ExportReady {
+		return nil, errors.New("export is not ready")
+	}
+	return export, nil
+}
+
+// ProcessExports builds queued exports and removes expired archives.
+func (s *AccountService) ProcessExports() error {
+	expired, err := s.exportRepo.GetExpired()
+	if err != nil {
+		return err
+	}
+	for _, export := range expired {
+		s.removeExportFile(export)
+		if err := s.exportRepo.Delete(export.ID); err != nil {
+			return err
+		}
+	}
+
+	pending, err := s.exportRepo.GetPending(exportBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, export := range pending {
+		path, size, err := s.writeExport(export)
+		if err != nil {
+			log.Printf("Failed to build export %d: %v", export.ID, err)
+			if err := s.exportRepo.MarkFailed(export.ID, "the export could not be created", s.exportTTL); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.exportRepo.MarkReady(export.ID, path, size, s.exportTTL); err != nil {
+			return err
+		}
+
+		user, err := s.userRepo.GetByID(export.UserID)
+		if err != nil {
+			return err
+		}
+		s.emailService.SendDataExportEmail(user, s.exportTTL)
+	}
+	return nil
+}
+
+// writeExport assembles the ZIP archive: one JSON file per kind of data.
+// Posts only store media URLs, so media.json lists them rather than
+// bundling the files.
+func (s *AccountService) writeExport(export *model.DataExport) (string, int64, error) {
+	files, err := s.collectExportData(export.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := os.MkdirAll(s.exportDir, 0o700); err != nil {
+		return "", 0, err
+	}
+	tmp, err := os.CreateTemp(s.exportDir, "export-*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive := zip.NewWriter(tmp)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return "", 0, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return "", 0, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	path := filepath.Join(s.exportDir, fmt.Sprintf("socialnet-export-%d-%d.zip", export.UserID, export.ID))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+type exportFile struct {
+	name string
+	data interface{}
+}
+
+func (s *AccountService) collectExportData(userID int64) ([]exportFile, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.postRepo.GetUserPosts(userID, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	drafts, err := s.postRepo.GetUnpublished(userID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.accountRepo.GetCommentsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	likes, err := s.accountRepo.GetLikesByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	friendships, err := s.accountRepo.GetFriendshipsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	follows, err := s.accountRepo.GetFollowsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	conversations, err := s.exportConversations(userID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groupRepo.GetUserGroups(userID)
+	if err != nil {
+		return nil, err
+	}
+	groupPosts, err := s.accountRepo.GetGroupPostsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var media []*model.ExportedMedia
+	if user.AvatarURL != "" {
+		media = append(media, &model.ExportedMedia{Source: "avatar", URL: user.AvatarURL})
+	}
+	for _, post := range append(posts, drafts...) {
+		if post.MediaURL != "" {
+			media = append(media, &model.ExportedMedia{Source: fmt.Sprintf("post %d", post.ID), URL: post.MediaURL})
+		}
+	}
+
+	return []exportFile{
+		{"profile.json", user},
+		{"posts.json", posts},
+		{"drafts.json", drafts},
+		{"comments.json", comments},
+		{"likes.json", likes},
+		{"friendships.json", friendships},
+		{"follows.json", follows},
+		{"messages.json", conversations},
+		{"groups.json", groups},
+		{"group_posts.json", groupPosts},
+		{"media.json", media},
+	}, nil
+}
+
+// exportConversations includes every message in the user's conversations,
+// sent and received, with participants named by username only.
+func (s *AccountService) exportConversations(userID int64) ([]*model.ExportedConversation, error) {
+	ids, err := s.accountRepo.GetConversationIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usernames := make(map[int64]string)
+	username := func(id int64) string {
+		if _, ok := usernames[id]; !ok {
+			if user, err := s.userRepo.GetByID(id); err == nil {
+				usernames[id] = user.Username
+			}
+		}
+		return usernames[id]
+	}
+
+	conversations := make([]*model.ExportedConversation, 0, len(ids))
+	for _, id := range ids {
+		memberIDs, err := s.messageRepo.GetMemberIDs(id)
+		if err != nil {
+			return nil, err
+		}
+		messages, err := s.messageRepo.GetMessages(id, -1)
+		if err != nil {
+			return nil, err
+		}
+
+		conversation := &model.ExportedConversation{ID: id, Messages: []*model.ExportedMessage{}}
+		for _, memberID := range memberIDs {
+			conversation.Participants = append(conversation.Participants, username(memberID))
+		}
+		for _, message := range messages {
+			conversation.Messages = append(conversation.Messages, &model.ExportedMessage{
+				From:      username(message.UserID),
+				Body:      message.Body,
+				CreatedAt: message.CreatedAt,
+				ReadAt:    message.ReadAt,
+			})
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+func (s *AccountService) removeExportFile(export *model.DataExport) {
+	if export.FilePath == "" {
+		return
+	}
+	if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to remove export file %s: %v", export.FilePath, err)
+	}
+}
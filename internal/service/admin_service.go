@@ -10,16 +10,21 @@ type AdminService struct {
 	reportRepo  *repository.ReportRepository
 	postRepo    *repository.PostRepository
 	commentRepo *repository.CommentRepository
-	userRepo    *repository.UserRepository
+	userRepo      *repository.UserRepository
+	twoFactorRepo  *repository.TwoFactorRepository
+	lockoutService *LockoutService
 }
 
 func NewAdminService(reportRepo *repository.ReportRepository, postRepo *repository.PostRepository,
-	commentRepo *repository.CommentRepository, userRepo *repository.UserRepository) *AdminService {
+	commentRepo *repository.CommentRepository, userRepo *repository.UserRepository,
+	twoFactorRepo *repository.TwoFactorRepository, lockoutService *LockoutService) *AdminService {
 	return &AdminService{
-		reportRepo:  reportRepo,
-		postRepo:    postRepo,
-		commentRepo: commentRepo,
-		userRepo:    userRepo,
+		reportRepo:     reportRepo,
+		postRepo:       postRepo,
+		commentRepo:    commentRepo,
+		userRepo:       userRepo,
+		twoFactorRepo:  twoFactorRepo,
+		lockoutService: lockoutService,
 	}
 }
 
@@ -45,11 +50,33 @@ func (s *AdminService) GetReports(status model.ReportStatus) ([]*model.Report, e
 	for _, report := range reports {
 		reporter, _ := s.userRepo.GetByID(report.ReporterID)
 		report.Reporter = reporter
+
+		if report.TargetType == model.ReportTargetPost {
+			s.showReportedContent(report)
+		}
 	}
 
 	return reports, nil
 }
 
+// showReportedContent fills in the reported post as it was when the report
+// was filed, so an edit cannot hide what was reported.
+func (s *AdminService) showReportedContent(report *model.Report) {
+	revision, err := s.postRepo.GetReportedRevision(report.TargetID, report.ID)
+	if err != nil {
+		return
+	}
+	if revision != nil {
+		report.ReportedContent = revision.Content
+		report.EditedSinceReport = true
+		return
+	}
+
+	if post, err := s.postRepo.GetByID(report.TargetID); err == nil {
+		report.ReportedContent = post.Content
+	}
+}
+
 func (s *AdminService) ReviewReport(reportID int64, status model.ReportStatus) error {
 	if status != model.ReportStatusReviewed && status != model.ReportStatusResolved {
 		return errors.New("invalid status")
@@ -68,3 +95,25 @@ func (s *AdminService) DeleteContent(targetType model.ReportTargetType, targetID
 		return errors.New("unsupported target type")
 	}
 }
+
+// SetTwoFactorRequired makes two-factor authentication mandatory for an admin
+// account. Admins who have not enrolled yet must do so at their next login.
+func (s *AdminService) SetTwoFactorRequired(userID int64, required bool) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return errors.New("two-factor authentication can only be required for admins")
+	}
+
+	return s.twoFactorRepo.SetRequired(userID, required)
+}
+
+func (s *AdminService) GetLockouts() ([]*model.Lockout, error) {
+	return s.lockoutService.GetLockouts()
+}
+
+func (s *AdminService) ClearLockout(id int64) error {
+	return s.lockoutService.ClearLockout(id)
+}
@@ -2,17 +2,49 @@ package service
 
 import (
 	"errors"
+	"log"
 	"socialnet/internal/model"
 	"socialnet/internal/repository"
 	"socialnet/internal/security"
+	"strings"
+	"time"
 )
 
+// resendInterval throttles how often a verification or reset email can be
+// requested for the same account.
+const resendInterval = time.Minute
+
 type AuthService struct {
-	userRepo *repository.UserRepository
+	userRepo         *repository.UserRepository
+	tokenRepo        *repository.AuthTokenRepository
+	accessTokenRepo  *repository.AccessTokenRepository
+	emailService     *EmailService
+	twoFactorService *TwoFactorService
+	lockoutService   *LockoutService
+	passwordPolicy   security.PasswordPolicy
+	passwordHasher   *security.PasswordHasher
+	verificationTTL  time.Duration
+	resetTTL         time.Duration
+	emailChangeTTL   time.Duration
 }
 
-func NewAuthService(userRepo *repository.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+func NewAuthService(userRepo *repository.UserRepository, tokenRepo *repository.AuthTokenRepository,
+	accessTokenRepo *repository.AccessTokenRepository, emailService *EmailService, twoFactorService *TwoFactorService, lockoutService *LockoutService,
+	passwordPolicy security.PasswordPolicy, passwordHasher *security.PasswordHasher,
+	verificationTTL, resetTTL, emailChangeTTL time.Duration) *AuthService {
+	return &AuthService{
+		userRepo:         userRepo,
+		tokenRepo:        tokenRepo,
+		accessTokenRepo:  accessTokenRepo,
+		emailService:     emailService,
+		twoFactorService: twoFactorService,
+		lockoutService:   lockoutService,
+		passwordPolicy:   passwordPolicy,
+		passwordHasher:   passwordHasher,
+		verificationTTL:  verificationTTL,
+		resetTTL:         resetTTL,
+		emailChangeTTL:   emailChangeTTL,
+	}
 }
 
 func (s *AuthService) Register(reg *model.UserRegistration) (*model.User, error) {
@@ -22,7 +54,7 @@ func (s *AuthService) Register(reg *model.UserRegistration) (*model.User, error)
 	if err := security.ValidateUsername(reg.Username); err != nil {
 		return nil, err
 	}
-	if err := security.ValidatePassword(reg.Password); err != nil {
+	if err := s.passwordPolicy.Validate(reg.Password, reg.Email, reg.Username, reg.FullName); err != nil {
 		return nil, err
 	}
 
@@ -30,11 +62,15 @@ func (s *AuthService) Register(reg *model.UserRegistration) (*model.User, error)
 		return nil, errors.New("email already exists")
 	}
 
-	if _, err := s.userRepo.GetByUsername(reg.Username); err == nil {
+	taken, err := s.userRepo.IsUsernameTaken(reg.Username, 0)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
 		return nil, errors.New("username already exists")
 	}
 
-	hashedPassword, err := security.HashPassword(reg.Password)
+	hashedPassword, err := s.passwordHasher.Hash(reg.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -52,22 +88,341 @@ func (s *AuthService) Register(reg *model.UserRegistration) (*model.User, error)
 	}
 
 	user.ID = id
+	s.sendVerification(user)
+
 	return user, nil
 }
 
-func (s *AuthService) Login(login *model.UserLogin) (*model.User, error) {
+// Login checks the password. When the account uses two-factor
+// authentication a challenge is returned instead of a user, and the login is
+// completed through the TwoFactorService. Unknown emails go through the same
+// bcrypt comparison and failure tracking as real ones.
+func (s *AuthService) Login(login *model.UserLogin, ip string) (*model.User, *model.LoginChallenge, error) {
 	if err := security.ValidateEmail(login.Email); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if err := s.lockoutService.Check(login.Email, ip); err != nil {
+		return nil, nil, err
 	}
 
 	user, err := s.userRepo.GetByEmail(login.Email)
+	var valid bool
+	if err == nil {
+		valid = s.passwordHasher.Compare(user.PasswordHash, login.Password)
+	} else {
+		user = nil
+		valid = s.passwordHasher.CompareDummy(login.Password)
+	}
+
+	if !valid {
+		if err := s.lockoutService.RecordFailure(login.Email, ip, user); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("invalid credentials")
+	}
+
+	s.rehashIfNeeded(user, login.Password)
+
+	challenge, err := s.twoFactorService.ChallengeFor(user)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, nil, err
+	}
+	// With a code still to enter the counter keeps running, otherwise wrong
+	// codes could be reset by simply logging in again.
+	if challenge != nil && challenge.TwoFactorRequired {
+		return nil, challenge, nil
 	}
 
-	if !security.ComparePassword(user.PasswordHash, login.Password) {
-		return nil, errors.New("invalid credentials")
+	if err := s.lockoutService.RecordSuccess(login.Email); err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
 	}
 
-	return user, nil
+	if err := restoreOnLogin(s.userRepo, user); err != nil {
+		return nil, nil, err
+	}
+	return user, nil, nil
+}
+
+func (s *AuthService) VerifyEmail(token string) error {
+	userID, err := s.tokenRepo.Consume(model.TokenEmailVerification, security.HashToken(token))
+	if err != nil {
+		return err
+	}
+	return s.userRepo.SetEmailVerified(userID)
+}
+
+func (s *AuthService) ResendVerification(userID int64) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return errors.New("email already verified")
+	}
+
+	recent, err := s.tokenRepo.IssuedWithin(userID, model.TokenEmailVerification, resendInterval)
+	if err != nil {
+		return err
+	}
+	if recent {
+		return &ThrottledError{Message: "please wait before requesting another email", RetryAfter: resendInterval}
+	}
+
+	return s.sendVerification(user)
+}
+
+// RequestPasswordReset emails a reset link if the address belongs to an
+// account. It reports success either way so it cannot be used to probe for
+// registered addresses.
+func (s *AuthService) RequestPasswordReset(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	recent, err := s.tokenRepo.IssuedWithin(user.ID, model.TokenPasswordReset, resendInterval)
+	if err != nil || recent {
+		return err
+	}
+
+	token, hash, err := security.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.tokenRepo.Create(user.ID, model.TokenPasswordReset, hash, s.resetTTL); err != nil {
+		return err
+	}
+	return s.emailService.SendPasswordResetEmail(user, token, s.resetTTL)
+}
+
+// ResetPassword sets a new password from a reset token. Existing sessions and
+// personal access tokens are revoked, and since the link arrived by email the
+// address counts as verified.
+func (s *AuthService) ResetPassword(reset *model.PasswordReset) error {
+	tokenHash := security.HashToken(reset.Token)
+	userID, err := s.tokenRepo.Lookup(model.TokenPasswordReset, tokenHash)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	// A rejected password leaves the token usable for another attempt.
+	if err := s.passwordPolicy.Validate(reset.NewPassword, user.Email, user.Username, user.FullName); err != nil {
+		return err
+	}
+
+	if _, err := s.tokenRepo.Consume(model.TokenPasswordReset, tokenHash); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(reset.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(userID, hashedPassword); err != nil {
+		return err
+	}
+	if err := s.accessTokenRepo.DeleteByUser(userID); err != nil {
+		return err
+	}
+	return s.userRepo.SetEmailVerified(userID)
+}
+
+// ChangePassword sets a new password for a signed-in user. The current
+// password is required unless the account was created through an identity
+// provider and never had one. All sessions and personal access tokens are
+// revoked; the returned user carries the new token version so the caller can
+// issue a fresh session.
+func (s *AuthService) ChangePassword(userID int64, change *model.PasswordChange) (*model.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCurrentPassword(s.userRepo, s.passwordHasher, user, change.CurrentPassword); err != nil {
+		return nil, err
+	}
+	if err := s.passwordPolicy.Validate(change.NewPassword, user.Email, user.Username, user.FullName); err != nil {
+		return nil, err
+	}
+	if s.passwordHasher.Compare(user.PasswordHash, change.NewPassword) {
+		return nil, errors.New("new password must be different from the current one")
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(change.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdatePassword(userID, hashedPassword); err != nil {
+		return nil, err
+	}
+	if err := s.accessTokenRepo.DeleteByUser(userID); err != nil {
+		return nil, err
+	}
+
+	s.emailService.SendAccountChangeNotice(user, user.Email,
+		"The password of your SocialNet account was changed, all other sessions were signed out and "+
+			"all personal access tokens were revoked. "+
+			"If this wasn't you, reset your password right away.")
+
+	return s.userRepo.GetByID(userID)
+}
+
+// RequestEmailChange sends a confirmation link to the new address. The
+// account keeps its current address until the link is used.
+func (s *AuthService) RequestEmailChange(userID int64, change *model.EmailChange) error {
+	if err := security.ValidateEmail(change.Email); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkCurrentPassword(s.userRepo, s.passwordHasher, user, change.Password); err != nil {
+		return err
+	}
+	if strings.EqualFold(change.Email, user.Email) {
+		return errors.New("this is already your email address")
+	}
+	if _, err := s.userRepo.GetByEmail(change.Email); err == nil {
+		return errors.New("email already exists")
+	}
+
+	recent, err := s.tokenRepo.IssuedWithin(userID, model.TokenEmailChange, resendInterval)
+	if err != nil {
+		return err
+	}
+	if recent {
+		return &ThrottledError{Message: "please wait before requesting another email", RetryAfter: resendInterval}
+	}
+
+	token, hash, err := security.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.tokenRepo.CreateEmailChange(userID, hash, change.Email, s.emailChangeTTL); err != nil {
+		return err
+	}
+	return s.emailService.SendEmailChangeEmail(user, change.Email, token, s.emailChangeTTL)
+}
+
+// ConfirmEmailChange switches the account to the address the token was sent
+// to and lets the old address know.
+func (s *AuthService) ConfirmEmailChange(token string) error {
+	userID, newEmail, err := s.tokenRepo.ConsumeEmailChange(security.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if newEmail == "" {
+		return errors.New("invalid or expired token")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	// The address may have been registered since the link was sent.
+	if _, err := s.userRepo.GetByEmail(newEmail); err == nil {
+		return errors.New("email already exists")
+	}
+
+	if err := s.userRepo.UpdateEmail(userID, newEmail); err != nil {
+		return err
+	}
+
+	return s.emailService.SendAccountChangeNotice(user, user.Email,
+		"The email address of your SocialNet account was changed to "+newEmail+". "+
+			"If this wasn't you, reset your password and contact support.")
+}
+
+// CheckSession rejects tokens issued before the user's sessions were revoked.
+func (s *AuthService) CheckSession(userID int64, tokenVersion int) error {
+	current, err := s.userRepo.GetTokenVersion(userID)
+	if err != nil {
+		return err
+	}
+	if current != tokenVersion {
+		return errors.New("session revoked")
+	}
+	return nil
+}
+
+// rehashIfNeeded upgrades a hash made with a lower cost while the plain
+// password is at hand. Failures only delay the upgrade to the next login.
+func (s *AuthService) rehashIfNeeded(user *model.User, password string) {
+	if !s.passwordHasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		return
+	}
+	updated, err := s.userRepo.UpdatePasswordHash(user.ID, user.PasswordHash, hashedPassword)
+	if err != nil {
+		log.Printf("Failed to store rehashed password for user %d: %v", user.ID, err)
+		return
+	}
+	// A password changed since the compare keeps its own hash.
+	if updated {
+		user.PasswordHash = hashedPassword
+	}
+}
+
+// checkCurrentPassword confirms a sensitive change. Accounts without a
+// password of their own have nothing to confirm with.
+func checkCurrentPassword(userRepo *repository.UserRepository, passwordHasher *security.PasswordHasher,
+	user *model.User, password string) error {
+	hasPassword, err := userRepo.HasPassword(user.ID)
+	if err != nil {
+		return err
+	}
+	if hasPassword && !passwordHasher.Compare(user.PasswordHash, password) {
+		return errors.New("current password is incorrect")
+	}
+	return nil
+}
+
+// restoreOnLogin reactivates an account whose owner signs in again, which
+// also cancels a scheduled deletion.
+func restoreOnLogin(userRepo *repository.UserRepository, user *model.User) error {
+	restored, err := userRepo.Reactivate(user.ID)
+	if err != nil {
+		return err
+	}
+	if restored {
+		log.Printf("Reactivated user %d on login", user.ID)
+	}
+	return nil
+}
+
+func (s *AuthService) sendVerification(user *model.User) error {
+	token, hash, err := security.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.tokenRepo.Create(user.ID, model.TokenEmailVerification, hash, s.verificationTTL); err != nil {
+		return err
+	}
+	return s.emailService.SendVerificationEmail(user, token, s.verificationTTL)
+}
+
+// requireVerified blocks users who have not confirmed their email address
+// from publishing content.
+func requireVerified(userRepo *repository.UserRepository, userID int64) error {
+	user, err := userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.EmailVerified {
+		return errors.New("email not verified")
+	}
+	return nil
 }
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultBookmarksPerPage = 20
+	maxBookmarksPerPage     = 50
+	maxCollectionNameLength = 50
+)
+
+// BookmarkService keeps the posts users saved for later. Bookmarked posts
+// are shown the way PostService shows them, and only while the user can
+// still see them.
+type BookmarkService struct {
+	bookmarkRepo   *repository.BookmarkRepository
+	postRepo       *repository.PostRepository
+	privacyService *PrivacyService
+	postService    *PostService
+}
+
+func NewBookmarkService(bookmarkRepo *repository.BookmarkRepository, postRepo *repository.PostRepository,
+	privacyService *PrivacyService, postService *PostService) *BookmarkService {
+	return &BookmarkService{
+		bookmarkRepo:   bookmarkRepo,
+		postRepo:       postRepo,
+		privacyService: privacyService,
+		postService:    postService,
+	}
+}
+
+// AddBookmark saves a post the user can see. Bookmarking a repost saves
+// its original.
+func (s *BookmarkService) AddBookmark(userID int64, create *model.BookmarkCreate) (*model.Bookmark, error) {
+	post, err := s.postService.shareable(create.PostID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCollection(create.CollectionID, userID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.bookmarkRepo.Get(userID, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("already bookmarked")
+	}
+
+	if _, err := s.bookmarkRepo.Create(userID, post.ID, create.CollectionID); err != nil {
+		return nil, err
+	}
+	return s.getBookmark(userID, post.ID)
+}
+
+// MoveBookmark puts the bookmark in another collection, or takes it out of
+// its collection when collectionID is nil.
+func (s *BookmarkService) MoveBookmark(userID, postID int64, collectionID *int64) (*model.Bookmark, error) {
+	bookmark, err := s.bookmarkRepo.Get(userID, postID)
+	if err != nil {
+		return nil, err
+	}
+	if bookmark == nil {
+		return nil, ErrBookmarkNotFound
+	}
+	if err := s.checkCollection(collectionID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.bookmarkRepo.Move(bookmark.ID, collectionID); err != nil {
+		return nil, err
+	}
+	return s.getBookmark(userID, postID)
+}
+
+// RemoveBookmark also works for posts the user can no longer see.
+func (s *BookmarkService) RemoveBookmark(userID, postID int64) error {
+	return s.bookmarkRepo.Delete(userID, postID)
+}
+
+// GetBookmarks returns a page of the user's bookmarks, leaving out posts
+// they can no longer see. Hidden bookmarks are skipped rather than counted,
+// so a page only comes back short once there are no more bookmarks.
+func (s *BookmarkService) GetBookmarks(userID int64, filter *model.BookmarkFilter) ([]*model.Bookmark, error) {
+	if err := s.checkCollection(filter.CollectionID, userID); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 || filter.Limit > maxBookmarksPerPage {
+		filter.Limit = defaultBookmarksPerPage
+	}
+
+	limit := filter.Limit
+	batch := *filter
+	shown := make([]*model.Bookmark, 0, limit)
+	for len(shown) < limit {
+		bookmarks, err := s.bookmarkRepo.GetBookmarks(userID, &batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, bookmark := range bookmarks {
+			if len(shown) < limit && s.showBookmark(bookmark, userID) {
+				shown = append(shown, bookmark)
+			}
+		}
+		if len(bookmarks) < batch.Limit {
+			break
+		}
+		batch.BeforeID = bookmarks[len(bookmarks)-1].ID
+	}
+	return shown, nil
+}
+
+func (s *BookmarkService) getBookmark(userID, postID int64) (*model.Bookmark, error) {
+	bookmark, err := s.bookmarkRepo.Get(userID, postID)
+	if err != nil {
+		return nil, err
+	}
+	if bookmark == nil {
+		return nil, ErrBookmarkNotFound
+	}
+
+	bookmark.Post, err = s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.showBookmark(bookmark, userID) {
+		return nil, ErrPostNotFound
+	}
+	return bookmark, nil
+}
+
+func (s *BookmarkService) showBookmark(bookmark *model.Bookmark, userID int64) bool {
+	if s.privacyService.CheckPostVisible(bookmark.Post, userID) != nil {
+		return false
+	}
+	return s.postService.showPost(bookmark.Post, userID)
+}
+
+func (s *BookmarkService) checkCollection(collectionID *int64, userID int64) error {
+	if collectionID == nil {
+		return nil
+	}
+	_, err := s.bookmarkRepo.GetCollection(*collectionID, userID)
+	return err
+}
+
+func (s *BookmarkService) GetCollections(userID int64) ([]*model.BookmarkCollection, error) {
+	return s.bookmarkRepo.GetCollections(userID)
+}
+
+func (s *BookmarkService) CreateCollection(userID int64, create *model.BookmarkCollectionCreate) (*model.BookmarkCollection, error) {
+	name, err := s.collectionName(userID, create.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.bookmarkRepo.CreateCollection(userID, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.bookmarkRepo.GetCollection(id, userID)
+}
+
+func (s *BookmarkService) RenameCollection(id, userID int64, update *model.BookmarkCollectionCreate) (*model.BookmarkCollection, error) {
+	if _, err := s.bookmarkRepo.GetCollection(id, userID); err != nil {
+		return nil, err
+	}
+	name, err := s.collectionName(userID, update.Name, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.bookmarkRepo.RenameCollection(id, name); err != nil {
+		return nil, err
+	}
+	return s.bookmarkRepo.GetCollection(id, userID)
+}
+
+// DeleteCollection keeps the bookmarks that were in the collection; they
+// become unsorted.
+func (s *BookmarkService) DeleteCollection(id, userID int64) error {
+	if _, err := s.bookmarkRepo.GetCollection(id, userID); err != nil {
+		return err
+	}
+	return s.bookmarkRepo.DeleteCollection(id)
+}
+
+func (s *BookmarkService) collectionName(userID int64, name string, exceptID int64) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("collection name is required")
+	}
+	if utf8.RuneCountInString(name) > maxCollectionNameLength {
+		return "", errors.New("collection name can be at most 50 characters")
+	}
+
+	taken, err := s.bookmarkRepo.CollectionNameTaken(userID, name, exceptID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", errors.New("a collection with this name already exists")
+	}
+	return name, nil
+}
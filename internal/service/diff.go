@@ -0,0 +1,110 @@
+package service
+
+import (
+	"socialnet/internal/model"
+	"unicode"
+)
+
+// maxDiffCells bounds the table the comparison needs. Edits that touch more
+// are shown as the old text replaced by the new one.
+const maxDiffCells = 1 << 18
+
+// diffWords compares two versions of a text word by word. Runs of
+// whitespace are tokens of their own, so the equal and deleted ops spell out
+// from and the equal and inserted ops spell out to.
+func diffWords(from, to string) []*model.DiffOp {
+	a, b := tokenize(from), tokenize(to)
+
+	// Shared leading and trailing tokens are cut off before the quadratic
+	// part, which then only covers what the edit touched.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []*model.DiffOp
+	add := func(op model.DiffOpType, text string) {
+		if last := len(ops) - 1; last >= 0 && ops[last].Op == op {
+			ops[last].Text += text
+			return
+		}
+		ops = append(ops, &model.DiffOp{Op: op, Text: text})
+	}
+
+	for _, token := range a[:prefix] {
+		add(model.DiffEqual, token)
+	}
+
+	x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(x)*len(y) > maxDiffCells {
+		for _, token := range x {
+			add(model.DiffDelete, token)
+		}
+		for _, token := range y {
+			add(model.DiffInsert, token)
+		}
+		for _, token := range a[len(a)-suffix:] {
+			add(model.DiffEqual, token)
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			add(model.DiffEqual, x[i])
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			add(model.DiffDelete, x[i])
+			i++
+		default:
+			add(model.DiffInsert, y[j])
+			j++
+		}
+	}
+
+	for _, token := range a[len(a)-suffix:] {
+		add(model.DiffEqual, token)
+	}
+	return ops
+}
+
+// tokenize splits text into alternating runs of whitespace and
+// non-whitespace.
+func tokenize(text string) []string {
+	var tokens []string
+	start, space := 0, false
+	for i, r := range text {
+		if i > start && unicode.IsSpace(r) != space {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
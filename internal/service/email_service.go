@@ -0,0 +1,333 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"socialnet/internal/mail"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"socialnet/internal/security"
+	"strconv"
+	"time"
+)
+
+const (
+	digestScope     = "digest"
+	digestItemLimit = 50
+)
+
+type EmailService struct {
+	userRepo          *repository.UserRepository
+	notifRepo         *repository.NotificationRepository
+	prefRepo          *repository.NotificationPreferenceRepository
+	mailQueue         chan *mail.Message
+	baseURL           string
+	unsubscribeSecret string
+}
+
+func NewEmailService(userRepo *repository.UserRepository, notifRepo *repository.NotificationRepository,
+	prefRepo *repository.NotificationPreferenceRepository, mailQueue chan *mail.Message,
+	baseURL, unsubscribeSecret string) *EmailService {
+	return &EmailService{
+		userRepo:          userRepo,
+		notifRepo:         notifRepo,
+		prefRepo:          prefRepo,
+		mailQueue:         mailQueue,
+		baseURL:           baseURL,
+		unsubscribeSecret: unsubscribeSecret,
+	}
+}
+
+// SendNotificationEmail queues an immediate email for the notification types
+// that warrant one. Everything else only reaches the inbox through digests.
+func (s *EmailService) SendNotificationEmail(notification *model.Notification) error {
+	if notification.Type == model.NotificationSecurity {
+		return s.sendSecurityAlert(notification)
+	}
+	if notification.Type != model.NotificationFriendRequest && notification.Type != model.NotificationMention {
+		return nil
+	}
+
+	recipient, err := s.userRepo.GetByID(notification.UserID)
+	if err != nil {
+		return err
+	}
+	actor, err := s.userRepo.GetByID(notification.ActorID)
+	if err != nil {
+		return err
+	}
+
+	unsubscribeURL := s.unsubscribeURL(recipient.ID, string(notification.Type))
+
+	var subject, body string
+	switch notification.Type {
+	case model.NotificationFriendRequest:
+		subject = actor.Username + " sent you a friend request"
+		body, err = mail.RenderFriendRequest(&mail.FriendRequestData{
+			RecipientName:  displayName(recipient),
+			ActorName:      actor.Username,
+			BaseURL:        s.baseURL,
+			UnsubscribeURL: unsubscribeURL,
+		})
+	case model.NotificationMention:
+		subject = actor.Username + " mentioned you"
+		body, err = mail.RenderMention(&mail.MentionData{
+			RecipientName:  displayName(recipient),
+			ActorName:      actor.Username,
+			PostID:         notification.TargetID,
+			BaseURL:        s.baseURL,
+			UnsubscribeURL: unsubscribeURL,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(recipient.Email, subject, body, unsubscribeURL)
+	return nil
+}
+
+func (s *EmailService) sendSecurityAlert(notification *model.Notification) error {
+	recipient, err := s.userRepo.GetByID(notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	body, err := mail.RenderSecurityAlert(&mail.SecurityAlertData{
+		RecipientName: displayName(recipient),
+		Message:       notification.Message,
+		BaseURL:       s.baseURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(recipient.Email, "Security alert for your SocialNet account", body, "")
+	return nil
+}
+
+// SendDueDigests queues a digest for every user whose daily or weekly digest
+// is due. Users inside their quiet hours are retried on the next run.
+func (s *EmailService) SendDueDigests(now time.Time) error {
+	schedules := []struct {
+		frequency model.DigestFrequency
+		interval  time.Duration
+		period    string
+	}{
+		{model.DigestDaily, 24 * time.Hour, "today"},
+		{model.DigestWeekly, 7 * 24 * time.Hour, "this week"},
+	}
+
+	for _, schedule := range schedules {
+		userIDs, err := s.prefRepo.GetDueDigests(schedule.frequency, schedule.interval)
+		if err != nil {
+			return err
+		}
+
+		for _, userID := range userIDs {
+			quietHours, err := s.prefRepo.GetQuietHours(userID)
+			if err != nil {
+				return err
+			}
+			if inQuietHours(quietHours, now) {
+				continue
+			}
+
+			if err := s.sendDigest(userID, schedule.period); err != nil {
+				return err
+			}
+			if err := s.prefRepo.MarkDigestSent(userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *EmailService) sendDigest(userID int64, period string) error {
+	notifications, err := s.notifRepo.GetDigestItems(userID, digestItemLimit)
+	if err != nil {
+		return err
+	}
+
+	var items []mail.DigestItem
+	for _, notification := range notifications {
+		enabled, err := s.prefRepo.IsEnabled(userID, notification.Type, model.ChannelEmail)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			continue
+		}
+		items = append(items, mail.DigestItem{Message: notification.Message, CreatedAt: notification.UpdatedAt})
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	recipient, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	unsubscribeURL := s.unsubscribeURL(userID, digestScope)
+	body, err := mail.RenderDigest(&mail.DigestData{
+		RecipientName:  displayName(recipient),
+		Period:         period,
+		Items:          items,
+		BaseURL:        s.baseURL,
+		UnsubscribeURL: unsubscribeURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(recipient.Email, "Your SocialNet digest", body, unsubscribeURL)
+	return nil
+}
+
+// Unsubscribe turns off the email channel named by a signed link: either one
+// notification type or the digest.
+func (s *EmailService) Unsubscribe(token string) error {
+	userID, scope, err := security.ParseUnsubscribeToken(token, s.unsubscribeSecret)
+	if err != nil {
+		return err
+	}
+
+	if scope == digestScope {
+		return s.prefRepo.SetDigestFrequency(userID, model.DigestOff)
+	}
+
+	notifType := model.NotificationType(scope)
+	if !isValidNotificationType(notifType) {
+		return errors.New("invalid token")
+	}
+
+	return s.prefRepo.SetPreference(userID, &model.NotificationPreference{
+		Type:    notifType,
+		Channel: model.ChannelEmail,
+		Enabled: false,
+	})
+}
+
+func (s *EmailService) SendVerificationEmail(user *model.User, token string, ttl time.Duration) error {
+	body, err := mail.RenderVerification(&mail.AccountLinkData{
+		RecipientName: displayName(user),
+		Link:          s.baseURL + "/verify-email?token=" + url.QueryEscape(token),
+		ExpiresIn:     humanDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(user.Email, "Confirm your SocialNet email address", body, "")
+	return nil
+}
+
+func (s *EmailService) SendPasswordResetEmail(user *model.User, token string, ttl time.Duration) error {
+	body, err := mail.RenderPasswordReset(&mail.AccountLinkData{
+		RecipientName: displayName(user),
+		Link:          s.baseURL + "/reset-password?token=" + url.QueryEscape(token),
+		ExpiresIn:     humanDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(user.Email, "Reset your SocialNet password", body, "")
+	return nil
+}
+
+// SendEmailChangeEmail sends the confirmation link to the new address.
+func (s *EmailService) SendEmailChangeEmail(user *model.User, newEmail, token string, ttl time.Duration) error {
+	body, err := mail.RenderEmailChange(&mail.AccountLinkData{
+		RecipientName: displayName(user),
+		Link:          s.baseURL + "/confirm-email?token=" + url.QueryEscape(token),
+		ExpiresIn:     humanDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(newEmail, "Confirm your new SocialNet email address", body, "")
+	return nil
+}
+
+func (s *EmailService) SendDataExportEmail(user *model.User, ttl time.Duration) error {
+	body, err := mail.RenderDataExport(&mail.AccountLinkData{
+		RecipientName: displayName(user),
+		Link:          s.baseURL + "/settings/account",
+		ExpiresIn:     humanDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(user.Email, "Your SocialNet data is ready to download", body, "")
+	return nil
+}
+
+// SendAccountChangeNotice tells the user about a change to their sign-in
+// details. to is passed separately so a notice about a new email address can
+// still reach the old one.
+func (s *EmailService) SendAccountChangeNotice(user *model.User, to, message string) error {
+	body, err := mail.RenderSecurityAlert(&mail.SecurityAlertData{
+		RecipientName: displayName(user),
+		Message:       message,
+		BaseURL:       s.baseURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(to, "Your SocialNet account was changed", body, "")
+	return nil
+}
+
+func (s *EmailService) unsubscribeURL(userID int64, scope string) string {
+	token := security.GenerateUnsubscribeToken(userID, scope, s.unsubscribeSecret)
+	return s.baseURL + "/email/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+// enqueue hands the message to the email worker. Transactional mail such as
+// verification links passes an empty unsubscribeURL and gets no list headers.
+func (s *EmailService) enqueue(to, subject, body, unsubscribeURL string) {
+	message := &mail.Message{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	}
+	if unsubscribeURL != "" {
+		message.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+	s.mailQueue <- message
+}
+
+func humanDuration(d time.Duration) string {
+	if d >= 72*time.Hour && d%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(d/(24*time.Hour))) + " days"
+	}
+	if d >= time.Hour && d%time.Hour == 0 {
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return strconv.Itoa(int(d/time.Hour)) + " hours"
+	}
+	minutes := int(d / time.Minute)
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return strconv.Itoa(minutes) + " minutes"
+}
+
+func displayName(user *model.User) string {
+	if user.FullName != "" {
+		return user.FullName
+	}
+	return user.Username
+}
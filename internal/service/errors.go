@@ -0,0 +1,30 @@
+package service
+
+import (
+	"errors"
+	"socialnet/internal/repository"
+	"time"
+)
+
+// Errors handlers tell apart to pick a status code. Those that start in the
+// repositories are passed through unchanged, so errors.Is matches either.
+var (
+	ErrUserNotFound       = repository.ErrUserNotFound
+	ErrPostNotFound       = repository.ErrPostNotFound
+	ErrBookmarkNotFound   = repository.ErrBookmarkNotFound
+	ErrCollectionNotFound = repository.ErrCollectionNotFound
+	ErrIdentityNotFound   = repository.ErrIdentityNotFound
+
+	ErrNoPoll = errors.New("post has no poll")
+)
+
+// ThrottledError reports a request repeated too soon. RetryAfter is the
+// longest the caller may have to wait before trying again.
+type ThrottledError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return e.Message
+}
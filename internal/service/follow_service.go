@@ -0,0 +1,192 @@
+package service
+
+import (
+	"errors"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+)
+
+type FollowService struct {
+	followRepo     *repository.FollowRepository
+	friendRepo     *repository.FriendshipRepository
+	userRepo       *repository.UserRepository
+	privacyService *PrivacyService
+	notifQueue     chan *model.Notification
+}
+
+func NewFollowService(followRepo *repository.FollowRepository, friendRepo *repository.FriendshipRepository,
+	userRepo *repository.UserRepository, privacyService *PrivacyService,
+	notifQueue chan *model.Notification) *FollowService {
+	return &FollowService{
+		followRepo:     followRepo,
+		friendRepo:     friendRepo,
+		userRepo:       userRepo,
+		privacyService: privacyService,
+		notifQueue:     notifQueue,
+	}
+}
+
+// Follow subscribes followerID to followeeID's public posts. Accounts that
+// approve their followers get a follow request instead; friends are always
+// accepted.
+func (s *FollowService) Follow(followerID, followeeID int64) (*model.Follow, error) {
+	if followerID == followeeID {
+		return nil, errors.New("cannot follow yourself")
+	}
+	if err := requireActive(s.userRepo, followeeID); err != nil {
+		return nil, err
+	}
+
+	friendship, err := s.friendRepo.GetBetween(followerID, followeeID)
+	if err != nil {
+		return nil, err
+	}
+	if friendship != nil && friendship.Status == model.FriendshipBlocked {
+		return nil, errors.New("you cannot follow this user")
+	}
+
+	existing, err := s.followRepo.Get(followerID, followeeID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.Status == model.FollowPending {
+			return nil, errors.New("follow request already sent")
+		}
+		return nil, errors.New("already following")
+	}
+
+	settings, err := s.privacyService.GetSettings(followeeID)
+	if err != nil {
+		return nil, err
+	}
+	status := model.FollowAccepted
+	if settings.ApproveFollowers && (friendship == nil || friendship.Status != model.FriendshipAccepted) {
+		status = model.FollowPending
+	}
+
+	id, err := s.followRepo.Create(followerID, followeeID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	follower, _ := s.userRepo.GetByID(followerID)
+	if status == model.FollowPending {
+		s.notifQueue <- &model.Notification{
+			UserID:   followeeID,
+			ActorID:  followerID,
+			Type:     model.NotificationFollowRequest,
+			TargetID: id,
+			Message:  follower.Username + " asked to follow you",
+		}
+	} else {
+		s.notifQueue <- &model.Notification{
+			UserID:   followeeID,
+			ActorID:  followerID,
+			Type:     model.NotificationFollow,
+			TargetID: followerID,
+			Message:  follower.Username + " started following you",
+		}
+	}
+
+	return s.followRepo.GetByID(id)
+}
+
+// Unfollow ends a follow or withdraws a pending follow request.
+func (s *FollowService) Unfollow(followerID, followeeID int64) error {
+	follow, err := s.followRepo.Get(followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if follow == nil {
+		return errors.New("not following this user")
+	}
+	return s.followRepo.Delete(follow.ID)
+}
+
+// GetFollowers lists the user's followers, if the viewer may see their
+// friend list.
+func (s *FollowService) GetFollowers(userID, viewerID int64) ([]*model.User, error) {
+	if err := s.checkCanView(userID, viewerID); err != nil {
+		return nil, err
+	}
+	return s.followRepo.GetFollowers(userID)
+}
+
+// GetFollowing lists the accounts the user follows, if the viewer may see
+// their friend list.
+func (s *FollowService) GetFollowing(userID, viewerID int64) ([]*model.User, error) {
+	if err := s.checkCanView(userID, viewerID); err != nil {
+		return nil, err
+	}
+	return s.followRepo.GetFollowing(userID)
+}
+
+func (s *FollowService) GetFollowRequests(userID int64) ([]*model.Follow, error) {
+	follows, err := s.followRepo.GetPendingRequests(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, follow := range follows {
+		follow.Follower = publicUser(s.userRepo, follow.FollowerID)
+	}
+
+	return follows, nil
+}
+
+func (s *FollowService) AcceptFollowRequest(requestID, userID int64) error {
+	follow, err := s.pendingRequest(requestID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.followRepo.Accept(follow.ID); err != nil {
+		return err
+	}
+
+	followee, _ := s.userRepo.GetByID(userID)
+	s.notifQueue <- &model.Notification{
+		UserID:   follow.FollowerID,
+		ActorID:  userID,
+		Type:     model.NotificationFollowRequest,
+		TargetID: follow.ID,
+		Message:  followee.Username + " accepted your follow request",
+	}
+	return nil
+}
+
+func (s *FollowService) DeclineFollowRequest(requestID, userID int64) error {
+	follow, err := s.pendingRequest(requestID, userID)
+	if err != nil {
+		return err
+	}
+	return s.followRepo.Delete(follow.ID)
+}
+
+func (s *FollowService) pendingRequest(requestID, userID int64) (*model.Follow, error) {
+	follow, err := s.followRepo.GetByID(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if follow.FolloweeID != userID {
+		return nil, errors.New("unauthorized")
+	}
+	if follow.Status != model.FollowPending {
+		return nil, errors.New("request already processed")
+	}
+	return follow, nil
+}
+
+func (s *FollowService) checkCanView(userID, viewerID int64) error {
+	if err := requireActive(s.userRepo, userID); err != nil {
+		return err
+	}
+	canView, err := s.privacyService.CanViewFriends(userID, viewerID)
+	if err != nil {
+		return err
+	}
+	if !canView {
+		return errors.New("this user's connections are private")
+	}
+	return nil
+}
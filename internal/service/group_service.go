@@ -8,17 +8,19 @@ import (
 )
 
 type GroupService struct {
-	groupRepo  *repository.GroupRepository
-	userRepo   *repository.UserRepository
-	notifQueue chan *model.Notification
+	groupRepo      *repository.GroupRepository
+	userRepo       *repository.UserRepository
+	privacyService *PrivacyService
+	notifQueue     chan *model.Notification
 }
 
 func NewGroupService(groupRepo *repository.GroupRepository, userRepo *repository.UserRepository,
-	notifQueue chan *model.Notification) *GroupService {
+	privacyService *PrivacyService, notifQueue chan *model.Notification) *GroupService {
 	return &GroupService{
-		groupRepo:  groupRepo,
-		userRepo:   userRepo,
-		notifQueue: notifQueue,
+		groupRepo:      groupRepo,
+		userRepo:       userRepo,
+		privacyService: privacyService,
+		notifQueue:     notifQueue,
 	}
 }
 
@@ -48,8 +50,7 @@ func (s *GroupService) GetGroup(groupID, userID int64) (*model.Group, error) {
 		return nil, err
 	}
 
-	owner, _ := s.userRepo.GetByID(group.OwnerID)
-	group.Owner = owner
+	group.Owner = publicUser(s.userRepo, group.OwnerID)
 
 	count, _ := s.groupRepo.GetMemberCount(groupID)
 	group.MemberCount = count
@@ -90,6 +91,9 @@ func (s *GroupService) PostToGroup(groupID, userID int64, create *model.GroupPos
 	if err := security.ValidateContent(create.Content, 5000); err != nil {
 		return nil, err
 	}
+	if err := requireVerified(s.userRepo, userID); err != nil {
+		return nil, err
+	}
 
 	isMember, _ := s.groupRepo.IsMember(groupID, userID)
 	if !isMember {
@@ -112,8 +116,7 @@ func (s *GroupService) PostToGroup(groupID, userID int64, create *model.GroupPos
 		return nil, err
 	}
 
-	author, _ := s.userRepo.GetByID(post.UserID)
-	post.Author = author
+	post.Author = publicUser(s.userRepo, post.UserID)
 
 	return post, nil
 }
@@ -130,8 +133,7 @@ func (s *GroupService) GetGroupPosts(groupID, userID int64) ([]*model.GroupPost,
 	}
 
 	for _, post := range posts {
-		author, _ := s.userRepo.GetByID(post.UserID)
-		post.Author = author
+		post.Author = publicUser(s.userRepo, post.UserID)
 	}
 
 	return posts, nil
@@ -144,8 +146,7 @@ func (s *GroupService) GetUserGroups(userID int64) ([]*model.Group, error) {
 	}
 
 	for _, group := range groups {
-		owner, _ := s.userRepo.GetByID(group.OwnerID)
-		group.Owner = owner
+		group.Owner = publicUser(s.userRepo, group.OwnerID)
 
 		count, _ := s.groupRepo.GetMemberCount(group.ID)
 		group.MemberCount = count
@@ -155,3 +156,36 @@ func (s *GroupService) GetUserGroups(userID int64) ([]*model.Group, error) {
 
 	return groups, nil
 }
+
+// GetGroupsOfUser lists the groups another user belongs to, if they let the
+// viewer see them. IsMember refers to the viewer.
+func (s *GroupService) GetGroupsOfUser(userID, viewerID int64) ([]*model.Group, error) {
+	if err := requireActive(s.userRepo, userID); err != nil {
+		return nil, err
+	}
+
+	canView, err := s.privacyService.CanViewGroups(userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		return nil, errors.New("this user's groups are private")
+	}
+
+	groups, err := s.groupRepo.GetUserGroups(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		group.Owner = publicUser(s.userRepo, group.OwnerID)
+
+		count, _ := s.groupRepo.GetMemberCount(group.ID)
+		group.MemberCount = count
+
+		isMember, _ := s.groupRepo.IsMember(group.ID, viewerID)
+		group.IsMember = isMember
+	}
+
+	return groups, nil
+}
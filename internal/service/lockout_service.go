@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"strings"
+	"time"
+)
+
+// LockoutPolicy configures how failed logins are throttled. Accounts back off
+// exponentially from BackoffThreshold failures and are locked for
+// LockoutDuration at LockoutThreshold; a client IP is locked once it reaches
+// IPLockoutThreshold failures across all accounts.
+type LockoutPolicy struct {
+	BackoffThreshold   int
+	BackoffBase        time.Duration
+	LockoutThreshold   int
+	LockoutDuration    time.Duration
+	IPLockoutThreshold int
+	FailureWindow      time.Duration
+}
+
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return "too many failed login attempts, try again later"
+}
+
+type LockoutService struct {
+	failureRepo *repository.LoginFailureRepository
+	notifQueue  chan *model.Notification
+	policy      LockoutPolicy
+}
+
+func NewLockoutService(failureRepo *repository.LoginFailureRepository, notifQueue chan *model.Notification,
+	policy LockoutPolicy) *LockoutService {
+	return &LockoutService{
+		failureRepo: failureRepo,
+		notifQueue:  notifQueue,
+		policy:      policy,
+	}
+}
+
+// Check returns a *LockedError while either the email or the IP is locked.
+// It never looks up the account, so locked and unknown emails behave alike.
+func (s *LockoutService) Check(email, ip string) error {
+	accountWait, err := s.failureRepo.LockedFor(model.LockoutAccount, normalizeEmail(email))
+	if err != nil {
+		return err
+	}
+	ipWait, err := s.failureRepo.LockedFor(model.LockoutIP, ip)
+	if err != nil {
+		return err
+	}
+
+	wait := max(accountWait, ipWait)
+	if wait > 0 {
+		return &LockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt against the email and the IP and
+// locks them when the policy says so. user is nil when the email does not
+// belong to an account; the owner of a real account is notified when it gets
+// locked.
+func (s *LockoutService) RecordFailure(email, ip string, user *model.User) error {
+	id, failures, err := s.failureRepo.RecordFailure(model.LockoutAccount, normalizeEmail(email), s.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+	if wait := s.accountLockDuration(failures); wait > 0 {
+		if err := s.failureRepo.Lock(id, wait); err != nil {
+			return err
+		}
+	}
+	if failures == s.policy.LockoutThreshold && user != nil {
+		s.notifQueue <- &model.Notification{
+			UserID: user.ID,
+			Type:   model.NotificationSecurity,
+			Message: fmt.Sprintf("Your account was locked for %s after %d failed login attempts. "+
+				"If this wasn't you, consider changing your password.", humanDuration(s.policy.LockoutDuration), failures),
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+	id, failures, err = s.failureRepo.RecordFailure(model.LockoutIP, ip, s.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+	if s.policy.IPLockoutThreshold > 0 && failures >= s.policy.IPLockoutThreshold {
+		return s.failureRepo.Lock(id, s.policy.LockoutDuration)
+	}
+	return nil
+}
+
+// RecordSuccess clears the account's counter. The IP counter is left alone so
+// that logging in to one account does not reset attempts against others.
+func (s *LockoutService) RecordSuccess(email string) error {
+	return s.failureRepo.Reset(model.LockoutAccount, normalizeEmail(email))
+}
+
+func (s *LockoutService) GetLockouts() ([]*model.Lockout, error) {
+	return s.failureRepo.GetLocked(100)
+}
+
+func (s *LockoutService) ClearLockout(id int64) error {
+	return s.failureRepo.Delete(id)
+}
+
+func (s *LockoutService) accountLockDuration(failures int) time.Duration {
+	if s.policy.LockoutThreshold > 0 && failures >= s.policy.LockoutThreshold {
+		return s.policy.LockoutDuration
+	}
+	if s.policy.BackoffThreshold <= 0 || failures < s.policy.BackoffThreshold {
+		return 0
+	}
+
+	wait := s.policy.BackoffBase << (failures - s.policy.BackoffThreshold)
+	if wait <= 0 || wait > s.policy.LockoutDuration {
+		return s.policy.LockoutDuration
+	}
+	return wait
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"regexp"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"strings"
+)
+
+var mentionRegex = regexp.MustCompile(`@([A-Za-z0-9_]{3,30})`)
+
+func extractMentions(content string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionRegex.FindAllStringSubmatch(content, -1) {
+		username := match[1]
+		if seen[strings.ToLower(username)] {
+			continue
+		}
+		seen[strings.ToLower(username)] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+// notifyMentions queues a mention notification for every existing user
+// referenced as @username in content, except the author.
+func notifyMentions(userRepo *repository.UserRepository, notifQueue chan *model.Notification,
+	authorID, postID int64, content, where string) {
+	usernames := extractMentions(content)
+	if len(usernames) == 0 {
+		return
+	}
+
+	author, err := userRepo.GetByID(authorID)
+	if err != nil {
+		return
+	}
+
+	for _, username := range usernames {
+		mentioned, err := findByUsername(userRepo, username)
+		if err != nil || mentioned.ID == authorID {
+			continue
+		}
+
+		notifQueue <- &model.Notification{
+			UserID:   mentioned.ID,
+			ActorID:  authorID,
+			Type:     model.NotificationMention,
+			TargetID: postID,
+			Message:  author.Username + " mentioned you in " + where,
+		}
+	}
+}
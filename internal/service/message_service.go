@@ -8,23 +8,32 @@ import (
 )
 
 type MessageService struct {
-	messageRepo *repository.MessageRepository
-	friendRepo  *repository.FriendshipRepository
-	userRepo    *repository.UserRepository
-	notifQueue  chan *model.Notification
+	messageRepo     *repository.MessageRepository
+	friendRepo      *repository.FriendshipRepository
+	userRepo        *repository.UserRepository
+	presenceService *PresenceService
+	notifQueue      chan *model.Notification
 }
 
 func NewMessageService(messageRepo *repository.MessageRepository, friendRepo *repository.FriendshipRepository,
-	userRepo *repository.UserRepository, notifQueue chan *model.Notification) *MessageService {
+	userRepo *repository.UserRepository, presenceService *PresenceService,
+	notifQueue chan *model.Notification) *MessageService {
 	return &MessageService{
-		messageRepo: messageRepo,
-		friendRepo:  friendRepo,
-		userRepo:    userRepo,
-		notifQueue:  notifQueue,
+		messageRepo:     messageRepo,
+		friendRepo:      friendRepo,
+		userRepo:        userRepo,
+		presenceService: presenceService,
+		notifQueue:      notifQueue,
 	}
 }
 
-func (s *MessageService) StartConversation(user1ID, user2ID int64) (*model.Conversation, error) {
+// StartConversation opens, or returns the existing, conversation with the
+// user create names, by ID or by username.
+func (s *MessageService) StartConversation(user1ID int64, create *model.ConversationCreate) (*model.Conversation, error) {
+	user2ID, err := resolveUserID(s.userRepo, create.ParticipantID, create.Username)
+	if err != nil {
+		return nil, err
+	}
 	if user1ID == user2ID {
 		return nil, errors.New("cannot message yourself")
 	}
@@ -85,11 +94,18 @@ func (s *MessageService) SendMessage(conversationID, userID int64, create *model
 	sender, _ := s.userRepo.GetByID(userID)
 	notifMessage := sender.Username + " sent you a message"
 
-	s.notifQueue <- &model.Notification{
-		UserID:   0,
-		Type:     model.NotificationMessage,
-		TargetID: conversationID,
-		Message:  notifMessage,
+	memberIDs, _ := s.messageRepo.GetMemberIDs(conversationID)
+	for _, memberID := range memberIDs {
+		if memberID == userID {
+			continue
+		}
+		s.notifQueue <- &model.Notification{
+			UserID:   memberID,
+			ActorID:  userID,
+			Type:     model.NotificationMessage,
+			TargetID: conversationID,
+			Message:  notifMessage,
+		}
 	}
 
 	return message, nil
@@ -107,13 +123,24 @@ func (s *MessageService) GetMessages(conversationID, userID int64) ([]*model.Mes
 	}
 
 	for _, message := range messages {
-		author, _ := s.userRepo.GetByID(message.UserID)
-		message.Author = author
+		message.Author = publicUser(s.userRepo, message.UserID)
 	}
 
 	return messages, nil
 }
 
 func (s *MessageService) GetConversations(userID int64) ([]*model.Conversation, error) {
-	return s.messageRepo.GetUserConversations(userID)
+	conversations, err := s.messageRepo.GetUserConversations(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := make([]*model.User, 0, len(conversations))
+	for _, conversation := range conversations {
+		participants = append(participants, conversation.Participant)
+	}
+	if err := s.presenceService.ShowPresence(participants, userID); err != nil {
+		return nil, err
+	}
+	return conversations, nil
 }
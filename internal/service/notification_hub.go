@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"socialnet/internal/model"
+	"sync"
+)
+
+const subscriberBuffer = 16
+
+// NotificationHub fans notification events out to the live connections of
+// each user. It only holds in-memory state; anything a client misses while
+// disconnected is replayed from the notifications table.
+type NotificationHub struct {
+	subscribers map[int64]map[chan *model.NotificationEvent]struct{}
+	maxPerUser  int
+	mu          sync.RWMutex
+}
+
+func NewNotificationHub(maxPerUser int) *NotificationHub {
+	return &NotificationHub{
+		subscribers: make(map[int64]map[chan *model.NotificationEvent]struct{}),
+		maxPerUser:  maxPerUser,
+	}
+}
+
+func (h *NotificationHub) Subscribe(userID int64) (chan *model.NotificationEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	channels := h.subscribers[userID]
+	if channels == nil {
+		channels = make(map[chan *model.NotificationEvent]struct{})
+		h.subscribers[userID] = channels
+	}
+	if h.maxPerUser > 0 && len(channels) >= h.maxPerUser {
+		return nil, errors.New("too many open streams")
+	}
+
+	ch := make(chan *model.NotificationEvent, subscriberBuffer)
+	channels[ch] = struct{}{}
+	return ch, nil
+}
+
+// Unsubscribe is safe to call for a channel Publish already dropped.
+func (h *NotificationHub) Unsubscribe(userID int64, ch chan *model.NotificationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	channels := h.subscribers[userID]
+	delete(channels, ch)
+	if len(channels) == 0 {
+		delete(h.subscribers, userID)
+	}
+}
+
+// Publish never blocks the caller. A subscriber whose buffer is full is
+// dropped and its channel closed, so its stream ends and the client
+// reconnects, catching up through Last-Event-ID.
+func (h *NotificationHub) Publish(userID int64, event *model.NotificationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	channels := h.subscribers[userID]
+	for ch := range channels {
+		select {
+		case ch <- event:
+		default:
+			delete(channels, ch)
+			close(ch)
+		}
+	}
+	if len(channels) == 0 {
+		delete(h.subscribers, userID)
+	}
+}
+
+func (h *NotificationHub) HasSubscribers(userID int64) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[userID]) > 0
+}
@@ -1,29 +1,194 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"socialnet/internal/model"
 	"socialnet/internal/repository"
+	"time"
+)
+
+const (
+	quietHoursLayout  = "15:04"
+	latestActorsLimit = 3
+	replayLimit       = 100
+
+	EventNotification = "notification"
+	EventUnreadCount  = "unread_count"
 )
 
 type NotificationService struct {
-	notifRepo *repository.NotificationRepository
+	notifRepo         *repository.NotificationRepository
+	prefRepo          *repository.NotificationPreferenceRepository
+	hub               *NotificationHub
+	aggregationWindow time.Duration
 }
 
-func NewNotificationService(notifRepo *repository.NotificationRepository) *NotificationService {
-	return &NotificationService{notifRepo: notifRepo}
+func NewNotificationService(notifRepo *repository.NotificationRepository,
+	prefRepo *repository.NotificationPreferenceRepository, hub *NotificationHub,
+	aggregationWindow time.Duration) *NotificationService {
+	return &NotificationService{
+		notifRepo:         notifRepo,
+		prefRepo:          prefRepo,
+		hub:               hub,
+		aggregationWindow: aggregationWindow,
+	}
 }
 
+// CreateNotification stores a notification and pushes it to the user's open
+// streams. Likes, comments and messages on the same target within the
+// aggregation window are folded into the existing row instead of creating a
+// new one.
 func (s *NotificationService) CreateNotification(notification *model.Notification) error {
-	_, err := s.notifRepo.Create(notification)
-	return err
+	id, err := s.store(notification)
+	if err != nil {
+		return err
+	}
+
+	if !s.hub.HasSubscribers(notification.UserID) {
+		return nil
+	}
+
+	stored, err := s.notifRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	actors, _ := s.notifRepo.GetActors(id, latestActorsLimit)
+	stored.Actors = actors
+
+	s.hub.Publish(stored.UserID, &model.NotificationEvent{Seq: stored.Seq, Name: EventNotification, Data: stored})
+	s.publishUnreadCount(stored.UserID)
+	return nil
+}
+
+func (s *NotificationService) store(notification *model.Notification) (int64, error) {
+	if notification.ActorID != 0 && isAggregatable(notification.Type) && s.aggregationWindow > 0 {
+		existing, err := s.notifRepo.FindAggregate(notification.UserID, notification.Type,
+			notification.TargetID, s.aggregationWindow)
+		if err != nil {
+			return 0, err
+		}
+		if existing != nil {
+			return existing.ID, s.aggregate(existing, notification)
+		}
+	}
+
+	id, err := s.notifRepo.Create(notification)
+	if err != nil {
+		return 0, err
+	}
+
+	if notification.ActorID != 0 {
+		if err := s.notifRepo.AddActor(id, notification.ActorID); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
 }
 
-func (s *NotificationService) GetNotifications(userID int64) ([]*model.Notification, error) {
-	return s.notifRepo.GetByUser(userID, 50)
+func (s *NotificationService) aggregate(existing *model.Notification, notification *model.Notification) error {
+	if err := s.notifRepo.AddActor(existing.ID, notification.ActorID); err != nil {
+		return err
+	}
+
+	count, err := s.notifRepo.GetActorCount(existing.ID)
+	if err != nil {
+		return err
+	}
+
+	actors, err := s.notifRepo.GetActors(existing.ID, 2)
+	if err != nil {
+		return err
+	}
+
+	// A lone actor keeps their own wording, such as the reaction they
+	// picked.
+	message := notification.Message
+	if count > 1 && len(actors) > 0 {
+		message = formatAggregateMessage(existing.Type, actors, count)
+	}
+
+	return s.notifRepo.UpdateAggregate(existing.ID, count, message)
 }
 
-func (s *NotificationService) MarkAsRead(notificationID int64) error {
-	return s.notifRepo.MarkAsRead(notificationID)
+func (s *NotificationService) GetNotifications(userID int64, filter *model.NotificationFilter) ([]*model.Notification, error) {
+	if filter.Type != "" && !isValidNotificationType(filter.Type) {
+		return nil, errors.New("invalid notification type")
+	}
+	filter.Limit = 50
+
+	notifications, err := s.notifRepo.GetByUser(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, notification := range notifications {
+		actors, _ := s.notifRepo.GetActors(notification.ID, latestActorsLimit)
+		notification.Actors = actors
+	}
+
+	return notifications, nil
+}
+
+// GetNotificationsSince returns up to a page of what a stream missed after
+// the given sequence number, oldest first. An empty page means the stream
+// has caught up.
+func (s *NotificationService) GetNotificationsSince(userID, seq int64) ([]*model.Notification, error) {
+	notifications, err := s.notifRepo.GetSince(userID, seq, replayLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, notification := range notifications {
+		actors, _ := s.notifRepo.GetActors(notification.ID, latestActorsLimit)
+		notification.Actors = actors
+	}
+
+	return notifications, nil
+}
+
+func (s *NotificationService) Subscribe(userID int64) (chan *model.NotificationEvent, error) {
+	return s.hub.Subscribe(userID)
+}
+
+func (s *NotificationService) Unsubscribe(userID int64, ch chan *model.NotificationEvent) {
+	s.hub.Unsubscribe(userID, ch)
+}
+
+func (s *NotificationService) MarkAsRead(notificationID, userID int64) error {
+	if err := s.notifRepo.MarkAsRead(notificationID, userID); err != nil {
+		return err
+	}
+	s.publishUnreadCount(userID)
+	return nil
+}
+
+func (s *NotificationService) MarkAllAsRead(userID, upToID int64) (int64, error) {
+	updated, err := s.notifRepo.MarkAllAsRead(userID, upToID)
+	if err != nil {
+		return 0, err
+	}
+	s.publishUnreadCount(userID)
+	return updated, nil
+}
+
+func (s *NotificationService) DeleteNotification(notificationID, userID int64) error {
+	if err := s.notifRepo.Delete(notificationID, userID); err != nil {
+		return err
+	}
+	s.publishUnreadCount(userID)
+	return nil
+}
+
+func (s *NotificationService) publishUnreadCount(userID int64) {
+	if !s.hub.HasSubscribers(userID) {
+		return
+	}
+	count, err := s.notifRepo.GetUnreadCount(userID)
+	if err != nil {
+		return
+	}
+	s.hub.Publish(userID, &model.NotificationEvent{Name: EventUnreadCount, Data: map[string]int{"count": count}})
 }
 
 func (s *NotificationService) GetUnreadCount(userID int64) (int, error) {
@@ -31,5 +196,257 @@ func (s *NotificationService) GetUnreadCount(userID int64) (int, error) {
 }
 
 func (s *NotificationService) ClearNotifications(userID int64) error {
-	return s.notifRepo.DeleteByUser(userID)
+	if err := s.notifRepo.DeleteByUser(userID); err != nil {
+		return err
+	}
+	s.publishUnreadCount(userID)
+	return nil
+}
+
+// ShouldDeliver decides whether a notification may go out on the given
+// channel right now. Muted targets and disabled types are dropped on every
+// channel. Quiet hours suppress email and push for good; they are not sent
+// later, but the in-app list is still written, so unread notifications show up
+// there and in the next email digest. Security alerts cannot be turned off.
+func (s *NotificationService) ShouldDeliver(notification *model.Notification, channel model.NotificationChannel,
+	now time.Time) (bool, error) {
+	if notification.Type == model.NotificationSecurity {
+		return channel != model.ChannelPush, nil
+	}
+
+	enabled, err := s.prefRepo.IsEnabled(notification.UserID, notification.Type, channel)
+	if err != nil || !enabled {
+		return false, err
+	}
+
+	if targetType, ok := muteTargetFor(notification.Type); ok && notification.TargetID != 0 {
+		muted, err := s.prefRepo.IsMuted(notification.UserID, targetType, notification.TargetID)
+		if err != nil || muted {
+			return false, err
+		}
+	}
+
+	if channel == model.ChannelInApp {
+		return true, nil
+	}
+
+	quietHours, err := s.prefRepo.GetQuietHours(notification.UserID)
+	if err != nil {
+		return false, err
+	}
+	return !inQuietHours(quietHours, now), nil
+}
+
+func (s *NotificationService) GetSettings(userID int64) (*model.NotificationSettings, error) {
+	preferences, err := s.prefRepo.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	mutes, err := s.prefRepo.GetMutes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quietHours, err := s.prefRepo.GetQuietHours(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := s.prefRepo.GetDigestFrequency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.NotificationSettings{
+		Preferences: preferences,
+		Mutes:       mutes,
+		QuietHours:  quietHours,
+		EmailDigest: digest,
+	}, nil
+}
+
+func (s *NotificationService) UpdateSettings(userID int64, update *model.NotificationSettingsUpdate) (*model.NotificationSettings, error) {
+	for _, preference := range update.Preferences {
+		if !isValidNotificationType(preference.Type) {
+			return nil, errors.New("invalid notification type")
+		}
+		if !isValidChannel(preference.Channel) {
+			return nil, errors.New("invalid notification channel")
+		}
+	}
+
+	if update.QuietHours != nil && (update.QuietHours.Start != "" || update.QuietHours.End != "") {
+		if err := validateQuietHours(update.QuietHours); err != nil {
+			return nil, err
+		}
+	}
+
+	switch update.EmailDigest {
+	case "", model.DigestOff, model.DigestDaily, model.DigestWeekly:
+	default:
+		return nil, errors.New("invalid email digest frequency")
+	}
+
+	for _, preference := range update.Preferences {
+		if err := s.prefRepo.SetPreference(userID, preference); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.QuietHours != nil {
+		var err error
+		if update.QuietHours.Start == "" && update.QuietHours.End == "" {
+			err = s.prefRepo.DeleteQuietHours(userID)
+		} else {
+			err = s.prefRepo.SetQuietHours(userID, update.QuietHours)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if update.EmailDigest != "" {
+		if err := s.prefRepo.SetDigestFrequency(userID, update.EmailDigest); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetSettings(userID)
+}
+
+func (s *NotificationService) Mute(userID int64, create *model.NotificationMuteCreate) error {
+	if !isValidMuteTarget(create.TargetType) {
+		return errors.New("invalid mute target type")
+	}
+	if create.TargetID <= 0 {
+		return errors.New("invalid target ID")
+	}
+	return s.prefRepo.Mute(userID, create.TargetType, create.TargetID)
+}
+
+func (s *NotificationService) Unmute(userID int64, targetType model.MuteTargetType, targetID int64) error {
+	if !isValidMuteTarget(targetType) {
+		return errors.New("invalid mute target type")
+	}
+	return s.prefRepo.Unmute(userID, targetType, targetID)
+}
+
+func isAggregatable(notifType model.NotificationType) bool {
+	switch notifType {
+	case model.NotificationLike, model.NotificationComment, model.NotificationMessage, model.NotificationShare:
+		return true
+	}
+	return false
+}
+
+func formatAggregateMessage(notifType model.NotificationType, actors []*model.NotificationActor, count int) string {
+	var action string
+	switch notifType {
+	case model.NotificationLike:
+		action = "reacted to your post"
+	case model.NotificationComment:
+		action = "commented on your post"
+	case model.NotificationShare:
+		action = "shared your post"
+	case model.NotificationMessage:
+		action = "sent you a message"
+	}
+
+	switch {
+	case count <= 1:
+		return fmt.Sprintf("%s %s", actors[0].Username, action)
+	case count == 2 && len(actors) > 1:
+		return fmt.Sprintf("%s and %s %s", actors[0].Username, actors[1].Username, action)
+	case count == 2:
+		return fmt.Sprintf("%s and 1 other %s", actors[0].Username, action)
+	default:
+		return fmt.Sprintf("%s and %d others %s", actors[0].Username, count-1, action)
+	}
+}
+
+func muteTargetFor(notifType model.NotificationType) (model.MuteTargetType, bool) {
+	switch notifType {
+	case model.NotificationLike, model.NotificationComment, model.NotificationMention, model.NotificationShare,
+		model.NotificationPollClosed:
+		return model.MuteTargetPost, true
+	case model.NotificationMessage:
+		return model.MuteTargetConversation, true
+	case model.NotificationGroupInvite:
+		return model.MuteTargetGroup, true
+	default:
+		return "", false
+	}
+}
+
+func isValidNotificationType(notifType model.NotificationType) bool {
+	switch notifType {
+	case model.NotificationFriendRequest, model.NotificationLike, model.NotificationComment,
+		model.NotificationMessage, model.NotificationGroupInvite, model.NotificationMention,
+		model.NotificationFollow, model.NotificationFollowRequest, model.NotificationShare,
+		model.NotificationPollClosed:
+		return true
+	}
+	return false
+}
+
+func isValidChannel(channel model.NotificationChannel) bool {
+	switch channel {
+	case model.ChannelInApp, model.ChannelEmail, model.ChannelPush:
+		return true
+	}
+	return false
+}
+
+func isValidMuteTarget(targetType model.MuteTargetType) bool {
+	switch targetType {
+	case model.MuteTargetPost, model.MuteTargetConversation, model.MuteTargetGroup:
+		return true
+	}
+	return false
+}
+
+func validateQuietHours(quietHours *model.QuietHours) error {
+	if _, err := time.Parse(quietHoursLayout, quietHours.Start); err != nil {
+		return errors.New("quiet hours start must be HH:MM")
+	}
+	if _, err := time.Parse(quietHoursLayout, quietHours.End); err != nil {
+		return errors.New("quiet hours end must be HH:MM")
+	}
+	if quietHours.Timezone == "" {
+		quietHours.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(quietHours.Timezone); err != nil {
+		return errors.New("invalid timezone")
+	}
+	return nil
+}
+
+func inQuietHours(quietHours *model.QuietHours, now time.Time) bool {
+	if quietHours == nil {
+		return false
+	}
+
+	location, err := time.LoadLocation(quietHours.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	start, err := time.Parse(quietHoursLayout, quietHours.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(quietHoursLayout, quietHours.End)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	return minute >= startMinute || minute < endMinute
 }
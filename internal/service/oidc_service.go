@@ -0,0 +1,324 @@
+package service
+
+import (
+	"errors"
+	"socialnet/internal/model"
+	"socialnet/internal/oidc"
+	"socialnet/internal/repository"
+	"socialnet/internal/security"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+type OIDCService struct {
+	providers        []*oidc.Provider
+	identityRepo     *repository.IdentityRepository
+	userRepo         *repository.UserRepository
+	twoFactorService *TwoFactorService
+	passwordHasher   *security.PasswordHasher
+}
+
+func NewOIDCService(providers []*oidc.Provider, identityRepo *repository.IdentityRepository,
+	userRepo *repository.UserRepository, twoFactorService *TwoFactorService,
+	passwordHasher *security.PasswordHasher) *OIDCService {
+	return &OIDCService{
+		providers:        providers,
+		identityRepo:     identityRepo,
+		userRepo:         userRepo,
+		twoFactorService: twoFactorService,
+		passwordHasher:   passwordHasher,
+	}
+}
+
+func (s *OIDCService) GetProviders() []*model.OIDCProviderInfo {
+	providers := make([]*model.OIDCProviderInfo, 0, len(s.providers))
+	for _, provider := range s.providers {
+		providers = append(providers, &model.OIDCProviderInfo{ID: provider.ID, Name: provider.Name})
+	}
+	return providers
+}
+
+// Begin starts an authorization code flow with PKCE. userID is
+// zero for a login and the current user when linking a provider.
+func (s *OIDCService) Begin(providerID string, userID int64) (*model.OIDCAuthorization, error) {
+	provider, err := s.provider(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _, err := security.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, _, err := security.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, _, err := security.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := provider.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := &model.OIDCState{
+		State:        state,
+		Provider:     provider.ID,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       userID,
+	}
+	if err := s.identityRepo.CreateState(pending, oidcStateTTL); err != nil {
+		return nil, err
+	}
+
+	return &model.OIDCAuthorization{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+		ExpiresAt:        time.Now().Add(oidcStateTTL),
+	}, nil
+}
+
+// Complete handles the provider's redirect back. Depending on the stored
+// state it links the identity to the user who started the flow, or signs in
+// the linked user, creating an account on first sign-in.
+func (s *OIDCService) Complete(callback *model.OIDCCallback) (*model.OIDCResult, error) {
+	if callback.Error != "" {
+		if callback.ErrorDescription != "" {
+			return nil, errors.New(callback.Error + ": " + callback.ErrorDescription)
+		}
+		return nil, errors.New(callback.Error)
+	}
+	if callback.Code == "" || callback.State == "" {
+		return nil, errors.New("code and state are required")
+	}
+
+	pending, err := s.identityRepo.ConsumeState(callback.State)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.provider(pending.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.Exchange(callback.Code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending.UserID != 0 {
+		return s.link(provider, pending.UserID, claims)
+	}
+	return s.login(provider, claims)
+}
+
+func (s *OIDCService) GetIdentities(userID int64) ([]*model.Identity, error) {
+	return s.identityRepo.GetByUser(userID)
+}
+
+// Unlink refuses to remove the last way into an account that was created
+// through a provider and never had a password set.
+func (s *OIDCService) Unlink(userID, identityID int64) error {
+	hasPassword, err := s.userRepo.HasPassword(userID)
+	if err != nil {
+		return err
+	}
+	if !hasPassword {
+		count, err := s.identityRepo.CountByUser(userID)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return errors.New("set a password before unlinking your last sign-in provider")
+		}
+	}
+
+	return s.identityRepo.Delete(identityID, userID)
+}
+
+func (s *OIDCService) link(provider *oidc.Provider, userID int64, claims *oidc.Claims) (*model.OIDCResult, error) {
+	existing, err := s.identityRepo.GetBySubject(provider.ID, claims.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, errors.New("this identity is already linked to another account")
+		}
+		return &model.OIDCResult{Identity: existing, Linked: true}, nil
+	}
+	if !errors.Is(err, repository.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	linked, err := s.identityRepo.GetByUserAndProvider(userID, provider.ID)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		return nil, errors.New("an identity from this provider is already linked to your account")
+	}
+
+	identity := &model.Identity{
+		UserID:   userID,
+		Provider: provider.ID,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	id, err := s.identityRepo.Create(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	identity.ID = id
+	identity.CreatedAt = time.Now().UTC()
+	return &model.OIDCResult{Identity: identity, Linked: true}, nil
+}
+
+func (s *OIDCService) login(provider *oidc.Provider, claims *oidc.Claims) (*model.OIDCResult, error) {
+	var user *model.User
+
+	identity, err := s.identityRepo.GetBySubject(provider.ID, claims.Subject)
+	switch {
+	case err == nil:
+		user, err = s.userRepo.GetByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.identityRepo.TouchLogin(identity.ID, claims.Email); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, repository.ErrIdentityNotFound):
+		user, err = s.createUser(provider, claims)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	challenge, err := s.twoFactorService.ChallengeFor(user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return &model.OIDCResult{Challenge: challenge}, nil
+	}
+	if err := restoreOnLogin(s.userRepo, user); err != nil {
+		return nil, err
+	}
+	return &model.OIDCResult{User: user}, nil
+}
+
+// createUser registers a new account on first sign-in. An existing account
+// with the same email is never taken over; its owner has to link the
+// provider from their settings instead.
+func (s *OIDCService) createUser(provider *oidc.Provider, claims *oidc.Claims) (*model.User, error) {
+	if claims.Email == "" {
+		return nil, errors.New("the identity provider did not share an email address")
+	}
+	if _, err := s.userRepo.GetByEmail(claims.Email); err == nil {
+		return nil, errors.New("an account with this email already exists; log in and link this provider from your settings")
+	}
+
+	username, err := s.availableUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account gets a random password nobody knows; the owner can set a
+	// real one through the password reset flow.
+	randomPassword, _, err := security.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.passwordHasher.Hash(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Email:        claims.Email,
+		Username:     username,
+		PasswordHash: hashedPassword,
+		FullName:     claims.Name,
+	}
+	id, err := s.userRepo.Create(user)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = id
+
+	if err := s.userRepo.MarkPasswordUnset(id); err != nil {
+		return nil, err
+	}
+	if claims.EmailVerified {
+		if err := s.userRepo.SetEmailVerified(id); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.identityRepo.Create(&model.Identity{
+		UserID:   id,
+		Provider: provider.ID,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetByID(id)
+}
+
+// availableUsername derives a valid username from the provider's claims and
+// appends a number when it is taken.
+func (s *OIDCService) availableUsername(claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	var b strings.Builder
+	for _, char := range base {
+		switch {
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9', char == '_':
+			b.WriteRune(char)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	username := b.String()
+	if len(username) > 25 {
+		username = username[:25]
+	}
+	if len(username) < 3 || security.IsReservedUsername(username) {
+		username = "user_" + username
+	}
+
+	candidate := username
+	for i := 2; i < 100; i++ {
+		taken, err := s.userRepo.IsUsernameTaken(candidate, 0)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = username + strconv.Itoa(i)
+	}
+	return "", errors.New("could not find a free username")
+}
+
+func (s *OIDCService) provider(id string) (*oidc.Provider, error) {
+	for _, provider := range s.providers {
+		if provider.ID == id {
+			return provider, nil
+		}
+	}
+	return nil, errors.New("unknown identity provider")
+}
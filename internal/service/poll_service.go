@@ -0,0 +1,274 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 10
+	maxPollOptionLength = 100
+	pollCloseBatchSize  = 50
+)
+
+type PollService struct {
+	pollRepo       *repository.PollRepository
+	postRepo       *repository.PostRepository
+	userRepo       *repository.UserRepository
+	privacyService *PrivacyService
+	notifQueue     chan *model.Notification
+}
+
+func NewPollService(pollRepo *repository.PollRepository, postRepo *repository.PostRepository,
+	userRepo *repository.UserRepository, privacyService *PrivacyService,
+	notifQueue chan *model.Notification) *PollService {
+	return &PollService{
+		pollRepo:       pollRepo,
+		postRepo:       postRepo,
+		userRepo:       userRepo,
+		privacyService: privacyService,
+		notifQueue:     notifQueue,
+	}
+}
+
+// GetPoll returns the poll of a post the viewer can see.
+func (s *PollService) GetPoll(postID, viewerID int64) (*model.Poll, error) {
+	post, poll, err := s.visiblePoll(postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := showPoll(s.pollRepo, poll, post, viewerID, true); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// Vote records the user's choice, replacing any earlier vote on the poll.
+func (s *PollService) Vote(postID, userID int64, vote *model.PollVote) (*model.Poll, error) {
+	if err := requireVerified(s.userRepo, userID); err != nil {
+		return nil, err
+	}
+	post, poll, err := s.visiblePoll(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPollOpen(post, poll); err != nil {
+		return nil, err
+	}
+
+	optionIDs, err := pollChoice(poll, vote.OptionIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pollRepo.SetVotes(poll.ID, userID, optionIDs); err != nil {
+		return nil, err
+	}
+
+	return s.GetPoll(postID, userID)
+}
+
+// RemoveVote withdraws the user's vote while the poll is open.
+func (s *PollService) RemoveVote(postID, userID int64) (*model.Poll, error) {
+	post, poll, err := s.visiblePoll(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPollOpen(post, poll); err != nil {
+		return nil, err
+	}
+
+	removed, err := s.pollRepo.DeleteVotes(poll.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, errors.New("not voted")
+	}
+
+	return s.GetPoll(postID, userID)
+}
+
+// NotifyClosedPolls tells the authors of polls that have ended. Each poll
+// is announced once.
+func (s *PollService) NotifyClosedPolls() error {
+	postIDs, err := s.pollRepo.GetClosedUnnotified(pollCloseBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, postID := range postIDs {
+		if err := s.notifyClosed(postID); err != nil {
+			log.Printf("Failed to close poll of post %d: %v", postID, err)
+		}
+	}
+	return nil
+}
+
+func (s *PollService) notifyClosed(postID int64) error {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return err
+	}
+	poll, err := s.pollRepo.GetByPostID(postID)
+	if err != nil || poll == nil {
+		return err
+	}
+
+	marked, err := s.pollRepo.MarkClosedNotified(poll.ID)
+	if err != nil || !marked {
+		return err
+	}
+
+	message := "Your poll has ended"
+	switch *poll.VoterCount {
+	case 0:
+		message += " without any votes"
+	case 1:
+		message += " with 1 vote"
+	default:
+		message += " with " + strconv.Itoa(*poll.VoterCount) + " votes"
+	}
+
+	s.notifQueue <- &model.Notification{
+		UserID:   post.UserID,
+		Type:     model.NotificationPollClosed,
+		TargetID: post.ID,
+		Message:  message,
+	}
+	return nil
+}
+
+func (s *PollService) visiblePoll(postID, viewerID int64) (*model.Post, *model.Poll, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := requireActive(s.userRepo, post.UserID); err != nil {
+		return nil, nil, ErrPostNotFound
+	}
+	if err := s.privacyService.CheckPostVisible(post, viewerID); err != nil {
+		return nil, nil, err
+	}
+
+	poll, err := s.pollRepo.GetByPostID(postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if poll == nil {
+		return nil, nil, ErrNoPoll
+	}
+	return post, poll, nil
+}
+
+func checkPollOpen(post *model.Post, poll *model.Poll) error {
+	if post.Status != model.PostPublished {
+		return errors.New("poll is not open until the post is published")
+	}
+	if poll.Closed {
+		return errors.New("poll is closed")
+	}
+	return nil
+}
+
+// pollChoice checks the chosen options against the poll and drops
+// duplicates.
+func pollChoice(poll *model.Poll, optionIDs []int64) ([]int64, error) {
+	valid := make(map[int64]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		valid[option.ID] = true
+	}
+
+	var choice []int64
+	chosen := make(map[int64]bool)
+	for _, id := range optionIDs {
+		if !valid[id] {
+			return nil, errors.New("invalid poll option")
+		}
+		if !chosen[id] {
+			chosen[id] = true
+			choice = append(choice, id)
+		}
+	}
+
+	switch {
+	case len(choice) == 0:
+		return nil, errors.New("choose at least one option")
+	case len(choice) > 1 && !poll.MultipleChoice:
+		return nil, errors.New("this poll allows only one choice")
+	}
+	return choice, nil
+}
+
+// validatePoll checks a new poll. It must end after the post is published,
+// which is now unless publishAt is set.
+func validatePoll(create *model.PollCreate, publishAt *time.Time) error {
+	if len(create.Options) < minPollOptions || len(create.Options) > maxPollOptions {
+		return errors.New("a poll needs between 2 and 10 options")
+	}
+
+	seen := make(map[string]bool)
+	for i, option := range create.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return errors.New("poll options cannot be empty")
+		}
+		if utf8.RuneCountInString(option) > maxPollOptionLength {
+			return errors.New("poll options can be at most 100 characters")
+		}
+		if seen[strings.ToLower(option)] {
+			return errors.New("poll options must be different")
+		}
+		seen[strings.ToLower(option)] = true
+		create.Options[i] = option
+	}
+
+	if create.EndsAt != nil {
+		start := time.Now()
+		if publishAt != nil {
+			start = *publishAt
+		}
+		if !create.EndsAt.After(start) {
+			return errors.New("a poll must end after the post is published")
+		}
+	}
+	return nil
+}
+
+// showPoll fills in what the viewer may see of the poll: their own votes
+// always, the results once they are visible, and with withVoters, who voted
+// for each option unless the poll is anonymous.
+func showPoll(pollRepo *repository.PollRepository, poll *model.Poll, post *model.Post,
+	viewerID int64, withVoters bool) error {
+	myVotes, err := pollRepo.GetUserVotes(poll.ID, viewerID)
+	if err != nil {
+		return err
+	}
+	poll.MyVotes = myVotes
+
+	poll.ResultsVisible = !poll.HideResults || poll.Closed || len(myVotes) > 0 || post.UserID == viewerID
+	if !poll.ResultsVisible {
+		poll.VoterCount = nil
+		for _, option := range poll.Options {
+			option.Votes = nil
+		}
+		return nil
+	}
+
+	if !withVoters || poll.Anonymous {
+		return nil
+	}
+	for _, option := range poll.Options {
+		option.Voters, err = pollRepo.GetVoters(option.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
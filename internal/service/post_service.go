@@ -2,23 +2,42 @@ package service
 
 import (
 	"errors"
+	"log"
 	"socialnet/internal/model"
 	"socialnet/internal/repository"
 	"socialnet/internal/security"
+	"time"
+)
+
+const (
+	defaultPostsPerPage = 20
+	maxPostsPerPage     = 50
+	publishBatchSize    = 50
+	maxScheduleAhead    = 365 * 24 * time.Hour
 )
 
 type PostService struct {
-	postRepo *repository.PostRepository
-	likeRepo *repository.LikeRepository
-	userRepo *repository.UserRepository
+	postRepo       *repository.PostRepository
+	likeRepo       *repository.LikeRepository
+	pollRepo       *repository.PollRepository
+	bookmarkRepo   *repository.BookmarkRepository
+	userRepo       *repository.UserRepository
+	privacyService *PrivacyService
+	notifQueue     chan *model.Notification
 }
 
 func NewPostService(postRepo *repository.PostRepository, likeRepo *repository.LikeRepository,
-	userRepo *repository.UserRepository) *PostService {
+	pollRepo *repository.PollRepository, bookmarkRepo *repository.BookmarkRepository,
+	userRepo *repository.UserRepository, privacyService *PrivacyService,
+	notifQueue chan *model.Notification) *PostService {
 	return &PostService{
-		postRepo: postRepo,
-		likeRepo: likeRepo,
-		userRepo: userRepo,
+		postRepo:       postRepo,
+		likeRepo:       likeRepo,
+		pollRepo:       pollRepo,
+		bookmarkRepo:   bookmarkRepo,
+		userRepo:       userRepo,
+		privacyService: privacyService,
+		notifQueue:     notifQueue,
 	}
 }
 
@@ -26,40 +45,288 @@ func (s *PostService) CreatePost(userID int64, create *model.PostCreate) (*model
 	if err := security.ValidateContent(create.Content, 5000); err != nil {
 		return nil, err
 	}
+	if err := requireVerified(s.userRepo, userID); err != nil {
+		return nil, err
+	}
 
 	post := &model.Post{
-		UserID:   userID,
-		Content:  create.Content,
-		MediaURL: create.MediaURL,
+		UserID:    userID,
+		Content:   create.Content,
+		MediaURL:  create.MediaURL,
+		Status:    model.PostPublished,
+		PublishAt: create.PublishAt,
+	}
+	switch {
+	case create.Draft && create.PublishAt != nil:
+		return nil, errors.New("a draft cannot have a publish time")
+	case create.Draft:
+		post.Status = model.PostDraft
+	case create.PublishAt != nil:
+		if err := validatePublishAt(create.PublishAt); err != nil {
+			return nil, err
+		}
+		post.Status = model.PostScheduled
+	}
+	if create.Poll != nil {
+		if err := validatePoll(create.Poll, create.PublishAt); err != nil {
+			return nil, err
+		}
 	}
 
-	id, err := s.postRepo.Create(post)
+	id, err := s.createPost(post, create.Poll)
 	if err != nil {
 		return nil, err
 	}
 
 	post.ID = id
+	if post.Status == model.PostPublished {
+		notifyMentions(s.userRepo, s.notifQueue, userID, id, create.Content, "a post")
+	}
+
 	return s.GetPost(id, userID)
 }
 
-func (s *PostService) GetPost(postID, currentUserID int64) (*model.Post, error) {
+// createPost stores the post and the poll it carries, if any. A post whose
+// poll could not be stored is removed again.
+func (s *PostService) createPost(post *model.Post, poll *model.PollCreate) (int64, error) {
+	id, err := s.postRepo.Create(post)
+	if err != nil || poll == nil {
+		return id, err
+	}
+
+	if _, err := s.pollRepo.Create(id, poll); err != nil {
+		if err := s.postRepo.Delete(id); err != nil {
+			log.Printf("Failed to remove post %d after its poll failed: %v", id, err)
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetDrafts returns the user's drafts and scheduled posts.
+func (s *PostService) GetDrafts(userID int64) ([]*model.Post, error) {
+	posts, err := s.postRepo.GetUnpublished(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.showPosts(posts, userID), nil
+}
+
+// SchedulePost sets when a draft or scheduled post is published. A nil
+// publish time cancels the schedule and keeps the post as a draft.
+func (s *PostService) SchedulePost(postID, userID int64, schedule *model.PostSchedule) (*model.Post, error) {
+	post, err := s.unpublishedPost(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.PublishAt != nil {
+		if err := validatePublishAt(schedule.PublishAt); err != nil {
+			return nil, err
+		}
+		if err := s.extendPollEnd(post, *schedule.PublishAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.postRepo.Schedule(post.ID, schedule.PublishAt); err != nil {
+		return nil, err
+	}
+	return s.GetPost(post.ID, userID)
+}
+
+// PublishPost publishes a draft or scheduled post right away.
+func (s *PostService) PublishPost(postID, userID int64) (*model.Post, error) {
+	post, err := s.unpublishedPost(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.extendPollEnd(post, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.publish(post); err != nil {
+		return nil, err
+	}
+	return s.GetPost(post.ID, userID)
+}
+
+// PublishDuePosts publishes every scheduled post whose time has come.
+func (s *PostService) PublishDuePosts() error {
+	posts, err := s.postRepo.GetDueScheduled(publishBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		if err := s.publish(post); err != nil {
+			log.Printf("Failed to publish post %d: %v", post.ID, err)
+		}
+	}
+	return nil
+}
+
+// publish makes the post public. Mentions are only notified now, as the
+// mentioned users could not see the post before.
+func (s *PostService) publish(post *model.Post) error {
+	published, err := s.postRepo.Publish(post.ID)
+	if err != nil || !published {
+		return err
+	}
+	notifyMentions(s.userRepo, s.notifQueue, post.UserID, post.ID, post.Content, "a post")
+	return nil
+}
+
+func (s *PostService) unpublishedPost(postID, userID int64) (*model.Post, error) {
 	post, err := s.postRepo.GetByID(postID)
 	if err != nil {
 		return nil, err
 	}
+	if post.UserID != userID {
+		return nil, errors.New("unauthorized")
+	}
+	if post.Status == model.PostPublished {
+		return nil, errors.New("post is already published")
+	}
+	return post, nil
+}
 
-	author, _ := s.userRepo.GetByID(post.UserID)
-	post.Author = author
+// extendPollEnd makes sure the post's poll, if any, is still open when the
+// post is published at publishAt. Drafts have no publish time to check the
+// end against, so a poll that would be over by then is moved to run as long
+// after publishAt as it was meant to run after the post was written.
+func (s *PostService) extendPollEnd(post *model.Post, publishAt time.Time) error {
+	poll, err := s.pollRepo.GetByPostID(post.ID)
+	if err != nil {
+		return err
+	}
+	if poll == nil || poll.EndsAt == nil || poll.EndsAt.After(publishAt) {
+		return nil
+	}
+	return s.pollRepo.SetEndsAt(poll.ID, publishAt.Add(poll.EndsAt.Sub(post.CreatedAt)))
+}
 
-	count, _ := s.likeRepo.GetCountByPostID(postID)
-	post.LikeCount = count
+func validatePublishAt(publishAt *time.Time) error {
+	if !publishAt.After(time.Now()) {
+		return errors.New("publish_at must be in the future")
+	}
+	if publishAt.After(time.Now().Add(maxScheduleAhead)) {
+		return errors.New("posts can be scheduled at most a year ahead")
+	}
+	return nil
+}
 
-	liked, _ := s.likeRepo.HasUserLiked(postID, currentUserID)
-	post.Liked = liked
+func (s *PostService) GetPost(postID, currentUserID int64) (*model.Post, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.privacyService.CheckPostVisible(post, currentUserID); err != nil {
+		return nil, err
+	}
+
+	if !s.showPost(post, currentUserID) {
+		return nil, ErrPostNotFound
+	}
 
 	return post, nil
 }
 
+// Repost shares a post as it is. Reposting a repost shares its original.
+func (s *PostService) Repost(postID, userID int64) (*model.Post, error) {
+	if err := requireVerified(s.userRepo, userID); err != nil {
+		return nil, err
+	}
+	original, err := s.shareable(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	repostID, err := s.postRepo.GetRepostID(userID, original.ID)
+	if err != nil {
+		return nil, err
+	}
+	if repostID != 0 {
+		return nil, errors.New("already reposted")
+	}
+
+	post := &model.Post{
+		UserID:       userID,
+		SharedPostID: original.ID,
+		ShareType:    model.ShareRepost,
+		Status:       model.PostPublished,
+	}
+
+	id, err := s.postRepo.Create(post)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyShare(original, userID, "reposted your post")
+
+	return s.GetPost(id, userID)
+}
+
+// QuotePost shares a post with the user's commentary.
+func (s *PostService) QuotePost(postID, userID int64, create *model.PostCreate) (*model.Post, error) {
+	if err := security.ValidateContent(create.Content, 5000); err != nil {
+		return nil, err
+	}
+	if create.Draft || create.PublishAt != nil {
+		return nil, errors.New("quotes are published right away")
+	}
+	if create.Poll != nil {
+		if err := validatePoll(create.Poll, nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := requireVerified(s.userRepo, userID); err != nil {
+		return nil, err
+	}
+	original, err := s.shareable(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	post := &model.Post{
+		UserID:       userID,
+		Content:      create.Content,
+		MediaURL:     create.MediaURL,
+		SharedPostID: original.ID,
+		ShareType:    model.ShareQuote,
+		Status:       model.PostPublished,
+	}
+
+	id, err := s.createPost(post, create.Poll)
+	if err != nil {
+		return nil, err
+	}
+
+	notifyMentions(s.userRepo, s.notifQueue, userID, id, create.Content, "a post")
+	s.notifyShare(original, userID, "quoted your post")
+
+	return s.GetPost(id, userID)
+}
+
+// Unrepost removes the user's repost of a post. The post can be given by
+// its own ID or by the ID of the repost.
+func (s *PostService) Unrepost(postID, userID int64) error {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return err
+	}
+	if post.ShareType == model.ShareRepost {
+		postID = post.SharedPostID
+	}
+
+	repostID, err := s.postRepo.GetRepostID(userID, postID)
+	if err != nil {
+		return err
+	}
+	if repostID == 0 {
+		return errors.New("not reposted")
+	}
+	return s.postRepo.Delete(repostID)
+}
+
 func (s *PostService) UpdatePost(postID, userID int64, update *model.PostUpdate) error {
 	if err := security.ValidateContent(update.Content, 5000); err != nil {
 		return err
@@ -73,13 +340,53 @@ func (s *PostService) UpdatePost(postID, userID int64, update *model.PostUpdate)
 	if post.UserID != userID {
 		return errors.New("unauthorized")
 	}
+	if post.ShareType == model.ShareRepost {
+		return errors.New("reposts cannot be edited")
+	}
+	if post.Content == update.Content && post.MediaURL == update.MediaURL {
+		return nil
+	}
 
 	post.Content = update.Content
 	post.MediaURL = update.MediaURL
 
+	if post.Status != model.PostPublished {
+		return s.postRepo.UpdateDraft(post)
+	}
+
 	return s.postRepo.Update(post)
 }
 
+// GetRevisions returns the earlier versions of a post, oldest first, each
+// with the changes the following edit made. Admins can see the history of
+// any post.
+func (s *PostService) GetRevisions(postID, viewerID int64, isAdmin bool) ([]*model.PostRevision, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		if err := s.privacyService.CheckPostVisible(post, viewerID); err != nil {
+			return nil, err
+		}
+	}
+
+	revisions, err := s.postRepo.GetRevisions(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, revision := range revisions {
+		next := post.Content
+		if i+1 < len(revisions) {
+			next = revisions[i+1].Content
+		}
+		revision.Diff = diffWords(revision.Content, next)
+	}
+
+	return revisions, nil
+}
+
 func (s *PostService) DeletePost(postID, userID int64, isAdmin bool) error {
 	post, err := s.postRepo.GetByID(postID)
 	if err != nil {
@@ -93,22 +400,163 @@ func (s *PostService) DeletePost(postID, userID int64, isAdmin bool) error {
 	return s.postRepo.Delete(postID)
 }
 
+// GetUserPosts returns a page of the user's timeline, if they let the viewer
+// see their posts.
+func (s *PostService) GetUserPosts(userID, viewerID, beforeID int64, limit int) ([]*model.Post, error) {
+	if err := requireActive(s.userRepo, userID); err != nil {
+		return nil, err
+	}
+
+	canView, err := s.privacyService.CanViewPosts(userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		return nil, errors.New("this user's posts are private")
+	}
+
+	if limit <= 0 || limit > maxPostsPerPage {
+		limit = defaultPostsPerPage
+	}
+	posts, err := s.postRepo.GetUserPosts(userID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.showPosts(posts, viewerID), nil
+}
+
 func (s *PostService) GetFeed(userID int64) ([]*model.Post, error) {
 	posts, err := s.postRepo.GetFeed(userID, 50)
 	if err != nil {
 		return nil, err
 	}
 
+	return s.showPosts(posts, userID), nil
+}
+
+// showPosts fills in the posts for the viewer, leaving out reposts of posts
+// they cannot see.
+func (s *PostService) showPosts(posts []*model.Post, viewerID int64) []*model.Post {
+	shown := make([]*model.Post, 0, len(posts))
 	for _, post := range posts {
-		author, _ := s.userRepo.GetByID(post.UserID)
-		post.Author = author
+		if s.showPost(post, viewerID) {
+			shown = append(shown, post)
+		}
+	}
+	return shown
+}
+
+// showPost fills in the author, reactions, shares, poll, bookmark and shared
+// post for the viewer. It reports false for a repost whose original the viewer cannot
+// see, as there is nothing left to show.
+func (s *PostService) showPost(post *model.Post, viewerID int64) bool {
+	post.Author = publicUser(s.userRepo, post.UserID)
+	s.showReactions(post, viewerID)
+	s.showShares(post, viewerID)
+	s.showPoll(post, viewerID)
+	post.Bookmarked, _ = s.bookmarkRepo.IsBookmarked(viewerID, post.ID)
+	post.Edited, _ = s.postRepo.HasRevisions(post.ID)
+
+	if post.ShareType == "" {
+		return true
+	}
+
+	original, err := s.postRepo.GetByID(post.SharedPostID)
+	if err != nil || requireActive(s.userRepo, original.UserID) != nil ||
+		s.privacyService.CheckPostVisible(original, viewerID) != nil {
+		post.SharedPostUnavailable = true
+		return post.ShareType != model.ShareRepost
+	}
+
+	original.Author = publicUser(s.userRepo, original.UserID)
+	s.showReactions(original, viewerID)
+	s.showShares(original, viewerID)
+	s.showPoll(original, viewerID)
+	original.Bookmarked, _ = s.bookmarkRepo.IsBookmarked(viewerID, original.ID)
+	original.Edited, _ = s.postRepo.HasRevisions(original.ID)
+	post.SharedPost = original
+	return true
+}
+
+// showPoll attaches the post's poll, if it has one. Voters are only listed
+// by PollService.GetPoll.
+func (s *PostService) showPoll(post *model.Post, viewerID int64) {
+	poll, err := s.pollRepo.GetByPostID(post.ID)
+	if err != nil || poll == nil {
+		return
+	}
+	if showPoll(s.pollRepo, poll, post, viewerID, false) == nil {
+		post.Poll = poll
+	}
+}
+
+func (s *PostService) showShares(post *model.Post, viewerID int64) {
+	reposts, quotes, _ := s.postRepo.CountShares(post.ID)
+	post.RepostCount = reposts
+	post.QuoteCount = quotes
+
+	repostID, _ := s.postRepo.GetRepostID(viewerID, post.ID)
+	post.Reposted = repostID != 0
+}
+
+// shareable returns the post a share of postID points at: the post itself,
+// or its original when it is a repost.
+func (s *PostService) shareable(postID, userID int64) (*model.Post, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.ShareType == model.ShareRepost {
+		post, err = s.postRepo.GetByID(post.SharedPostID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if post.Status != model.PostPublished {
+		return nil, ErrPostNotFound
+	}
+	if err := requireActive(s.userRepo, post.UserID); err != nil {
+		return nil, ErrPostNotFound
+	}
+	if err := s.privacyService.CheckPostVisible(post, userID); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (s *PostService) notifyShare(original *model.Post, userID int64, action string) {
+	if original.UserID == userID {
+		return
+	}
+
+	sharer, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return
+	}
+
+	s.notifQueue <- &model.Notification{
+		UserID:   original.UserID,
+		ActorID:  userID,
+		Type:     model.NotificationShare,
+		TargetID: original.ID,
+		Message:  sharer.Username + " " + action,
+	}
+}
 
-		count, _ := s.likeRepo.GetCountByPostID(post.ID)
-		post.LikeCount = count
+// showReactions fills in the post's reaction counts and the viewer's own
+// reaction.
+func (s *PostService) showReactions(post *model.Post, viewerID int64) {
+	counts, _ := s.likeRepo.GetReactionCounts(post.ID)
+	post.Reactions = counts
 
-		liked, _ := s.likeRepo.HasUserLiked(post.ID, userID)
-		post.Liked = liked
+	post.LikeCount = 0
+	for _, count := range counts {
+		post.LikeCount += count
 	}
 
-	return posts, nil
+	reaction, _ := s.likeRepo.GetReaction(post.ID, viewerID)
+	post.MyReaction = reaction
+	post.Liked = reaction != ""
 }
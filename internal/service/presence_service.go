@@ -0,0 +1,131 @@
+package service
+
+import (
+	"log"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"sync"
+	"time"
+)
+
+// PresenceService is the registry of who is online. Activity is kept in
+// memory and written to the user's last_seen_at at most once per
+// writeInterval, so requests do not each cost a database write. A user is
+// online while a connection they registered is open, e.g. a notification
+// stream, or for onlineWindow after their last request.
+type PresenceService struct {
+	userRepo       *repository.UserRepository
+	privacyService *PrivacyService
+	onlineWindow   time.Duration
+	writeInterval  time.Duration
+
+	mu          sync.Mutex
+	lastSeen    map[int64]time.Time
+	lastWritten map[int64]time.Time
+	connections map[int64]int
+}
+
+func NewPresenceService(userRepo *repository.UserRepository, privacyService *PrivacyService,
+	onlineWindow, writeInterval time.Duration) *PresenceService {
+	return &PresenceService{
+		userRepo:       userRepo,
+		privacyService: privacyService,
+		onlineWindow:   onlineWindow,
+		writeInterval:  writeInterval,
+		lastSeen:       make(map[int64]time.Time),
+		lastWritten:    make(map[int64]time.Time),
+		connections:    make(map[int64]int),
+	}
+}
+
+// Touch records activity by the user.
+func (s *PresenceService) Touch(userID int64) {
+	now := time.Now()
+
+	s.mu.Lock()
+	s.lastSeen[userID] = now
+	write := now.Sub(s.lastWritten[userID]) >= s.writeInterval
+	if write {
+		s.lastWritten[userID] = now
+	}
+	s.mu.Unlock()
+
+	if write {
+		s.persist(userID)
+	}
+}
+
+// Connect registers an open connection; the user stays online until every
+// connection is closed with Disconnect.
+func (s *PresenceService) Connect(userID int64) {
+	s.mu.Lock()
+	s.connections[userID]++
+	s.mu.Unlock()
+
+	s.Touch(userID)
+}
+
+func (s *PresenceService) Disconnect(userID int64) {
+	now := time.Now()
+
+	s.mu.Lock()
+	s.connections[userID]--
+	if s.connections[userID] <= 0 {
+		delete(s.connections, userID)
+	}
+	s.lastSeen[userID] = now
+	s.lastWritten[userID] = now
+	s.mu.Unlock()
+
+	s.persist(userID)
+}
+
+// ShowPresence fills in Online and LastSeenAt on the users whose presence
+// the viewer may see.
+func (s *PresenceService) ShowPresence(users []*model.User, viewerID int64) error {
+	for _, user := range users {
+		if user == nil {
+			continue
+		}
+		canView, err := s.privacyService.CanViewPresence(user.ID, viewerID)
+		if err != nil {
+			return err
+		}
+		if !canView {
+			continue
+		}
+
+		online, lastSeen, err := s.status(user.ID)
+		if err != nil {
+			return err
+		}
+		user.Online = &online
+		user.LastSeenAt = lastSeen
+	}
+	return nil
+}
+
+func (s *PresenceService) status(userID int64) (bool, *time.Time, error) {
+	s.mu.Lock()
+	connected := s.connections[userID] > 0
+	lastSeen, ok := s.lastSeen[userID]
+	s.mu.Unlock()
+
+	if !ok {
+		stored, err := s.userRepo.GetLastSeen(userID)
+		if err != nil || stored == nil {
+			return connected, nil, err
+		}
+		lastSeen = *stored
+	}
+
+	online := connected || time.Since(lastSeen) < s.onlineWindow
+	lastSeen = lastSeen.UTC().Truncate(time.Second)
+	return online, &lastSeen, nil
+}
+
+func (s *PresenceService) persist(userID int64) {
+	if err := s.userRepo.UpdateLastSeen(userID); err != nil {
+		log.Printf("Failed to update last seen time of user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"errors"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+)
+
+type PrivacyService struct {
+	privacyRepo *repository.PrivacyRepository
+	friendRepo  *repository.FriendshipRepository
+	followRepo  *repository.FollowRepository
+}
+
+func NewPrivacyService(privacyRepo *repository.PrivacyRepository, friendRepo *repository.FriendshipRepository,
+	followRepo *repository.FollowRepository) *PrivacyService {
+	return &PrivacyService{
+		privacyRepo: privacyRepo,
+		friendRepo:  friendRepo,
+		followRepo:  followRepo,
+	}
+}
+
+func (s *PrivacyService) GetSettings(userID int64) (*model.PrivacySettings, error) {
+	return s.privacyRepo.Get(userID)
+}
+
+func (s *PrivacyService) UpdateSettings(userID int64, update *model.PrivacySettingsUpdate) (*model.PrivacySettings, error) {
+	for _, audience := range []model.Audience{update.EmailVisibility, update.FriendsVisibility,
+		update.PostsVisibility, update.GroupsVisibility, update.LocationVisibility,
+		update.WebsiteVisibility, update.BirthdayVisibility, update.PresenceVisibility} {
+		if audience != "" && !isValidAudience(audience) {
+			return nil, errors.New("invalid audience: use everyone, friends or only_me")
+		}
+	}
+	switch update.FriendRequests {
+	case "", model.FriendRequestsEveryone, model.FriendRequestsFriendsOfFriends, model.FriendRequestsNobody:
+	default:
+		return nil, errors.New("invalid friend request setting: use everyone, friends_of_friends or nobody")
+	}
+
+	settings, err := s.privacyRepo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if update.EmailVisibility != "" {
+		settings.EmailVisibility = update.EmailVisibility
+	}
+	if update.FriendsVisibility != "" {
+		settings.FriendsVisibility = update.FriendsVisibility
+	}
+	if update.PostsVisibility != "" {
+		settings.PostsVisibility = update.PostsVisibility
+	}
+	if update.GroupsVisibility != "" {
+		settings.GroupsVisibility = update.GroupsVisibility
+	}
+	if update.LocationVisibility != "" {
+		settings.LocationVisibility = update.LocationVisibility
+	}
+	if update.WebsiteVisibility != "" {
+		settings.WebsiteVisibility = update.WebsiteVisibility
+	}
+	if update.BirthdayVisibility != "" {
+		settings.BirthdayVisibility = update.BirthdayVisibility
+	}
+	if update.PresenceVisibility != "" {
+		settings.PresenceVisibility = update.PresenceVisibility
+	}
+	if update.FriendRequests != "" {
+		settings.FriendRequests = update.FriendRequests
+	}
+	if update.Searchable != nil {
+		settings.Searchable = *update.Searchable
+	}
+	stopsApproving := update.ApproveFollowers != nil && settings.ApproveFollowers && !*update.ApproveFollowers
+	if update.ApproveFollowers != nil {
+		settings.ApproveFollowers = *update.ApproveFollowers
+	}
+
+	if err := s.privacyRepo.Set(userID, settings); err != nil {
+		return nil, err
+	}
+	// Nobody is left waiting once approval is no longer needed.
+	if stopsApproving {
+		if err := s.followRepo.AcceptAllPending(userID); err != nil {
+			return nil, err
+		}
+	}
+	return settings, nil
+}
+
+// Profile projects a user for the viewer, leaving out what the owner keeps
+// private from them.
+func (s *PrivacyService) Profile(user *model.User, details *model.ProfileDetails, viewerID int64) (*model.Profile, error) {
+	settings, err := s.privacyRepo.Get(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &model.Profile{
+		ID:           user.ID,
+		Username:     user.Username,
+		FullName:     user.FullName,
+		Bio:          user.Bio,
+		AvatarURL:    user.AvatarURL,
+		CoverURL:     details.CoverURL,
+		IsAdmin:      user.IsAdmin,
+		CreatedAt:    user.CreatedAt,
+		Relationship: model.RelationshipSelf,
+	}
+
+	if viewerID == user.ID {
+		verified := user.EmailVerified
+		profile.EmailVerified = &verified
+	}
+
+	if viewerID != user.ID {
+		friendship, err := s.friendRepo.GetBetween(user.ID, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		profile.Relationship = relationshipTo(friendship, viewerID)
+		if friendship != nil {
+			profile.FriendshipID = friendship.ID
+		}
+
+		following, err := s.followRepo.Get(viewerID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if following != nil {
+			profile.FollowStatus = following.Status
+		}
+		follower, err := s.followRepo.Get(user.ID, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		profile.FollowsYou = follower != nil && follower.Status == model.FollowAccepted
+	}
+	isFriend := profile.Relationship == model.RelationshipFriends
+
+	if audienceIncludes(settings.EmailVisibility, user.ID, viewerID, isFriend) {
+		profile.Email = user.Email
+	}
+	if audienceIncludes(settings.LocationVisibility, user.ID, viewerID, isFriend) {
+		profile.Location = details.Location
+	}
+	if audienceIncludes(settings.WebsiteVisibility, user.ID, viewerID, isFriend) {
+		profile.Website = details.Website
+	}
+	if audienceIncludes(settings.BirthdayVisibility, user.ID, viewerID, isFriend) {
+		profile.Birthday = details.Birthday
+	}
+	profile.CanViewFriends = audienceIncludes(settings.FriendsVisibility, user.ID, viewerID, isFriend)
+	profile.CanViewPosts = audienceIncludes(settings.PostsVisibility, user.ID, viewerID, isFriend)
+	profile.CanViewGroups = audienceIncludes(settings.GroupsVisibility, user.ID, viewerID, isFriend)
+
+	if profile.Relationship == model.RelationshipNone {
+		profile.CanSendFriendRequest, err = s.acceptsRequest(settings, user.ID, viewerID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return profile, nil
+}
+
+// CanViewFriends also covers the user's followers and the accounts they
+// follow.
+func (s *PrivacyService) CanViewFriends(ownerID, viewerID int64) (bool, error) {
+	return s.canView(ownerID, viewerID, func(settings *model.PrivacySettings) model.Audience {
+		return settings.FriendsVisibility
+	})
+}
+
+func (s *PrivacyService) CanViewPosts(ownerID, viewerID int64) (bool, error) {
+	return s.canView(ownerID, viewerID, func(settings *model.PrivacySettings) model.Audience {
+		return settings.PostsVisibility
+	})
+}
+
+func (s *PrivacyService) CanViewGroups(ownerID, viewerID int64) (bool, error) {
+	return s.canView(ownerID, viewerID, func(settings *model.PrivacySettings) model.Audience {
+		return settings.GroupsVisibility
+	})
+}
+
+// CanViewPresence reports whether the viewer may see if the user is online
+// and when they were last active.
+func (s *PrivacyService) CanViewPresence(ownerID, viewerID int64) (bool, error) {
+	return s.canView(ownerID, viewerID, func(settings *model.PrivacySettings) model.Audience {
+		return settings.PresenceVisibility
+	})
+}
+
+// CheckPostVisible reports a post the viewer may not see as not found.
+// Drafts and scheduled posts are only visible to their author.
+func (s *PrivacyService) CheckPostVisible(post *model.Post, viewerID int64) error {
+	if post.Status != model.PostPublished && post.UserID != viewerID {
+		return ErrPostNotFound
+	}
+
+	canView, err := s.CanViewPosts(post.UserID, viewerID)
+	if err != nil {
+		return err
+	}
+	if !canView {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// CheckFriendRequest returns an error if the addressee does not accept
+// friend requests from the requester.
+func (s *PrivacyService) CheckFriendRequest(addresseeID, requesterID int64) error {
+	settings, err := s.privacyRepo.Get(addresseeID)
+	if err != nil {
+		return err
+	}
+	accepts, err := s.acceptsRequest(settings, addresseeID, requesterID)
+	if err != nil {
+		return err
+	}
+	if !accepts {
+		return errors.New("this user is not accepting friend requests from you")
+	}
+	return nil
+}
+
+func (s *PrivacyService) canView(ownerID, viewerID int64, audience func(*model.PrivacySettings) model.Audience) (bool, error) {
+	if ownerID == viewerID {
+		return true, nil
+	}
+	settings, err := s.privacyRepo.Get(ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	switch audience(settings) {
+	case model.AudienceEveryone:
+		return true, nil
+	case model.AudienceFriends:
+		return s.friendRepo.AreFriends(ownerID, viewerID)
+	default:
+		return false, nil
+	}
+}
+
+func (s *PrivacyService) acceptsRequest(settings *model.PrivacySettings, addresseeID, requesterID int64) (bool, error) {
+	switch settings.FriendRequests {
+	case model.FriendRequestsEveryone:
+		return true, nil
+	case model.FriendRequestsFriendsOfFriends:
+		return s.friendRepo.HaveMutualFriend(addresseeID, requesterID)
+	default:
+		return false, nil
+	}
+}
+
+func relationshipTo(friendship *model.Friendship, viewerID int64) model.Relationship {
+	switch {
+	case friendship == nil:
+		return model.RelationshipNone
+	case friendship.Status == model.FriendshipAccepted:
+		return model.RelationshipFriends
+	case friendship.Status == model.FriendshipBlocked:
+		return model.RelationshipBlocked
+	case friendship.RequesterID == viewerID:
+		return model.RelationshipRequestSent
+	default:
+		return model.RelationshipRequestReceived
+	}
+}
+
+func audienceIncludes(audience model.Audience, ownerID, viewerID int64, isFriend bool) bool {
+	switch {
+	case ownerID == viewerID:
+		return true
+	case audience == model.AudienceEveryone:
+		return true
+	case audience == model.AudienceFriends:
+		return isFriend
+	default:
+		return false
+	}
+}
+
+func isValidAudience(audience model.Audience) bool {
+	switch audience {
+	case model.AudienceEveryone, model.AudienceFriends, model.AudienceOnlyMe:
+		return true
+	}
+	return false
+}
+
+// publicUser looks up a user to show next to their content. The email
+// address is left out; only the profile endpoint shows it, and only to the
+// audience its owner picked.
+func publicUser(userRepo *repository.UserRepository, id int64) *model.User {
+	user, err := userRepo.GetByID(id)
+	if err != nil {
+		return nil
+	}
+	user.Email = ""
+	return user
+}
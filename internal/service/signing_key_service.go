@@ -0,0 +1,115 @@
+package service
+
+import (
+	"log"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"socialnet/internal/security"
+	"time"
+)
+
+// keyActivationDelay is how long a new signing key is published before it
+// signs tokens. It gives other server instances time to load the key and
+// external verifiers time to refresh their copy of the JWKS.
+const keyActivationDelay = 10 * time.Minute
+
+type SigningKeyService struct {
+	signingKeyRepo   *repository.SigningKeyRepository
+	keys             *security.KeySet
+	algorithm        string
+	rotationInterval time.Duration
+	tokenLifetime    time.Duration
+}
+
+func NewSigningKeyService(signingKeyRepo *repository.SigningKeyRepository, keys *security.KeySet,
+	algorithm string, rotationInterval, tokenLifetime time.Duration) *SigningKeyService {
+	return &SigningKeyService{
+		signingKeyRepo:   signingKeyRepo,
+		keys:             keys,
+		algorithm:        algorithm,
+		rotationInterval: rotationInterval,
+		tokenLifetime:    tokenLifetime,
+	}
+}
+
+// RotateIfDue creates a new signing key when the newest one is older than
+// the rotation interval, drops expired keys and reloads the key set. The
+// very first key is active immediately.
+func (s *SigningKeyService) RotateIfDue() error {
+	stored, err := s.signingKeyRepo.GetValid()
+	if err != nil {
+		return err
+	}
+
+	var newest *model.SigningKey
+	for _, key := range stored {
+		if newest == nil || key.CreatedAt.After(newest.CreatedAt) {
+			newest = key
+		}
+	}
+
+	if newest == nil || time.Since(newest.CreatedAt) >= s.rotationInterval {
+		activationDelay := keyActivationDelay
+		if newest == nil {
+			activationDelay = 0
+		}
+		if err := s.rotate(activationDelay); err != nil {
+			return err
+		}
+	}
+
+	if err := s.signingKeyRepo.DeleteExpired(); err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+func (s *SigningKeyService) Reload() error {
+	stored, err := s.signingKeyRepo.GetValid()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*security.SigningKey, 0, len(stored))
+	for _, key := range stored {
+		parsed, err := security.ParseSigningKey(key.ID, key.Algorithm, key.PrivateKey, key.ActivatesAt)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", key.ID, err)
+			continue
+		}
+		keys = append(keys, parsed)
+	}
+	s.keys.Replace(keys)
+	return nil
+}
+
+func (s *SigningKeyService) JWKS() *security.JSONWebKeySet {
+	return s.keys.JWKS()
+}
+
+func (s *SigningKeyService) rotate(activationDelay time.Duration) error {
+	key, err := security.GenerateSigningKey(s.algorithm)
+	if err != nil {
+		return err
+	}
+	privateKey, err := key.MarshalPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	// Replaced keys must outlive every token they signed: those issued until
+	// the new key activates, each valid for the token lifetime.
+	retention := activationDelay + s.tokenLifetime
+	added, err := s.signingKeyRepo.Rotate(&model.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: privateKey,
+	}, activationDelay, retention, s.rotationInterval)
+	if err != nil {
+		return err
+	}
+	if added {
+		log.Printf("Created %s signing key %s", key.Algorithm, key.ID)
+	}
+	return nil
+}
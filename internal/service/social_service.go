@@ -8,34 +8,47 @@ import (
 )
 
 type SocialService struct {
-	friendRepo  *repository.FriendshipRepository
-	likeRepo    *repository.LikeRepository
-	commentRepo *repository.CommentRepository
-	postRepo    *repository.PostRepository
-	userRepo    *repository.UserRepository
-	notifQueue  chan *model.Notification
+	friendRepo      *repository.FriendshipRepository
+	followRepo      *repository.FollowRepository
+	likeRepo        *repository.LikeRepository
+	commentRepo     *repository.CommentRepository
+	postRepo        *repository.PostRepository
+	userRepo        *repository.UserRepository
+	privacyService  *PrivacyService
+	presenceService *PresenceService
+	notifQueue      chan *model.Notification
 }
 
-func NewSocialService(friendRepo *repository.FriendshipRepository, likeRepo *repository.LikeRepository,
-	commentRepo *repository.CommentRepository, postRepo *repository.PostRepository,
-	userRepo *repository.UserRepository, notifQueue chan *model.Notification) *SocialService {
+func NewSocialService(friendRepo *repository.FriendshipRepository, followRepo *repository.FollowRepository,
+	likeRepo *repository.LikeRepository, commentRepo *repository.CommentRepository, postRepo *repository.PostRepository,
+	userRepo *repository.UserRepository, privacyService *PrivacyService, presenceService *PresenceService,
+	notifQueue chan *model.Notification) *SocialService {
 	return &SocialService{
-		friendRepo:  friendRepo,
-		likeRepo:    likeRepo,
-		commentRepo: commentRepo,
-		postRepo:    postRepo,
-		userRepo:    userRepo,
-		notifQueue:  notifQueue,
+		friendRepo:      friendRepo,
+		followRepo:      followRepo,
+		likeRepo:        likeRepo,
+		commentRepo:     commentRepo,
+		postRepo:        postRepo,
+		userRepo:        userRepo,
+		privacyService:  privacyService,
+		presenceService: presenceService,
+		notifQueue:      notifQueue,
 	}
 }
 
-func (s *SocialService) SendFriendRequest(requesterID, addresseeID int64) error {
+// SendFriendRequest sends a request to the user req names, by ID or by
+// username.
+func (s *SocialService) SendFriendRequest(requesterID int64, req *model.FriendRequest) error {
+	addresseeID, err := resolveUserID(s.userRepo, req.AddresseeID, req.Username)
+	if err != nil {
+		return err
+	}
 	if requesterID == addresseeID {
 		return errors.New("cannot send friend request to yourself")
 	}
 
-	if _, err := s.userRepo.GetByID(addresseeID); err != nil {
-		return errors.New("user not found")
+	if err := requireActive(s.userRepo, addresseeID); err != nil {
+		return ErrUserNotFound
 	}
 
 	areFriends, _ := s.friendRepo.AreFriends(requesterID, addresseeID)
@@ -43,6 +56,10 @@ func (s *SocialService) SendFriendRequest(requesterID, addresseeID int64) error
 		return errors.New("already friends")
 	}
 
+	if err := s.privacyService.CheckFriendRequest(addresseeID, requesterID); err != nil {
+		return err
+	}
+
 	id, err := s.friendRepo.CreateRequest(requesterID, addresseeID)
 	if err != nil {
 		return err
@@ -53,6 +70,7 @@ func (s *SocialService) SendFriendRequest(requesterID, addresseeID int64) error
 
 	s.notifQueue <- &model.Notification{
 		UserID:   addresseeID,
+		ActorID:  requesterID,
 		Type:     model.NotificationFriendRequest,
 		TargetID: id,
 		Message:  message,
@@ -75,7 +93,11 @@ func (s *SocialService) AcceptFriendRequest(requestID, userID int64) error {
 		return errors.New("request already processed")
 	}
 
-	return s.friendRepo.UpdateStatus(requestID, model.FriendshipAccepted)
+	if err := s.friendRepo.UpdateStatus(requestID, model.FriendshipAccepted); err != nil {
+		return err
+	}
+	// Friends follow each other.
+	return s.followRepo.FollowMutually(friendship.RequesterID, friendship.AddresseeID)
 }
 
 func (s *SocialService) BlockUser(requestID, userID int64) error {
@@ -88,11 +110,46 @@ func (s *SocialService) BlockUser(requestID, userID int64) error {
 		return errors.New("unauthorized")
 	}
 
-	return s.friendRepo.UpdateStatus(requestID, model.FriendshipBlocked)
+	if err := s.friendRepo.UpdateStatus(requestID, model.FriendshipBlocked); err != nil {
+		return err
+	}
+	return s.followRepo.DeleteBetween(friendship.RequesterID, friendship.AddresseeID)
 }
 
 func (s *SocialService) GetFriends(userID int64) ([]*model.User, error) {
-	return s.friendRepo.GetFriends(userID)
+	friends, err := s.friendRepo.GetFriends(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.presenceService.ShowPresence(friends, userID); err != nil {
+		return nil, err
+	}
+	return friends, nil
+}
+
+// GetUserFriends lists another user's friends, if they let the viewer see
+// them.
+func (s *SocialService) GetUserFriends(userID, viewerID int64) ([]*model.User, error) {
+	if err := requireActive(s.userRepo, userID); err != nil {
+		return nil, err
+	}
+
+	canView, err := s.privacyService.CanViewFriends(userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		return nil, errors.New("this user's friend list is private")
+	}
+
+	friends, err := s.friendRepo.GetFriends(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.presenceService.ShowPresence(friends, viewerID); err != nil {
+		return nil, err
+	}
+	return friends, nil
 }
 
 func (s *SocialService) GetPendingRequests(userID int64) ([]*model.Friendship, error) {
@@ -102,27 +159,55 @@ func (s *SocialService) GetPendingRequests(userID int64) ([]*model.Friendship, e
 	}
 
 	for _, friendship := range friendships {
-		requester, _ := s.userRepo.GetByID(friendship.RequesterID)
-		friendship.Requester = requester
+		friendship.Requester = publicUser(s.userRepo, friendship.RequesterID)
 	}
 
 	return friendships, nil
 }
 
+// LikePost reacts to the post with a like.
 func (s *SocialService) LikePost(postID, userID int64) error {
+	reaction, err := s.likeRepo.GetReaction(postID, userID)
+	if err != nil {
+		return err
+	}
+	if reaction == model.ReactionLike {
+		return errors.New("already liked")
+	}
+
+	return s.ReactToPost(postID, userID, &model.ReactionSet{Reaction: model.ReactionLike})
+}
+
+// ReactToPost sets the user's reaction to the post, replacing the one they
+// had. The author is notified of new reactions but not of changed ones.
+func (s *SocialService) ReactToPost(postID, userID int64, set *model.ReactionSet) error {
+	if !isValidReaction(set.Reaction) {
+		return errors.New("invalid reaction: use like, love, haha, wow, sad or angry")
+	}
+
 	post, err := s.postRepo.GetByID(postID)
 	if err != nil {
 		return err
 	}
+	if err := s.privacyService.CheckPostVisible(post, userID); err != nil {
+		return err
+	}
 
-	liked, _ := s.likeRepo.HasUserLiked(postID, userID)
-	if liked {
-		return errors.New("already liked")
+	current, err := s.likeRepo.GetReaction(postID, userID)
+	if err != nil {
+		return err
+	}
+	if current == set.Reaction {
+		return nil
+	}
+	if current != "" {
+		return s.likeRepo.UpdateReaction(postID, userID, set.Reaction)
 	}
 
 	like := &model.Like{
-		PostID: postID,
-		UserID: userID,
+		PostID:   postID,
+		UserID:   userID,
+		Reaction: set.Reaction,
 	}
 
 	if err := s.likeRepo.Create(like); err != nil {
@@ -131,32 +216,57 @@ func (s *SocialService) LikePost(postID, userID int64) error {
 
 	if post.UserID != userID {
 		liker, _ := s.userRepo.GetByID(userID)
-		message := liker.Username + " liked your post"
 
 		s.notifQueue <- &model.Notification{
 			UserID:   post.UserID,
+			ActorID:  userID,
 			Type:     model.NotificationLike,
 			TargetID: postID,
-			Message:  message,
+			Message:  reactionMessage(liker.Username, set.Reaction),
 		}
 	}
 
 	return nil
 }
 
+// UnlikePost removes the user's reaction to the post, whatever its type.
 func (s *SocialService) UnlikePost(postID, userID int64) error {
 	return s.likeRepo.Delete(postID, userID)
 }
 
+// GetReactions lists who reacted to a post the viewer can see, optionally
+// only with the given reaction.
+func (s *SocialService) GetReactions(postID, viewerID int64, reaction model.ReactionType) ([]*model.Like, error) {
+	if reaction != "" && !isValidReaction(reaction) {
+		return nil, errors.New("invalid reaction: use like, love, haha, wow, sad or angry")
+	}
+
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.privacyService.CheckPostVisible(post, viewerID); err != nil {
+		return nil, err
+	}
+
+	return s.likeRepo.GetReactions(postID, reaction)
+}
+
 func (s *SocialService) CommentOnPost(postID, userID int64, create *model.CommentCreate) (*model.Comment, error) {
 	if err := security.ValidateContent(create.Content, 1000); err != nil {
 		return nil, err
 	}
+	if err := requireVerified(s.userRepo, userID); err != nil {
+		return nil, err
+	}
 
 	post, err := s.postRepo.GetByID(postID)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.privacyService.CheckPostVisible(post, userID); err != nil {
+		return nil, err
+	}
 
 	comment := &model.Comment{
 		PostID:  postID,
@@ -174,8 +284,9 @@ func (s *SocialService) CommentOnPost(postID, userID int64, create *model.Commen
 		return nil, err
 	}
 
-	author, _ := s.userRepo.GetByID(comment.UserID)
-	comment.Author = author
+	comment.Author = publicUser(s.userRepo, comment.UserID)
+
+	notifyMentions(s.userRepo, s.notifQueue, userID, postID, create.Content, "a comment")
 
 	if post.UserID != userID {
 		commenter, _ := s.userRepo.GetByID(userID)
@@ -183,6 +294,7 @@ func (s *SocialService) CommentOnPost(postID, userID int64, create *model.Commen
 
 		s.notifQueue <- &model.Notification{
 			UserID:   post.UserID,
+			ActorID:  userID,
 			Type:     model.NotificationComment,
 			TargetID: postID,
 			Message:  message,
@@ -192,16 +304,43 @@ func (s *SocialService) CommentOnPost(postID, userID int64, create *model.Commen
 	return comment, nil
 }
 
-func (s *SocialService) GetComments(postID int64) ([]*model.Comment, error) {
+func (s *SocialService) GetComments(postID, userID int64) ([]*model.Comment, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.privacyService.CheckPostVisible(post, userID); err != nil {
+		return nil, err
+	}
+
 	comments, err := s.commentRepo.GetByPostID(postID)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, comment := range comments {
-		author, _ := s.userRepo.GetByID(comment.UserID)
-		comment.Author = author
+		comment.Author = publicUser(s.userRepo, comment.UserID)
 	}
 
 	return comments, nil
 }
+
+func isValidReaction(reaction model.ReactionType) bool {
+	switch reaction {
+	case model.ReactionLike, model.ReactionLove, model.ReactionHaha,
+		model.ReactionWow, model.ReactionSad, model.ReactionAngry:
+		return true
+	}
+	return false
+}
+
+func reactionMessage(username string, reaction model.ReactionType) string {
+	switch reaction {
+	case model.ReactionLike:
+		return username + " liked your post"
+	case model.ReactionLove:
+		return username + " loved your post"
+	default:
+		return username + " reacted to your post with " + string(reaction)
+	}
+}
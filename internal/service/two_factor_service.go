@@ -0,0 +1,303 @@
+package service
+
+import (
+	"errors"
+	"socialnet/internal/model"
+	"socialnet/internal/repository"
+	"socialnet/internal/security"
+	"strings"
+	"time"
+)
+
+const (
+	twoFactorIssuer   = "SocialNet"
+	recoveryCodeCount = 10
+
+	challengeLogin  = "2fa_login"
+	challengeEnroll = "2fa_enroll"
+)
+
+type TwoFactorService struct {
+	twoFactorRepo   *repository.TwoFactorRepository
+	userRepo        *repository.UserRepository
+	lockoutService  *LockoutService
+	passwordHasher  *security.PasswordHasher
+	challengeSecret string
+	challengeTTL    time.Duration
+}
+
+func NewTwoFactorService(twoFactorRepo *repository.TwoFactorRepository, userRepo *repository.UserRepository,
+	lockoutService *LockoutService, passwordHasher *security.PasswordHasher, challengeSecret string,
+	challengeTTL time.Duration) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepo:   twoFactorRepo,
+		userRepo:        userRepo,
+		lockoutService:  lockoutService,
+		passwordHasher:  passwordHasher,
+		challengeSecret: challengeSecret,
+		challengeTTL:    challengeTTL,
+	}
+}
+
+func (s *TwoFactorService) GetStatus(userID int64) (*model.TwoFactorStatus, error) {
+	enabled, err := s.twoFactorRepo.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.twoFactorRepo.IsRequired(userID)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := s.twoFactorRepo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorStatus{
+		Enabled:                enabled,
+		Required:               required,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// Setup generates a new secret for the user. It only takes effect once a
+// code from it is confirmed through Enable.
+func (s *TwoFactorService) Setup(userID int64) (*model.TwoFactorSetup, error) {
+	enabled, err := s.twoFactorRepo.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errors.New("two-factor authentication already enabled")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.SaveSecret(userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURI: security.TOTPURI(twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// Enable confirms the pending secret with a code from the authenticator app
+// and returns the recovery codes, which are shown only this once.
+func (s *TwoFactorService) Enable(userID int64, code string) ([]string, error) {
+	secret, enabled, err := s.twoFactorRepo.GetSecret(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errors.New("two-factor authentication already enabled")
+	}
+
+	if err := s.verifyTOTP(userID, secret, code); err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.Enable(userID); err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(userID)
+}
+
+func (s *TwoFactorService) Disable(userID int64, disable *model.TwoFactorDisable) error {
+	required, err := s.twoFactorRepo.IsRequired(userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return errors.New("two-factor authentication is required for this account")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkCurrentPassword(s.userRepo, s.passwordHasher, user, disable.Password); err != nil {
+		return err
+	}
+
+	if err := s.verifyCode(userID, disable.Code, ""); err != nil {
+		return err
+	}
+
+	return s.twoFactorRepo.Delete(userID)
+}
+
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID int64, code string) ([]string, error) {
+	if err := s.verifyCode(userID, code, ""); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(userID)
+}
+
+// ChallengeFor decides whether a login that passed the password check needs
+// a second step. Users with 2FA enabled must enter a code; users required to
+// have 2FA but not enrolled yet must set it up before they get a session.
+func (s *TwoFactorService) ChallengeFor(user *model.User) (*model.LoginChallenge, error) {
+	enabled, err := s.twoFactorRepo.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return s.issueChallenge(user, challengeLogin), nil
+	}
+
+	required, err := s.twoFactorRepo.IsRequired(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		return s.issueChallenge(user, challengeEnroll), nil
+	}
+
+	return nil, nil
+}
+
+// CompleteLogin counts wrong codes as failed logins, so a challenge token
+// cannot be used to guess codes faster than passwords.
+func (s *TwoFactorService) CompleteLogin(login *model.TwoFactorLogin, ip string) (*model.User, error) {
+	user, err := s.parseChallenge(login.ChallengeToken, challengeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.lockoutService.Check(user.Email, ip); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyCode(user.ID, login.Code, login.RecoveryCode); err != nil {
+		if err := s.lockoutService.RecordFailure(user.Email, ip, user); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := s.lockoutService.RecordSuccess(user.Email); err != nil {
+		return nil, err
+	}
+	if err := restoreOnLogin(s.userRepo, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *TwoFactorService) SetupFromChallenge(challengeToken string) (*model.TwoFactorSetup, error) {
+	user, err := s.parseChallenge(challengeToken, challengeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return s.Setup(user.ID)
+}
+
+func (s *TwoFactorService) EnableFromChallenge(challengeToken, code string) (*model.User, []string, error) {
+	user, err := s.parseChallenge(challengeToken, challengeEnroll)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	codes, err := s.Enable(user.ID, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := restoreOnLogin(s.userRepo, user); err != nil {
+		return nil, nil, err
+	}
+
+	return user, codes, nil
+}
+
+func (s *TwoFactorService) issueChallenge(user *model.User, purpose string) *model.LoginChallenge {
+	expiresAt := time.Now().Add(s.challengeTTL)
+	token := security.GenerateChallengeToken(&security.Challenge{
+		UserID:       user.ID,
+		Purpose:      purpose,
+		TokenVersion: user.TokenVersion,
+		ExpiresAt:    expiresAt,
+	}, s.challengeSecret)
+
+	return &model.LoginChallenge{
+		TwoFactorRequired:      purpose == challengeLogin,
+		TwoFactorSetupRequired: purpose == challengeEnroll,
+		ChallengeToken:         token,
+		ExpiresAt:              expiresAt.UTC().Truncate(time.Second),
+	}
+}
+
+// parseChallenge also rejects challenges issued before the user's sessions
+// were revoked, e.g. by a password reset between the two steps.
+func (s *TwoFactorService) parseChallenge(token, purpose string) (*model.User, error) {
+	challenge, err := security.ParseChallengeToken(token, s.challengeSecret)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Purpose != purpose {
+		return nil, errors.New("invalid challenge")
+	}
+
+	user, err := s.userRepo.GetByID(challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TokenVersion != challenge.TokenVersion {
+		return nil, errors.New("challenge expired")
+	}
+
+	return user, nil
+}
+
+// verifyCode accepts either a TOTP code or, when given, a recovery code,
+// which is then spent.
+func (s *TwoFactorService) verifyCode(userID int64, code, recoveryCode string) error {
+	secret, enabled, err := s.twoFactorRepo.GetSecret(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.New("two-factor authentication not enabled")
+	}
+
+	if recoveryCode != "" {
+		normalized := strings.ToLower(strings.TrimSpace(recoveryCode))
+		return s.twoFactorRepo.UseRecoveryCode(userID, security.HashToken(normalized))
+	}
+
+	return s.verifyTOTP(userID, secret, code)
+}
+
+func (s *TwoFactorService) verifyTOTP(userID int64, secret, code string) error {
+	step, ok := security.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return errors.New("invalid code")
+	}
+	return s.twoFactorRepo.UseStep(userID, step)
+}
+
+func (s *TwoFactorService) newRecoveryCodes(userID int64) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := security.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = security.HashToken(code)
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
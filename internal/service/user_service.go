@@ -4,18 +4,110 @@ import (
 	"errors"
 	"socialnet/internal/model"
 	"socialnet/internal/repository"
+	"socialnet/internal/security"
+	"strings"
+	"time"
 )
 
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo            *repository.UserRepository
+	friendRepo          *repository.FriendshipRepository
+	followRepo          *repository.FollowRepository
+	postRepo            *repository.PostRepository
+	groupRepo           *repository.GroupRepository
+	privacyService      *PrivacyService
+	usernameCooldown    time.Duration
+	usernameRedirectTTL time.Duration
 }
 
-func NewUserService(userRepo *repository.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo *repository.UserRepository, friendRepo *repository.FriendshipRepository,
+	followRepo *repository.FollowRepository, postRepo *repository.PostRepository, groupRepo *repository.GroupRepository,
+	privacyService *PrivacyService, usernameCooldown, usernameRedirectTTL time.Duration) *UserService {
+	return &UserService{
+		userRepo:            userRepo,
+		friendRepo:          friendRepo,
+		followRepo:          followRepo,
+		postRepo:            postRepo,
+		groupRepo:           groupRepo,
+		privacyService:      privacyService,
+		usernameCooldown:    usernameCooldown,
+		usernameRedirectTTL: usernameRedirectTTL,
+	}
 }
 
-func (s *UserService) GetProfile(userID int64) (*model.User, error) {
-	return s.userRepo.GetByID(userID)
+// GetProfile returns the user as the viewer is allowed to see them, with
+// counts of the lists the viewer has access to.
+func (s *UserService) GetProfile(userID, viewerID int64) (*model.Profile, error) {
+	if err := requireActive(s.userRepo, userID); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	details, err := s.userRepo.GetDetails(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.privacyService.Profile(user, details, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &model.ProfileStats{}
+	if profile.CanViewPosts {
+		count, err := s.postRepo.CountByUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		stats.Posts = &count
+	}
+	if profile.CanViewFriends {
+		count, err := s.friendRepo.CountFriends(userID)
+		if err != nil {
+			return nil, err
+		}
+		stats.Friends = &count
+
+		followers, err := s.followRepo.CountFollowers(userID)
+		if err != nil {
+			return nil, err
+		}
+		stats.Followers = &followers
+		following, err := s.followRepo.CountFollowing(userID)
+		if err != nil {
+			return nil, err
+		}
+		stats.Following = &following
+	}
+	if profile.CanViewGroups {
+		count, err := s.groupRepo.CountUserGroups(userID)
+		if err != nil {
+			return nil, err
+		}
+		stats.Groups = &count
+	}
+	if viewerID != userID {
+		mutual, err := s.friendRepo.GetMutualFriends(userID, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		stats.MutualFriends = len(mutual)
+	}
+	profile.Stats = stats
+
+	return profile, nil
+}
+
+// GetMutualFriends lists the friends the viewer and the user have in common.
+// They are the viewer's friends too, so they are shown even when the user's
+// friend list is private.
+func (s *UserService) GetMutualFriends(userID, viewerID int64) ([]*model.User, error) {
+	if err := requireActive(s.userRepo, userID); err != nil {
+		return nil, err
+	}
+	return s.friendRepo.GetMutualFriends(userID, viewerID)
 }
 
 func (s *UserService) UpdateProfile(userID int64, profile *model.UserProfile) error {
@@ -23,12 +115,95 @@ func (s *UserService) UpdateProfile(userID int64, profile *model.UserProfile) er
 	if err != nil {
 		return err
 	}
+	details, err := s.userRepo.GetDetails(userID)
+	if err != nil {
+		return err
+	}
+
+	if profile.CoverURL != nil {
+		details.CoverURL = strings.TrimSpace(*profile.CoverURL)
+	}
+	if profile.Location != nil {
+		details.Location = strings.TrimSpace(*profile.Location)
+		if len(details.Location) > 100 {
+			return errors.New("location must be at most 100 characters")
+		}
+	}
+	if profile.Website != nil {
+		details.Website = strings.TrimSpace(*profile.Website)
+		if details.Website != "" {
+			if err := security.ValidateWebsite(details.Website); err != nil {
+				return err
+			}
+		}
+	}
+	if profile.Birthday != nil {
+		details.Birthday = strings.TrimSpace(*profile.Birthday)
+		if details.Birthday != "" {
+			if err := security.ValidateBirthday(details.Birthday); err != nil {
+				return err
+			}
+		}
+	}
 
 	user.FullName = profile.FullName
 	user.Bio = profile.Bio
 	user.AvatarURL = profile.AvatarURL
 
-	return s.userRepo.Update(user)
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	return s.userRepo.UpdateDetails(userID, details)
+}
+
+// ChangeUsername renames the user at most once per cooldown period. The old
+// name keeps resolving to the account, e.g. in @mentions, for the redirect
+// period and cannot be taken by anyone else until then.
+func (s *UserService) ChangeUsername(userID int64, username string) (*model.User, error) {
+	if err := security.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if username == user.Username {
+		return nil, errors.New("this is already your username")
+	}
+
+	changedAt, err := s.userRepo.GetUsernameChangedAt(userID)
+	if err != nil {
+		return nil, err
+	}
+	if changedAt != nil {
+		if next := changedAt.Add(s.usernameCooldown); time.Now().Before(next) {
+			return nil, errors.New("username can be changed again after " + next.UTC().Format("Jan 2, 2006 15:04 MST"))
+		}
+	}
+
+	taken, err := s.userRepo.IsUsernameTaken(username, userID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, errors.New("username already exists")
+	}
+
+	if err := s.userRepo.ChangeUsername(userID, user.Username, username, s.usernameRedirectTTL); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetByID(userID)
+}
+
+// GetProfileByUsername resolves a username, or a former one that still
+// redirects, and returns the profile as GetProfile does.
+func (s *UserService) GetProfileByUsername(username string, viewerID int64) (*model.Profile, error) {
+	user, err := findByUsername(s.userRepo, username)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetProfile(user.ID, viewerID)
 }
 
 func (s *UserService) SearchUsers(searchTerm string) ([]*model.User, error) {
@@ -37,3 +212,39 @@ func (s *UserService) SearchUsers(searchTerm string) ([]*model.User, error) {
 	}
 	return s.userRepo.Search(searchTerm, 20)
 }
+
+// requireActive reports deactivated and deleted accounts as not found.
+func requireActive(userRepo *repository.UserRepository, userID int64) error {
+	active, err := userRepo.IsActive(userID)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// findByUsername looks a user up by username, ignoring case and a leading @.
+// Former usernames resolve to their account while they still redirect.
+func findByUsername(userRepo *repository.UserRepository, username string) (*model.User, error) {
+	username = strings.TrimPrefix(username, "@")
+	user, err := userRepo.GetByUsername(username)
+	if err != nil {
+		user, err = userRepo.GetByPreviousUsername(username)
+	}
+	return user, err
+}
+
+// resolveUserID lets requests name a user by ID or by username: id is used
+// when set, otherwise the account username belongs to.
+func resolveUserID(userRepo *repository.UserRepository, id int64, username string) (int64, error) {
+	if id != 0 || username == "" {
+		return id, nil
+	}
+	user, err := findByUsername(userRepo, username)
+	if err != nil {
+		return 0, ErrUserNotFound
+	}
+	return user.ID, nil
+}
@@ -2,20 +2,24 @@ package worker
 
 import (
 	"log"
+	"socialnet/internal/mail"
 	"socialnet/internal/model"
 	"socialnet/internal/service"
 	"time"
 )
 
 type NotificationWorker struct {
-	queue   chan *model.Notification
-	service *service.NotificationService
+	queue        chan *model.Notification
+	service      *service.NotificationService
+	emailService *service.EmailService
 }
 
-func NewNotificationWorker(queue chan *model.Notification, service *service.NotificationService) *NotificationWorker {
+func NewNotificationWorker(queue chan *model.Notification, service *service.NotificationService,
+	emailService *service.EmailService) *NotificationWorker {
 	return &NotificationWorker{
-		queue:   queue,
-		service: service,
+		queue:        queue,
+		service:      service,
+		emailService: emailService,
 	}
 }
 
@@ -23,8 +27,83 @@ func (w *NotificationWorker) Start() {
 	go func() {
 		log.Println("Notification worker started")
 		for notification := range w.queue {
-			if err := w.service.CreateNotification(notification); err != nil {
-				log.Printf("Failed to create notification: %v", err)
+			w.process(notification)
+		}
+	}()
+}
+
+// process delivers one notification on each channel the recipient allows.
+// There is no push transport yet, so the push channel is not consulted.
+func (w *NotificationWorker) process(notification *model.Notification) {
+	now := time.Now()
+
+	deliver, err := w.service.ShouldDeliver(notification, model.ChannelInApp, now)
+	if err != nil {
+		log.Printf("Failed to check notification preferences: %v", err)
+		return
+	}
+	if deliver {
+		if err := w.service.CreateNotification(notification); err != nil {
+			log.Printf("Failed to create notification: %v", err)
+		}
+	}
+
+	deliver, err = w.service.ShouldDeliver(notification, model.ChannelEmail, now)
+	if err != nil {
+		log.Printf("Failed to check notification preferences: %v", err)
+		return
+	}
+	if deliver {
+		if err := w.emailService.SendNotificationEmail(notification); err != nil {
+			log.Printf("Failed to queue notification email: %v", err)
+		}
+	}
+}
+
+type EmailWorker struct {
+	queue  chan *mail.Message
+	mailer mail.Mailer
+}
+
+func NewEmailWorker(queue chan *mail.Message, mailer mail.Mailer) *EmailWorker {
+	return &EmailWorker{
+		queue:  queue,
+		mailer: mailer,
+	}
+}
+
+func (w *EmailWorker) Start() {
+	go func() {
+		log.Println("Email worker started")
+		for message := range w.queue {
+			if err := w.mailer.Send(message); err != nil {
+				log.Printf("Failed to send email to %s: %v", message.To, err)
+			}
+		}
+	}()
+}
+
+type DigestWorker struct {
+	service  *service.EmailService
+	interval time.Duration
+}
+
+func NewDigestWorker(service *service.EmailService, interval time.Duration) *DigestWorker {
+	return &DigestWorker{
+		service:  service,
+		interval: interval,
+	}
+}
+
+func (w *DigestWorker) Start() {
+	go func() {
+		log.Println("Digest worker started")
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := w.service.SendDueDigests(time.Now()); err != nil {
+				log.Printf("Failed to send digests: %v", err)
 			}
 		}
 	}()
@@ -55,3 +134,140 @@ func (w *CleanupWorker) Start() {
 		}
 	}()
 }
+
+type KeyRotationWorker struct {
+	service  *service.SigningKeyService
+	interval time.Duration
+}
+
+func NewKeyRotationWorker(service *service.SigningKeyService, interval time.Duration) *KeyRotationWorker {
+	return &KeyRotationWorker{
+		service:  service,
+		interval: interval,
+	}
+}
+
+// Start also reloads the key set on every tick, which picks up keys created
+// by other server instances before they become active.
+func (w *KeyRotationWorker) Start() {
+	go func() {
+		log.Println("Key rotation worker started")
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := w.service.RotateIfDue(); err != nil {
+				log.Printf("Failed to rotate signing keys: %v", err)
+			}
+		}
+	}()
+}
+
+type DataExportWorker struct {
+	service  *service.AccountService
+	interval time.Duration
+}
+
+func NewDataExportWorker(service *service.AccountService, interval time.Duration) *DataExportWorker {
+	return &DataExportWorker{
+		service:  service,
+		interval: interval,
+	}
+}
+
+func (w *DataExportWorker) Start() {
+	go func() {
+		log.Println("Data export worker started")
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := w.service.ProcessExports(); err != nil {
+				log.Printf("Failed to process data exports: %v", err)
+			}
+		}
+	}()
+}
+
+type AccountDeletionWorker struct {
+	service  *service.AccountService
+	interval time.Duration
+}
+
+func NewAccountDeletionWorker(service *service.AccountService, interval time.Duration) *AccountDeletionWorker {
+	return &AccountDeletionWorker{
+		service:  service,
+		interval: interval,
+	}
+}
+
+func (w *AccountDeletionWorker) Start() {
+	go func() {
+		log.Println("Account deletion worker started")
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := w.service.PurgeDueAccounts(); err != nil {
+				log.Printf("Failed to delete accounts: %v", err)
+			}
+		}
+	}()
+}
+
+// ScheduledPostWorker publishes scheduled posts once they are due. The
+// schedule lives in the database, so posts that fell due while the server
+// was down are published on the first run after it starts.
+type ScheduledPostWorker struct {
+	service  *service.PostService
+	interval time.Duration
+}
+
+func NewScheduledPostWorker(service *service.PostService, interval time.Duration) *ScheduledPostWorker {
+	return &ScheduledPostWorker{
+		service:  service,
+		interval: interval,
+	}
+}
+
+func (w *ScheduledPostWorker) Start() {
+	go func() {
+		log.Println("Scheduled post worker started")
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := w.service.PublishDuePosts(); err != nil {
+				log.Printf("Failed to publish scheduled posts: %v", err)
+			}
+		}
+	}()
+}
+
+// PollWorker tells the authors of polls that have ended. Polls close by
+// themselves at their end time; only the notification waits for the worker.
+type PollWorker struct {
+	service  *service.PollService
+	interval time.Duration
+}
+
+func NewPollWorker(service *service.PollService, interval time.Duration) *PollWorker {
+	return &PollWorker{
+		service:  service,
+		interval: interval,
+	}
+}
+
+func (w *PollWorker) Start() {
+	go func() {
+		log.Println("Poll worker started")
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := w.service.NotifyClosedPolls(); err != nil {
+				log.Printf("Failed to notify closed polls: %v", err)
+			}
+		}
+	}()
+}
@@ -8,8 +8,11 @@ import (
 	httpRouter "socialnet/internal/http"
 	httpHandler "socialnet/internal/http/handler"
 	httpMiddleware "socialnet/internal/http/middleware"
+	"socialnet/internal/mail"
 	"socialnet/internal/model"
+	"socialnet/internal/oidc"
 	"socialnet/internal/repository"
+	"socialnet/internal/security"
 	"socialnet/internal/service"
 	"socialnet/internal/worker"
 	"time"
@@ -17,6 +20,9 @@ import (
 
 func main() {
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	db, err := database.New(cfg.DatabasePath)
 	if err != nil {
@@ -38,43 +44,156 @@ func main() {
 	messageRepo := repository.NewMessageRepository(db.DB)
 	groupRepo := repository.NewGroupRepository(db.DB)
 	notifRepo := repository.NewNotificationRepository(db.DB)
+	notifPrefRepo := repository.NewNotificationPreferenceRepository(db.DB)
 	reportRepo := repository.NewReportRepository(db.DB)
+	authTokenRepo := repository.NewAuthTokenRepository(db.DB)
+	twoFactorRepo := repository.NewTwoFactorRepository(db.DB)
+	loginFailureRepo := repository.NewLoginFailureRepository(db.DB)
+	identityRepo := repository.NewIdentityRepository(db.DB)
+	accessTokenRepo := repository.NewAccessTokenRepository(db.DB)
+	signingKeyRepo := repository.NewSigningKeyRepository(db.DB)
+	accountRepo := repository.NewAccountRepository(db.DB)
+	dataExportRepo := repository.NewDataExportRepository(db.DB)
+	privacyRepo := repository.NewPrivacyRepository(db.DB)
+	followRepo := repository.NewFollowRepository(db.DB)
+	pollRepo := repository.NewPollRepository(db.DB)
+	bookmarkRepo := repository.NewBookmarkRepository(db.DB)
 
 	notifQueue := make(chan *model.Notification, 100)
+	notifHub := service.NewNotificationHub(cfg.MaxStreamsPerUser)
+	mailQueue := make(chan *mail.Message, 100)
+
+	var mailer mail.Mailer
+	if cfg.MailDriver == "smtp" {
+		mailer = mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	} else {
+		mailer = mail.NewLogMailer(cfg.MailLogPath, cfg.MailFrom)
+	}
+
+	signingKeys := security.NewKeySet(cfg.BaseURL)
+	signingKeyService := service.NewSigningKeyService(signingKeyRepo, signingKeys, cfg.JWTSigningAlgorithm,
+		cfg.JWTKeyRotationInterval, cfg.SessionDuration)
+	if err := signingKeyService.RotateIfDue(); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+
+	passwordHasher, err := security.NewPasswordHasher(cfg.BcryptCost)
+	if err != nil {
+		log.Fatalf("Failed to set up password hashing: %v", err)
+	}
+	passwordPolicy := security.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		MinStrength:   cfg.PasswordMinStrength,
+	}
+	if cfg.PasswordBreachedList != "" {
+		passwordPolicy.Breached, err = security.NewBreachedPasswords(cfg.PasswordBreachedList)
+		if err != nil {
+			log.Fatalf("Failed to open breached password list: %v", err)
+		}
+	}
 
-	authService := service.NewAuthService(userRepo)
-	userService := service.NewUserService(userRepo)
-	postService := service.NewPostService(postRepo, likeRepo, userRepo)
-	socialService := service.NewSocialService(friendRepo, likeRepo, commentRepo, postRepo, userRepo, notifQueue)
-	messageService := service.NewMessageService(messageRepo, friendRepo, userRepo, notifQueue)
-	groupService := service.NewGroupService(groupRepo, userRepo, notifQueue)
-	notifService := service.NewNotificationService(notifRepo)
-	adminService := service.NewAdminService(reportRepo, postRepo, commentRepo, userRepo)
-
-	authHandler := httpHandler.NewAuthHandler(authService, cfg.JWTSecret, cfg.SessionDuration)
-	userHandler := httpHandler.NewUserHandler(userService)
-	postHandler := httpHandler.NewPostHandler(postService)
+	privacyService := service.NewPrivacyService(privacyRepo, friendRepo, followRepo)
+	userService := service.NewUserService(userRepo, friendRepo, followRepo, postRepo, groupRepo, privacyService,
+		cfg.UsernameChangeCooldown, cfg.UsernameRedirectTTL)
+	postService := service.NewPostService(postRepo, likeRepo, pollRepo, bookmarkRepo, userRepo, privacyService,
+		notifQueue)
+	pollService := service.NewPollService(pollRepo, postRepo, userRepo, privacyService, notifQueue)
+	bookmarkService := service.NewBookmarkService(bookmarkRepo, postRepo, privacyService, postService)
+	presenceService := service.NewPresenceService(userRepo, privacyService, cfg.PresenceOnlineWindow,
+		cfg.PresenceWriteInterval)
+	socialService := service.NewSocialService(friendRepo, followRepo, likeRepo, commentRepo, postRepo, userRepo,
+		privacyService, presenceService, notifQueue)
+	followService := service.NewFollowService(followRepo, friendRepo, userRepo, privacyService, notifQueue)
+	messageService := service.NewMessageService(messageRepo, friendRepo, userRepo, presenceService, notifQueue)
+	groupService := service.NewGroupService(groupRepo, userRepo, privacyService, notifQueue)
+	notifService := service.NewNotificationService(notifRepo, notifPrefRepo, notifHub, cfg.NotificationAggregationWindow)
+	emailService := service.NewEmailService(userRepo, notifRepo, notifPrefRepo, mailQueue,
+		cfg.BaseURL, cfg.UnsubscribeSecret)
+	lockoutService := service.NewLockoutService(loginFailureRepo, notifQueue, service.LockoutPolicy{
+		BackoffThreshold:   cfg.LoginBackoffThreshold,
+		BackoffBase:        cfg.LoginBackoffBase,
+		LockoutThreshold:   cfg.LoginLockoutThreshold,
+		LockoutDuration:    cfg.LoginLockoutDuration,
+		IPLockoutThreshold: cfg.LoginIPLockoutThreshold,
+		FailureWindow:      cfg.LoginFailureWindow,
+	})
+	twoFactorService := service.NewTwoFactorService(twoFactorRepo, userRepo, lockoutService, passwordHasher,
+		cfg.JWTSecret, cfg.TwoFactorChallengeTTL)
+	authService := service.NewAuthService(userRepo, authTokenRepo, accessTokenRepo, emailService, twoFactorService,
+		lockoutService, passwordPolicy, passwordHasher, cfg.EmailVerificationTTL, cfg.PasswordResetTTL,
+		cfg.EmailChangeTTL)
+	var oidcProviders []*oidc.Provider
+	for _, provider := range cfg.OIDCProviders {
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("Skipping OIDC provider %q: issuer and client ID are required", provider.ID)
+			continue
+		}
+		oidcProviders = append(oidcProviders, oidc.NewProvider(provider.ID, provider.Name, provider.Issuer,
+			provider.ClientID, provider.ClientSecret, provider.Scopes, cfg.OIDCRedirectURL))
+	}
+	accessTokenService := service.NewAccessTokenService(accessTokenRepo)
+	oidcService := service.NewOIDCService(oidcProviders, identityRepo, userRepo, twoFactorService, passwordHasher)
+	accountService := service.NewAccountService(accountRepo, dataExportRepo, userRepo, postRepo, messageRepo, groupRepo,
+		emailService, passwordHasher, cfg.AccountDeletionGracePeriod, cfg.DataExportDir, cfg.DataExportTTL)
+	adminService := service.NewAdminService(reportRepo, postRepo, commentRepo, userRepo, twoFactorRepo, lockoutService)
+
+	authHandler := httpHandler.NewAuthHandler(authService, twoFactorService, signingKeys, cfg.SessionDuration)
+	userHandler := httpHandler.NewUserHandler(userService, privacyService)
+	postHandler := httpHandler.NewPostHandler(postService, pollService)
 	socialHandler := httpHandler.NewSocialHandler(socialService)
 	messageHandler := httpHandler.NewMessageHandler(messageService)
 	groupHandler := httpHandler.NewGroupHandler(groupService)
-	notifHandler := httpHandler.NewNotificationHandler(notifService)
+	notifHandler := httpHandler.NewNotificationHandler(notifService, presenceService, cfg.StreamHeartbeatInterval)
 	adminHandler := httpHandler.NewAdminHandler(adminService)
+	emailHandler := httpHandler.NewEmailHandler(emailService)
+	oidcHandler := httpHandler.NewOIDCHandler(oidcService, signingKeys, cfg.SessionDuration)
+	accessTokenHandler := httpHandler.NewAccessTokenHandler(accessTokenService)
+	jwksHandler := httpHandler.NewJWKSHandler(signingKeyService)
+	accountHandler := httpHandler.NewAccountHandler(accountService)
+	followHandler := httpHandler.NewFollowHandler(followService)
+	bookmarkHandler := httpHandler.NewBookmarkHandler(bookmarkService)
 
-	authMiddleware := httpMiddleware.NewAuthMiddleware(cfg.JWTSecret)
+	authMiddleware := httpMiddleware.NewAuthMiddleware(signingKeys, authService, accessTokenService, presenceService)
 	rateLimiter := httpMiddleware.NewRateLimiter(cfg.RateLimitPerMin, time.Minute)
 
 	router := httpRouter.NewRouter(
 		authHandler, userHandler, postHandler, socialHandler,
-		messageHandler, groupHandler, notifHandler, adminHandler,
+		messageHandler, groupHandler, notifHandler, adminHandler, emailHandler, oidcHandler,
+		accessTokenHandler, jwksHandler, accountHandler, followHandler, bookmarkHandler,
 		authMiddleware, rateLimiter,
 	)
 
-	notifWorker := worker.NewNotificationWorker(notifQueue, notifService)
+	notifWorker := worker.NewNotificationWorker(notifQueue, notifService, emailService)
 	notifWorker.Start()
 
+	emailWorker := worker.NewEmailWorker(mailQueue, mailer)
+	emailWorker.Start()
+
+	digestWorker := worker.NewDigestWorker(emailService, cfg.DigestCheckInterval)
+	digestWorker.Start()
+
 	cleanupWorker := worker.NewCleanupWorker(notifService, cfg.CleanupInterval, 7*24*time.Hour)
 	cleanupWorker.Start()
 
+	keyRotationWorker := worker.NewKeyRotationWorker(signingKeyService, cfg.JWTKeyCheckInterval)
+	keyRotationWorker.Start()
+
+	dataExportWorker := worker.NewDataExportWorker(accountService, cfg.DataExportCheckInterval)
+	dataExportWorker.Start()
+
+	accountDeletionWorker := worker.NewAccountDeletionWorker(accountService, cfg.AccountDeletionCheckInterval)
+	accountDeletionWorker.Start()
+
+	scheduledPostWorker := worker.NewScheduledPostWorker(postService, cfg.ScheduledPostCheckInterval)
+	scheduledPostWorker.Start()
+
+	pollWorker := worker.NewPollWorker(pollService, cfg.PollCheckInterval)
+	pollWorker.Start()
+
 	log.Printf("Server starting on port %s", cfg.ServerPort)
 	log.Fatal(http.ListenAndServe(":"+cfg.ServerPort, router.Setup()))
 }
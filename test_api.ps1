@@ -7,7 +7,7 @@ Write-Host "1. Testing Registration..." -ForegroundColor Yellow
 $registerResponse = Invoke-RestMethod -Uri "$baseUrl/register" -Method Post -ContentType "application/json" -Body (@{
     email = "test@example.com"
     username = "testuser"
-    password = "Test123!"
+    password = "Quiet-Harbor-42"
     full_name = "Test User"
 } | ConvertTo-Json)
 
@@ -17,7 +17,7 @@ Write-Host ""
 Write-Host "2. Testing Login..." -ForegroundColor Yellow
 $loginResponse = Invoke-RestMethod -Uri "$baseUrl/login" -Method Post -ContentType "application/json" -Body (@{
     email = "test@example.com"
-    password = "Test123!"
+    password = "Quiet-Harbor-42"
 } | ConvertTo-Json)
 
 $token = $loginResponse.token
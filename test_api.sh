@@ -13,7 +13,7 @@ echo ""
 echo -e "${YELLOW}1. Testing Registration...${NC}"
 REGISTER_RESPONSE=$(curl -s -X POST "$BASE_URL/register" \
   -H "Content-Type: application/json" \
-  -d '{"email":"test@example.com","username":"testuser","password":"Test123!","full_name":"Test User"}')
+  -d '{"email":"test@example.com","username":"testuser","password":"Quiet-Harbor-42","full_name":"Test User"}')
 
 USERNAME=$(echo $REGISTER_RESPONSE | grep -o '"username":"[^"]*"' | cut -d'"' -f4)
 echo -e "${GREEN}   User registered: $USERNAME${NC}"
@@ -22,7 +22,7 @@ echo ""
 echo -e "${YELLOW}2. Testing Login...${NC}"
 LOGIN_RESPONSE=$(curl -s -X POST "$BASE_URL/login" \
   -H "Content-Type: application/json" \
-  -d '{"email":"test@example.com","password":"Test123!"}')
+  -d '{"email":"test@example.com","password":"Quiet-Harbor-42"}')
 
 TOKEN=$(echo $LOGIN_RESPONSE | grep -o '"token":"[^"]*"' | cut -d'"' -f4)
 USER_ID=$(echo $LOGIN_RESPONSE | grep -o '"id":[0-9]*' | head -1 | cut -d':' -f2)